@@ -0,0 +1,311 @@
+// Package audit keeps an append-only log of everything forcedata does that changes an org, or could: authenticating,
+// and creating, uploading to, closing, aborting and deleting Bulk API jobs, along with the state jobs finish in. Each
+// entry is a line of JSON saying who did it, in which org, to which object, with which file and from which command.
+//
+// Entries can be chained: each carries the hash of the entry before it, and its own hash covers that, so an entry
+// that's changed or removed breaks the chain from there on. Removing entries from the end of the log can't be detected
+// this way.
+package audit
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"github.com/pkg/errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Actions entries record.
+const (
+	Authenticate = "authenticate"
+	Create       = "create"
+	Upload       = "upload"
+	State        = "state"
+	Abort        = "abort"
+	Delete       = "delete"
+)
+
+// Entry is a line of the audit log.
+type Entry struct {
+	Time   time.Time `json:"time"`
+	Action string    `json:"action"`
+
+	// Org and User are the IDs of the org and user of the session, and Instance the org's URL.
+	Org      string `json:"org,omitempty"`
+	User     string `json:"user,omitempty"`
+	Instance string `json:"instance,omitempty"`
+
+	JobID     string `json:"jobId,omitempty"`
+	Object    string `json:"object,omitempty"`
+	Operation string `json:"operation,omitempty"`
+	State     string `json:"state,omitempty"`
+
+	// File and FileHash are the file being loaded and the hash of its contents, when a command loading one is run.
+	File     string `json:"file,omitempty"`
+	FileHash string `json:"fileHash,omitempty"`
+
+	// Records, Bytes and ContentHash describe the content of an upload.
+	Records     int    `json:"records,omitempty"`
+	Bytes       int    `json:"bytes,omitempty"`
+	ContentHash string `json:"contentHash,omitempty"`
+
+	// Processed and Failed are the counts of records a job reported in the state it was seen in.
+	Processed uint `json:"processed,omitempty"`
+	Failed    uint `json:"failed,omitempty"`
+
+	// Status is the HTTP status the org responded with, and Error why the request failed, if it did.
+	Status int    `json:"status,omitempty"`
+	Error  string `json:"error,omitempty"`
+
+	Command string `json:"command,omitempty"`
+
+	// Prev is the hash of the entry before a chained entry, and Hash the entry's own.
+	Prev string `json:"prev,omitempty"`
+	Hash string `json:"hash,omitempty"`
+}
+
+// sum returns the hash of an entry, covering everything in it but its own hash.
+func (e Entry) sum() string {
+	e.Hash = ""
+	b, _ := json.Marshal(e)
+	sum := sha256.Sum256(b)
+
+	return hex.EncodeToString(sum[:])
+}
+
+// Log is an audit log file.
+type Log struct {
+	path  string
+	chain bool
+
+	mu sync.Mutex
+}
+
+// Chained entries are appended holding a lock file next to the log, so that processes appending at the same time each
+// chain to the entry the other appended. lockWait is how long Append waits for the lock, and lockStale how old a lock
+// file must be to be taken as left behind by a process that died holding it.
+const (
+	lockWait  = 10 * time.Second
+	lockStale = time.Minute
+)
+
+// Open returns the log at path, which is created when the first entry is appended. If chain is set, entries are
+// chained to the ones before them.
+func Open(path string, chain bool) *Log {
+	return &Log{path: path, chain: chain}
+}
+
+// Path returns the path of the log.
+func (l *Log) Path() string {
+	return l.path
+}
+
+// Append adds an entry to the end of the log, setting its time if it has none, and chaining it to the last entry of the
+// log if the log is chained.
+func (l *Log) Append(e Entry) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if e.Time.IsZero() {
+		e.Time = time.Now().UTC()
+	}
+
+	if err := os.MkdirAll(filepath.Dir(l.path), 0700); err != nil {
+		return errors.Wrap(err, "could not create audit log directory")
+	}
+
+	if l.chain {
+		unlock, err := l.lock()
+
+		if err != nil {
+			return err
+		}
+
+		defer unlock()
+
+		if e.Prev, err = l.lastHash(); err != nil {
+			return err
+		}
+
+		e.Hash = e.sum()
+	}
+
+	b, err := json.Marshal(e)
+
+	if err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(l.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+
+	if err != nil {
+		return errors.Wrap(err, "could not open audit log")
+	}
+
+	if _, err := f.Write(append(b, '\n')); err != nil {
+		f.Close()
+		return errors.Wrap(err, "could not write audit log")
+	}
+
+	if err := f.Close(); err != nil {
+		return errors.Wrap(err, "could not write audit log")
+	}
+
+	return nil
+}
+
+// lock creates the log's lock file, waiting for another process holding it to remove it, and returns a function
+// removing it.
+func (l *Log) lock() (func(), error) {
+	path := l.path + ".lock"
+	deadline := time.Now().Add(lockWait)
+
+	for {
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+
+		if err == nil {
+			f.Close()
+			return func() { os.Remove(path) }, nil
+		}
+
+		if !os.IsExist(err) {
+			return nil, errors.Wrap(err, "could not lock audit log")
+		}
+
+		if info, err := os.Stat(path); err == nil && time.Since(info.ModTime()) > lockStale {
+			os.Remove(path)
+			continue
+		}
+
+		if time.Now().After(deadline) {
+			return nil, errors.Errorf("could not lock audit log; remove %s if no other forcedata is running", path)
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// lastHash returns the hash of the last entry of the log, reading back from its end until it has the whole of the last
+// line.
+func (l *Log) lastHash() (string, error) {
+	f, err := os.Open(l.path)
+
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+
+	if err != nil {
+		return "", errors.Wrap(err, "could not read audit log")
+	}
+
+	defer f.Close()
+
+	info, err := f.Stat()
+
+	if err != nil {
+		return "", errors.Wrap(err, "could not read audit log")
+	}
+
+	var tail []byte
+
+	for end := info.Size(); end > 0; {
+		start := end - 4096
+
+		if start < 0 {
+			start = 0
+		}
+
+		block := make([]byte, end-start)
+
+		if _, err := f.ReadAt(block, start); err != nil {
+			return "", errors.Wrap(err, "could not read audit log")
+		}
+
+		tail = append(block, tail...)
+		end = start
+
+		if line := bytes.TrimSpace(tail); bytes.LastIndexByte(line, '\n') >= 0 || start == 0 && len(line) > 0 {
+			var e Entry
+
+			if err := json.Unmarshal(line[bytes.LastIndexByte(line, '\n')+1:], &e); err != nil {
+				return "", errors.Wrap(err, "could not read the last entry of the audit log")
+			}
+
+			return e.Hash, nil
+		}
+	}
+
+	return "", nil
+}
+
+// Read returns the entries of the log at path, in the order they were appended.
+func Read(path string) ([]Entry, error) {
+	b, err := ioutil.ReadFile(path)
+
+	if err != nil {
+		return nil, errors.Wrap(err, "could not read audit log")
+	}
+
+	var entries []Entry
+
+	s := bufio.NewScanner(bytes.NewReader(b))
+	s.Buffer(nil, len(b)+1)
+
+	for n := 1; s.Scan(); n++ {
+		if len(bytes.TrimSpace(s.Bytes())) == 0 {
+			continue
+		}
+
+		var e Entry
+
+		if err := json.Unmarshal(s.Bytes(), &e); err != nil {
+			return nil, errors.Wrapf(err, "could not read line %d of the audit log", n)
+		}
+
+		entries = append(entries, e)
+	}
+
+	return entries, s.Err()
+}
+
+// Verify checks the chain of the entries of a log, returning an error naming the first, by 1-based number, whose hash
+// doesn't match its contents or whose predecessor isn't the entry before it. Entries that aren't chained are skipped,
+// but break the chain if they're followed by chained ones.
+func Verify(entries []Entry) error {
+	prev := ""
+
+	for i, e := range entries {
+		if e.Hash != "" {
+			if e.Prev != prev {
+				return errors.Errorf("entry %d doesn't follow the entry before it; entries were removed or reordered", i+1)
+			}
+
+			if e.sum() != e.Hash {
+				return errors.Errorf("entry %d doesn't match its hash; it was changed", i+1)
+			}
+		}
+
+		prev = e.Hash
+	}
+
+	return nil
+}
+
+// Identity returns the org and user IDs of a session's identity URL, such as
+// https://login.salesforce.com/id/00Dxx0000001gPL/005xx000001Svm.
+func Identity(id string) (org, user string) {
+	parts := strings.Split(strings.TrimSuffix(id, "/"), "/")
+
+	if len(parts) < 3 || parts[len(parts)-3] != "id" {
+		return "", ""
+	}
+
+	return parts[len(parts)-2], parts[len(parts)-1]
+}
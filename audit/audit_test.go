@@ -0,0 +1,198 @@
+package audit
+
+import (
+	"github.com/rfaulhaber/forcedata/job"
+	"github.com/rfaulhaber/forcedata/jobtest"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func tempLog(t *testing.T, chain bool) (*Log, func()) {
+	dir, err := ioutil.TempDir("", "audit")
+	assert.NoError(t, err)
+
+	return Open(filepath.Join(dir, "logs", "audit.jsonl"), chain), func() { os.RemoveAll(dir) }
+}
+
+func TestLog_Chain(t *testing.T) {
+	l, cleanup := tempLog(t, true)
+	defer cleanup()
+
+	for _, action := range []string{Create, Upload, State} {
+		assert.NoError(t, l.Append(Entry{Action: action, JobID: "750A", Records: 2}))
+	}
+
+	// a second log of the same file carries on the chain
+	assert.NoError(t, Open(l.Path(), true).Append(Entry{Action: Delete, JobID: "750A"}))
+
+	entries, err := Read(l.Path())
+	assert.NoError(t, err)
+	assert.Len(t, entries, 4)
+	assert.Equal(t, "", entries[0].Prev)
+	assert.Equal(t, entries[2].Hash, entries[3].Prev)
+	assert.NoError(t, Verify(entries))
+
+	changed := append([]Entry(nil), entries...)
+	changed[1].Records = 1
+	assert.EqualError(t, Verify(changed), "entry 2 doesn't match its hash; it was changed")
+
+	removed := append(append([]Entry(nil), entries[:1]...), entries[2:]...)
+	assert.EqualError(t, Verify(removed), "entry 2 doesn't follow the entry before it; entries were removed or reordered")
+}
+
+func TestLog_ChainConcurrent(t *testing.T) {
+	l, cleanup := tempLog(t, true)
+	defer cleanup()
+
+	// each log stands for a process of its own appending to the same file, first in turns and then at the same time
+	logs := []*Log{l, Open(l.Path(), true), Open(l.Path(), true)}
+
+	for i := 0; i < 6; i++ {
+		assert.NoError(t, logs[i%len(logs)].Append(Entry{Action: State}))
+	}
+
+	var wg sync.WaitGroup
+
+	for _, l := range logs {
+		wg.Add(1)
+
+		go func(l *Log) {
+			defer wg.Done()
+
+			for j := 0; j < 10; j++ {
+				assert.NoError(t, l.Append(Entry{Action: State}))
+			}
+		}(l)
+	}
+
+	wg.Wait()
+
+	entries, err := Read(l.Path())
+	assert.NoError(t, err)
+	assert.Len(t, entries, 36)
+	assert.NoError(t, Verify(entries))
+}
+
+func TestLog_Unchained(t *testing.T) {
+	l, cleanup := tempLog(t, false)
+	defer cleanup()
+
+	assert.NoError(t, l.Append(Entry{Action: Authenticate}))
+
+	entries, err := Read(l.Path())
+	assert.NoError(t, err)
+	assert.Equal(t, "", entries[0].Hash)
+	assert.False(t, entries[0].Time.IsZero())
+	assert.NoError(t, Verify(entries))
+}
+
+func TestIdentity(t *testing.T) {
+	org, user := Identity("https://login.salesforce.com/id/00Dxx0000001gPL/005xx000001Svm")
+	assert.Equal(t, "00Dxx0000001gPL", org)
+	assert.Equal(t, "005xx000001Svm", user)
+
+	org, user = Identity("not an identity")
+	assert.Equal(t, "", org+user)
+}
+
+func TestRecordCounter(t *testing.T) {
+	testCases := []struct {
+		content string
+		records int
+	}{
+		{"", 0},
+		{"Name\n", 0},
+		{"Name,Notes\nAcme,\"one\ntwo\"\n\nGlobex,\"say \"\"hi\"\"\"\r\nInitech,5\" pipe", 3},
+		{"Name|Notes\n\"Acme\"|\"a|\nb\"\n", 1},
+	}
+
+	for _, tc := range testCases {
+		var c recordCounter
+
+		// written a byte at a time, as a body may be read in any pieces
+		for i := range tc.content {
+			c.Write([]byte{tc.content[i]})
+		}
+
+		assert.Equal(t, tc.records, c.Count(), tc.content)
+	}
+}
+
+func TestTransport(t *testing.T) {
+	sim := jobtest.New()
+	sim.ProcessingTime = 10 * time.Millisecond
+	sim.Rules = []jobtest.Rule{jobtest.FailWhen("LastName", "", "REQUIRED_FIELD_MISSING", "Required fields are missing: [LastName]")}
+
+	server := sim.Start()
+	defer server.Close()
+
+	l, cleanup := tempLog(t, true)
+	defer cleanup()
+
+	session := sim.Session(server.URL)
+
+	transport := NewTransport(l, nil, "data load contacts.csv")
+	transport.SetSession(session.ID, session.InstanceURL)
+	transport.SetFile("contacts.csv", "abc123")
+
+	defer func(previous http.RoundTripper) { http.DefaultClient.Transport = previous }(http.DefaultClient.Transport)
+	http.DefaultClient.Transport = transport
+
+	config := job.JobConfig{Object: "Contact", Operation: "insert", ContentType: "CSV", Delim: "COMMA"}
+
+	loaded := job.New(config, session)
+	assert.NoError(t, loaded.Create())
+	assert.NoError(t, loaded.Upload([]byte("FirstName,LastName\nPerson,One\nPerson,\n")))
+
+	_, err := loaded.Wait(time.Millisecond)
+	assert.NoError(t, err)
+
+	aborted := job.New(config, session)
+	assert.NoError(t, aborted.Create())
+	assert.NoError(t, aborted.Abort())
+	assert.NoError(t, aborted.Delete())
+
+	entries, err := Read(l.Path())
+	assert.NoError(t, err)
+	assert.NoError(t, Verify(entries))
+
+	var (
+		actions, states []string
+		finished        Entry
+	)
+
+	for _, e := range entries {
+		assert.Equal(t, "00DMOCK000000000AAA", e.Org)
+		assert.Equal(t, "mock@example.com", e.User)
+		assert.Equal(t, "contacts.csv", e.File)
+		assert.Equal(t, "data load contacts.csv", e.Command)
+		assert.Equal(t, "Contact", e.Object)
+
+		// whether the job is seen in progress depends on timing
+		if e.State == "InProgress" {
+			continue
+		}
+
+		if e.State == "JobComplete" {
+			finished = e
+		}
+
+		actions = append(actions, e.Action)
+		states = append(states, e.State)
+	}
+
+	assert.Equal(t, []string{Create, Upload, State, State, Create, Abort, Delete}, actions)
+	assert.Equal(t, []string{"Open", "Open", "UploadComplete", "JobComplete", "Open", "Aborted", "Aborted"}, states)
+
+	assert.Equal(t, loaded.ID(), entries[1].JobID)
+	assert.Equal(t, 2, entries[1].Records)
+	assert.NotEmpty(t, entries[1].ContentHash)
+	assert.Equal(t, uint(2), finished.Processed)
+	assert.Equal(t, uint(1), finished.Failed)
+}
@@ -0,0 +1,393 @@
+package audit
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"hash"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+var (
+	tokenPath  = regexp.MustCompile(`/services/oauth2/token/?$`)
+	ingestPath = regexp.MustCompile(`/jobs/ingest/?$`)
+	jobPath    = regexp.MustCompile(`/jobs/ingest/([^/]+)/?$`)
+	batchPath  = regexp.MustCompile(`/jobs/ingest/([^/]+)/batches/?$`)
+)
+
+// Transport is an http.RoundTripper that appends an entry to a log for each request that authenticates or changes a
+// Bulk API ingest job, and for each change in the state of a job it has seen changed when the job is checked.
+type Transport struct {
+	Log  *Log
+	Next http.RoundTripper
+
+	// Error, if set, is called with the error of an entry that couldn't be appended. Requests aren't failed for it, as
+	// they've already been sent.
+	Error func(err error)
+
+	mu   sync.Mutex
+	base Entry
+	jobs map[string]*Entry
+}
+
+// NewTransport returns a transport appending to log, sending requests through next, or http.DefaultTransport if next is
+// nil. command is recorded in every entry.
+func NewTransport(log *Log, next http.RoundTripper, command string) *Transport {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+
+	return &Transport{Log: log, Next: next, base: Entry{Command: command}, jobs: make(map[string]*Entry)}
+}
+
+// SetSession records the org and user of the session requests are made with in the entries after it, from the
+// session's identity URL and instance URL.
+func (t *Transport) SetSession(id, instance string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.base.Org, t.base.User = Identity(id)
+	t.base.Instance = instance
+}
+
+// SetFile records the file being loaded and the hash of its contents in the entries after it.
+func (t *Transport) SetFile(file, hash string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.base.File, t.base.FileHash = file, hash
+}
+
+// RoundTrip sends the request, appending an entry for it if it's audited.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	action, jobID := classify(req)
+
+	if action == "" {
+		return t.Next.RoundTrip(req)
+	}
+
+	sent := newRecordingBody(req.Body)
+
+	if req.Body != nil {
+		// the body is recorded as it's sent rather than read first, so that uploads can report their progress
+		req = req.WithContext(req.Context())
+		req.Body = sent
+	}
+
+	resp, err := t.Next.RoundTrip(req)
+
+	e := t.entry(jobID)
+	e.Action = action
+
+	if err != nil {
+		if req.Method != "GET" {
+			e.Error = err.Error()
+			t.append(e)
+		}
+
+		return resp, err
+	}
+
+	e.Status = resp.StatusCode
+
+	respBody, readErr := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	resp.Body = ioutil.NopCloser(bytes.NewReader(respBody))
+
+	if readErr != nil {
+		return resp, readErr
+	}
+
+	if resp.StatusCode >= 300 {
+		e.Error = errorMessage(respBody)
+	}
+
+	switch action {
+	case Authenticate:
+		var session struct {
+			ID          string `json:"id"`
+			InstanceURL string `json:"instance_url"`
+		}
+
+		if json.Unmarshal(respBody, &session) == nil && session.ID != "" {
+			e.Org, e.User = Identity(session.ID)
+			e.Instance = session.InstanceURL
+		}
+	case Create:
+		t.info(&e, respBody, true)
+	case Upload:
+		e.Bytes, e.Records, e.ContentHash = sent.Summary()
+	case State:
+		var patch struct {
+			State string `json:"state"`
+		}
+
+		if req.Method == "PATCH" {
+			json.Unmarshal(sent.Head(), &patch)
+			e.State = patch.State
+
+			if patch.State == "Aborted" {
+				e.Action = Abort
+			}
+		}
+
+		changed := t.info(&e, respBody, req.Method == "PATCH")
+
+		// jobs are only checked, which changes nothing, by GET; those are audited when they show the job finishing
+		// or otherwise changing state after this process changed it
+		if req.Method == "GET" && (!changed || resp.StatusCode >= 300) {
+			return resp, nil
+		}
+	}
+
+	t.append(e)
+
+	return resp, nil
+}
+
+// classify returns the action a request is audited as, and the ID of the job it's about, if any. Requests that aren't
+// audited have no action. Requests for a job's info are classed as State, and only audited if the state changed.
+func classify(req *http.Request) (string, string) {
+	path := req.URL.Path
+
+	switch {
+	case req.Method == "POST" && tokenPath.MatchString(path):
+		return Authenticate, ""
+	case req.Method == "POST" && ingestPath.MatchString(path):
+		return Create, ""
+	case req.Method == "PUT" && batchPath.MatchString(path):
+		return Upload, batchPath.FindStringSubmatch(path)[1]
+	}
+
+	m := jobPath.FindStringSubmatch(path)
+
+	if m == nil {
+		return "", ""
+	}
+
+	switch req.Method {
+	case "PATCH", "GET":
+		return State, m[1]
+	case "DELETE":
+		return Delete, m[1]
+	}
+
+	return "", ""
+}
+
+// entry returns a new entry about a job, with what's known of the session, file and job.
+func (t *Transport) entry(jobID string) Entry {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	e := t.base
+	e.JobID = jobID
+
+	if job, ok := t.jobs[jobID]; ok {
+		e.Object, e.Operation, e.State = job.Object, job.Operation, job.State
+	}
+
+	return e
+}
+
+// info fills in an entry from a job's info, returning whether the job's state changed since it was last seen. Jobs
+// are tracked once this process has changed them, if track is set; until then they're never changed.
+func (t *Transport) info(e *Entry, body []byte, track bool) bool {
+	var info struct {
+		ID        string `json:"id"`
+		Object    string `json:"object"`
+		Operation string `json:"operation"`
+		State     string `json:"state"`
+		Processed uint   `json:"numberRecordsProcessed"`
+		Failed    uint   `json:"numberRecordsFailed"`
+	}
+
+	if json.Unmarshal(body, &info) != nil || info.ID == "" {
+		return false
+	}
+
+	e.JobID, e.Object, e.Operation, e.State = info.ID, info.Object, info.Operation, info.State
+	e.Processed, e.Failed = info.Processed, info.Failed
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	job, ok := t.jobs[info.ID]
+
+	if !ok && !track {
+		return false
+	}
+
+	t.jobs[info.ID] = &Entry{Object: info.Object, Operation: info.Operation, State: info.State}
+
+	return !ok || job.State != info.State
+}
+
+// append appends an entry to the log. Entries recording a change the org refused are appended too, so that attempts
+// are audited as well as changes.
+func (t *Transport) append(e Entry) {
+	if err := t.Log.Append(e); err != nil && t.Error != nil {
+		t.Error(err)
+	}
+}
+
+// headSize is how much of a request body is kept, enough for the JSON of a change of a job's state.
+const headSize = 4096
+
+// recordingBody hashes a request body, and counts its bytes and CSV records, as it's read, keeping only its first
+// headSize bytes, so that large uploads aren't held in memory twice.
+type recordingBody struct {
+	io.ReadCloser
+
+	mu      sync.Mutex
+	hash    hash.Hash
+	size    int
+	records recordCounter
+	head    bytes.Buffer
+}
+
+func newRecordingBody(body io.ReadCloser) *recordingBody {
+	return &recordingBody{ReadCloser: body, hash: sha256.New()}
+}
+
+func (b *recordingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.hash.Write(p[:n])
+	b.records.Write(p[:n])
+	b.size += n
+
+	if room := headSize - b.head.Len(); room > 0 {
+		if room > n {
+			room = n
+		}
+
+		b.head.Write(p[:room])
+	}
+
+	return n, err
+}
+
+// Head returns the first headSize bytes read of the body.
+func (b *recordingBody) Head() []byte {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return append([]byte(nil), b.head.Bytes()...)
+}
+
+// Summary returns the number of bytes read of the body, the number of CSV records in them, not counting the header,
+// and their hash.
+func (b *recordingBody) Summary() (int, int, string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.size, b.records.Count(), hex.EncodeToString(b.hash.Sum(nil))
+}
+
+// errorMessage returns the messages of a Salesforce error response, or its body if it isn't one.
+func errorMessage(body []byte) string {
+	var errs []struct {
+		ErrorCode string `json:"errorCode"`
+		Message   string `json:"message"`
+	}
+
+	if json.Unmarshal(body, &errs) == nil && len(errs) > 0 {
+		var messages []string
+
+		for _, err := range errs {
+			messages = append(messages, err.ErrorCode+": "+err.Message)
+		}
+
+		return strings.Join(messages, "; ")
+	}
+
+	var oauth struct {
+		Error       string `json:"error"`
+		Description string `json:"error_description"`
+	}
+
+	if json.Unmarshal(body, &oauth) == nil && oauth.Error != "" {
+		return oauth.Error + ": " + oauth.Description
+	}
+
+	return strings.TrimSpace(string(body))
+}
+
+// recordCounter counts the records of CSV written to it in pieces, as encoding/csv reads them with LazyQuotes:
+// records end at line breaks outside quoted fields, and blank lines aren't records. Fields may be separated by any of
+// the Bulk API's delimiters.
+type recordCounter struct {
+	// lines counts the records ended so far, and content is whether the current line has any.
+	lines   int
+	content bool
+
+	// inField is whether the current field has started, quoted whether it's quoted, and quote whether the last byte of
+	// a quoted field was a quote, which either ends the field or is doubled.
+	inField bool
+	quoted  bool
+	quote   bool
+}
+
+func (c *recordCounter) Write(p []byte) (int, error) {
+	for _, b := range p {
+		if c.quoted {
+			switch {
+			case c.quote && b == '"':
+				// a doubled quote is a quote within the field
+				c.quote = false
+				continue
+			case c.quote:
+				c.quoted, c.quote = false, false
+			case b == '"':
+				c.quote = true
+				continue
+			default:
+				continue
+			}
+		}
+
+		switch b {
+		case '\n':
+			if c.content {
+				c.lines++
+			}
+
+			c.content, c.inField = false, false
+		case '\r':
+		case '"':
+			c.content = true
+			c.quoted = !c.inField
+			c.inField = true
+		default:
+			c.content = true
+			c.inField = strings.IndexByte(",\t|;^`", b) < 0
+		}
+	}
+
+	return len(p), nil
+}
+
+// Count returns the number of records written, not counting the header.
+func (c *recordCounter) Count() int {
+	n := c.lines
+
+	if c.content {
+		n++
+	}
+
+	if n == 0 {
+		return 0
+	}
+
+	return n - 1
+}
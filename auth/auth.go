@@ -50,6 +50,7 @@ type Session struct {
 	ID          string `json:"id" mapstructure:"id"`
 	IssuedAt    string `json:"issued_at" mapstructure:"issued_at"`
 	Signature   string `json:"signature" mapstructure:"signature"`
+	APIVersion  string `json:"api_version,omitempty" mapstructure:"api_version"`
 }
 
 // TODO implement!
@@ -0,0 +1,177 @@
+package auth
+
+import (
+	"encoding/json"
+	"github.com/pkg/errors"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+)
+
+const (
+	// DefaultAPIVersion is used when no version has been negotiated or pinned for a session.
+	DefaultAPIVersion = "43.0"
+	dataEndpoint      = "/services/data/"
+)
+
+// Version is a single entry from the list of REST API versions an org supports.
+type Version struct {
+	Label   string `json:"label"`
+	URL     string `json:"url"`
+	Version string `json:"version"`
+}
+
+// DataURL returns the root of the versioned REST API for the session, e.g.
+// https://na1.salesforce.com/services/data/v43.0. Every versioned REST call should build its URL from this.
+func (s Session) DataURL() string {
+	version := s.APIVersion
+
+	if version == "" {
+		version = DefaultAPIVersion
+	}
+
+	return strings.TrimSuffix(s.InstanceURL, "/") + dataEndpoint + "v" + version
+}
+
+// Versions queries the org for every REST API version it supports.
+func Versions(session Session) ([]Version, error) {
+	req, err := http.NewRequest("GET", strings.TrimSuffix(session.InstanceURL, "/")+dataEndpoint, nil)
+
+	if err != nil {
+		return nil, errors.Wrap(err, "could not generate versions request")
+	}
+
+	req.Header.Add("Accept", "application/json")
+	req.Header.Add("Authorization", "Bearer "+session.AccessToken)
+
+	resp, err := http.DefaultClient.Do(req)
+
+	if err != nil {
+		return nil, errors.Wrap(err, "versions request failed")
+	}
+
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+
+	if err != nil {
+		return nil, errors.Wrap(err, "could not read versions response")
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("versions: server responded with %d", resp.StatusCode)
+	}
+
+	var versions []Version
+
+	if err := json.Unmarshal(body, &versions); err != nil {
+		return nil, errors.Wrap(err, "could not parse versions response")
+	}
+
+	return versions, nil
+}
+
+// LatestVersion returns the highest API version the org supports.
+func LatestVersion(session Session) (string, error) {
+	versions, err := Versions(session)
+
+	if err != nil {
+		return "", err
+	}
+
+	latest := ""
+
+	for _, v := range versions {
+		if latest == "" || CompareVersions(v.Version, latest) > 0 {
+			latest = v.Version
+		}
+	}
+
+	if latest == "" {
+		return "", errors.New("server did not report any API versions")
+	}
+
+	return latest, nil
+}
+
+// CompareVersions compares two API versions such as "43.0" and "58.0" numerically. It returns a negative number if
+// a < b, zero if they are equal, and a positive number if a > b.
+func CompareVersions(a, b string) int {
+	aParts := strings.Split(a, ".")
+	bParts := strings.Split(b, ".")
+
+	for i := 0; i < len(aParts) || i < len(bParts); i++ {
+		var x, y int
+
+		if i < len(aParts) {
+			x, _ = strconv.Atoi(aParts[i])
+		}
+
+		if i < len(bParts) {
+			y, _ = strconv.Atoi(bParts[i])
+		}
+
+		if x != y {
+			return x - y
+		}
+	}
+
+	return 0
+}
+
+// VersionCache remembers the API version negotiated for each org, keyed by instance URL.
+type VersionCache map[string]string
+
+// LoadVersionCache reads a cache from path. A missing file yields an empty cache.
+func LoadVersionCache(path string) (VersionCache, error) {
+	cache := VersionCache{}
+
+	b, err := ioutil.ReadFile(path)
+
+	if os.IsNotExist(err) {
+		return cache, nil
+	}
+
+	if err != nil {
+		return cache, errors.Wrap(err, "could not read version cache")
+	}
+
+	if err := json.Unmarshal(b, &cache); err != nil {
+		return VersionCache{}, errors.Wrap(err, "could not parse version cache")
+	}
+
+	return cache, nil
+}
+
+// Save writes the cache to path.
+func (c VersionCache) Save(path string) error {
+	b, err := json.MarshalIndent(c, "", "\t")
+
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(path, b, 0600)
+}
+
+// NegotiateVersion returns the cached version for the session's org, querying the org and caching the result if
+// there isn't one yet.
+func NegotiateVersion(session Session, cache VersionCache) (string, error) {
+	key := strings.TrimSuffix(session.InstanceURL, "/")
+
+	if version, ok := cache[key]; ok && version != "" {
+		return version, nil
+	}
+
+	version, err := LatestVersion(session)
+
+	if err != nil {
+		return "", err
+	}
+
+	cache[key] = version
+
+	return version, nil
+}
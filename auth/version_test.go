@@ -0,0 +1,120 @@
+package auth
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSession_DataURL(t *testing.T) {
+	testCases := []struct {
+		session  Session
+		expected string
+	}{
+		{Session{InstanceURL: "https://na1.salesforce.com"}, "https://na1.salesforce.com/services/data/v" + DefaultAPIVersion},
+		{Session{InstanceURL: "https://na1.salesforce.com/", APIVersion: "58.0"}, "https://na1.salesforce.com/services/data/v58.0"},
+	}
+
+	for _, tc := range testCases {
+		if result := tc.session.DataURL(); result != tc.expected {
+			t.Error("expected: ", tc.expected, "received: ", result)
+		}
+	}
+}
+
+func TestCompareVersions(t *testing.T) {
+	testCases := []struct {
+		a, b     string
+		expected int
+	}{
+		{"43.0", "43.0", 0},
+		{"9.0", "43.0", -1},
+		{"58.0", "43.0", 1},
+		{"43.1", "43.0", 1},
+	}
+
+	for _, tc := range testCases {
+		result := CompareVersions(tc.a, tc.b)
+
+		if (result < 0 && tc.expected >= 0) || (result > 0 && tc.expected <= 0) || (result == 0 && tc.expected != 0) {
+			t.Error("comparing", tc.a, "and", tc.b, "expected sign of", tc.expected, "received", result)
+		}
+	}
+}
+
+func TestNegotiateVersion(t *testing.T) {
+	calls := 0
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+
+		if r.URL.Path != "/services/data/" {
+			t.Error("unexpected path", r.URL.Path)
+		}
+
+		data, _ := json.Marshal([]Version{
+			{Label: "Summer '18", URL: "/services/data/v43.0", Version: "43.0"},
+			{Label: "Winter '19", URL: "/services/data/v44.0", Version: "44.0"},
+			{Label: "Winter '11", URL: "/services/data/v20.0", Version: "20.0"},
+		})
+		w.Write(data)
+	}))
+	defer ts.Close()
+
+	session := Session{InstanceURL: ts.URL, AccessToken: "token123"}
+	cache := VersionCache{}
+
+	version, err := NegotiateVersion(session, cache)
+
+	if err != nil {
+		t.Error("expected no error", err)
+	}
+
+	if version != "44.0" {
+		t.Error("expected: 44.0 received: ", version)
+	}
+
+	version, _ = NegotiateVersion(session, cache)
+
+	if version != "44.0" || calls != 1 {
+		t.Error("expected cached version to be used, server called", calls, "times")
+	}
+}
+
+func TestVersionCache_Save(t *testing.T) {
+	dir, err := ioutil.TempDir("", "forcedata-test")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "versions.json")
+
+	empty, err := LoadVersionCache(path)
+
+	if err != nil || len(empty) != 0 {
+		t.Error("expected missing cache file to load as empty cache", err)
+	}
+
+	cache := VersionCache{"https://na1.salesforce.com": "44.0"}
+
+	if err := cache.Save(path); err != nil {
+		t.Error("expected no error saving cache", err)
+	}
+
+	result, err := LoadVersionCache(path)
+
+	if err != nil {
+		t.Error("expected no error loading cache", err)
+	}
+
+	if result["https://na1.salesforce.com"] != "44.0" {
+		t.Error("expected: 44.0 received: ", result["https://na1.salesforce.com"])
+	}
+}
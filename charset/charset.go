@@ -0,0 +1,229 @@
+// Package charset converts files to the UTF-8 content with LF line endings that jobs are created with. Files saved by
+// Excel on Windows are usually Windows-1252 or UTF-16 with CRLF line endings, which the Bulk API would otherwise store
+// as mangled characters.
+package charset
+
+import (
+	"bytes"
+	"encoding/binary"
+	"github.com/pkg/errors"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// Encodings that content can be decoded from.
+const (
+	Auto        = "auto"
+	UTF8        = "utf-8"
+	UTF16LE     = "utf-16le"
+	UTF16BE     = "utf-16be"
+	Windows1252 = "windows-1252"
+	Latin1      = "iso-8859-1"
+)
+
+// sampleSize is how much of the content Detect looks at when there is no byte order mark.
+const sampleSize = 4096
+
+var (
+	bomUTF8    = []byte{0xef, 0xbb, 0xbf}
+	bomUTF16LE = []byte{0xff, 0xfe}
+	bomUTF16BE = []byte{0xfe, 0xff}
+)
+
+var aliases = map[string]string{
+	"":            Auto,
+	"utf8":        UTF8,
+	"utf-16":      UTF16LE,
+	"utf16":       UTF16LE,
+	"utf16le":     UTF16LE,
+	"utf16be":     UTF16BE,
+	"unicode":     UTF16LE,
+	"cp1252":      Windows1252,
+	"windows1252": Windows1252,
+	"latin1":      Latin1,
+	"latin-1":     Latin1,
+	"iso8859-1":   Latin1,
+	"iso_8859-1":  Latin1,
+}
+
+// windows1252 holds the characters Windows-1252 places in 0x80-0x9F, where ISO-8859-1 has control characters. Every
+// other byte has the same value as its code point. The five bytes Windows-1252 leaves undefined keep their control
+// character.
+var windows1252 = [32]rune{
+	'€', 0x81, '‚', 'ƒ', '„', '…', '†', '‡', 'ˆ', '‰', 'Š', '‹', 'Œ', 0x8d, 'Ž', 0x8f,
+	0x90, '‘', '’', '“', '”', '•', '–', '—', '˜', '™', 'š', '›', 'œ', 0x9d, 'ž', 'Ÿ',
+}
+
+// Name returns the canonical name of an encoding, accepting common aliases in any case. It returns false if the
+// encoding isn't supported.
+func Name(encoding string) (string, bool) {
+	name := strings.ToLower(strings.TrimSpace(encoding))
+
+	if alias, ok := aliases[name]; ok {
+		return alias, true
+	}
+
+	switch name {
+	case Auto, UTF8, UTF16LE, UTF16BE, Windows1252, Latin1:
+		return name, true
+	}
+
+	return "", false
+}
+
+// Detect guesses the encoding of content. A byte order mark is trusted if there is one. Otherwise content with many
+// zero bytes in alternate positions is taken to be UTF-16, valid UTF-8 is UTF-8, and anything else is Windows-1252.
+func Detect(content []byte) string {
+	switch {
+	case bytes.HasPrefix(content, bomUTF8):
+		return UTF8
+	case bytes.HasPrefix(content, bomUTF16LE):
+		return UTF16LE
+	case bytes.HasPrefix(content, bomUTF16BE):
+		return UTF16BE
+	}
+
+	sample := content
+
+	if len(sample) > sampleSize {
+		sample = sample[:sampleSize]
+	}
+
+	var even, odd int
+
+	for i, b := range sample {
+		if b != 0 {
+			continue
+		}
+
+		if i%2 == 0 {
+			even++
+		} else {
+			odd++
+		}
+	}
+
+	pairs := len(sample) / 2
+
+	switch {
+	case pairs > 0 && odd > pairs/3 && even == 0:
+		return UTF16LE
+	case pairs > 0 && even > pairs/3 && odd == 0:
+		return UTF16BE
+	case utf8.Valid(content):
+		return UTF8
+	}
+
+	return Windows1252
+}
+
+// Decode converts content in the given encoding to UTF-8, removing any byte order mark. If encoding is Auto it's
+// detected first. It returns the encoding the content was decoded from.
+func Decode(content []byte, encoding string) ([]byte, string, error) {
+	name, ok := Name(encoding)
+
+	if !ok {
+		return nil, "", errors.Errorf("unsupported encoding %q", encoding)
+	}
+
+	if name == Auto {
+		name = Detect(content)
+	}
+
+	var (
+		decoded []byte
+		err     error
+	)
+
+	switch name {
+	case UTF8:
+		decoded, err = decodeUTF8(content)
+	case UTF16LE:
+		decoded, err = decodeUTF16(bytes.TrimPrefix(content, bomUTF16LE), binary.LittleEndian)
+	case UTF16BE:
+		decoded, err = decodeUTF16(bytes.TrimPrefix(content, bomUTF16BE), binary.BigEndian)
+	case Windows1252:
+		decoded = decodeSingleByte(content, true)
+	case Latin1:
+		decoded = decodeSingleByte(content, false)
+	}
+
+	if err != nil {
+		return nil, name, errors.Wrapf(err, "could not decode content as %s", name)
+	}
+
+	return decoded, name, nil
+}
+
+// NormalizeLineEndings replaces CRLF and lone CR line endings with LF.
+func NormalizeLineEndings(content []byte) []byte {
+	if bytes.IndexByte(content, '\r') == -1 {
+		return content
+	}
+
+	content = bytes.Replace(content, []byte("\r\n"), []byte("\n"), -1)
+
+	return bytes.Replace(content, []byte("\r"), []byte("\n"), -1)
+}
+
+// Normalize decodes content and normalizes its line endings, returning UTF-8 content with LF line endings and the
+// encoding it was decoded from.
+func Normalize(content []byte, encoding string) ([]byte, string, error) {
+	decoded, name, err := Decode(content, encoding)
+
+	if err != nil {
+		return nil, name, err
+	}
+
+	return NormalizeLineEndings(decoded), name, nil
+}
+
+func decodeUTF8(content []byte) ([]byte, error) {
+	content = bytes.TrimPrefix(content, bomUTF8)
+
+	if !utf8.Valid(content) {
+		return nil, errors.New("invalid UTF-8")
+	}
+
+	return content, nil
+}
+
+func decodeUTF16(content []byte, order binary.ByteOrder) ([]byte, error) {
+	if len(content)%2 != 0 {
+		return nil, errors.New("odd number of bytes")
+	}
+
+	units := make([]uint16, len(content)/2)
+
+	for i := range units {
+		units[i] = order.Uint16(content[i*2:])
+	}
+
+	var buf bytes.Buffer
+
+	for _, r := range utf16.Decode(units) {
+		buf.WriteRune(r)
+	}
+
+	return buf.Bytes(), nil
+}
+
+func decodeSingleByte(content []byte, cp1252 bool) []byte {
+	var buf bytes.Buffer
+
+	buf.Grow(len(content))
+
+	for _, b := range content {
+		switch {
+		case b < 0x80:
+			buf.WriteByte(b)
+		case cp1252 && b < 0xa0:
+			buf.WriteRune(windows1252[b-0x80])
+		default:
+			buf.WriteRune(rune(b))
+		}
+	}
+
+	return buf.Bytes()
+}
@@ -0,0 +1,87 @@
+package charset
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+// utf16le encodes ASCII s as UTF-16LE.
+func utf16le(s string) []byte {
+	var b []byte
+
+	for _, c := range []byte(s) {
+		b = append(b, c, 0)
+	}
+
+	return b
+}
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		name    string
+		content []byte
+		want    string
+	}{
+		{"ascii", []byte("Name\nacme\n"), UTF8},
+		{"utf-8", []byte("Name\nJosé\n"), UTF8},
+		{"utf-8 bom", []byte("\xef\xbb\xbfName\n"), UTF8},
+		{"utf-16le bom", append([]byte{0xff, 0xfe}, utf16le("Name\n")...), UTF16LE},
+		{"utf-16be bom", []byte{0xfe, 0xff, 0, 'N'}, UTF16BE},
+		{"utf-16le", utf16le("Name,Email\nacme,a@example.com\n"), UTF16LE},
+		{"windows-1252", []byte("Name\nJos\xe9\n"), Windows1252},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.want, Detect(test.content))
+		})
+	}
+}
+
+func TestDecode(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  []byte
+		encoding string
+		want     string
+		decoded  string
+	}{
+		{"utf-8 bom", []byte("\xef\xbb\xbfName\nJosé\n"), Auto, "Name\nJosé\n", UTF8},
+		{"utf-16le bom", append([]byte{0xff, 0xfe}, 'J', 0, 'o', 0, 0xe9, 0), Auto, "Joé", UTF16LE},
+		{"utf-16be", []byte{0, 'J', 0x20, 0xac}, "UTF-16BE", "J€", UTF16BE},
+		{"windows-1252", []byte("Jos\xe9 \x93quoted\x94 \x80"), Auto, "José “quoted” €", Windows1252},
+		{"cp1252 alias", []byte("\x9c"), "cp1252", "œ", Windows1252},
+		{"latin1", []byte("Jos\xe9 \x80"), "latin1", "José \u0080", Latin1},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			actual, name, err := Decode(test.content, test.encoding)
+
+			assert.NoError(t, err)
+			assert.Equal(t, test.want, string(actual))
+			assert.Equal(t, test.decoded, name)
+		})
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	_, _, err := Decode([]byte("abc"), "ebcdic")
+	assert.EqualError(t, err, `unsupported encoding "ebcdic"`)
+
+	_, _, err = Decode([]byte("Jos\xe9"), UTF8)
+	assert.EqualError(t, err, "could not decode content as utf-8: invalid UTF-8")
+
+	_, _, err = Decode([]byte{0xff, 0xfe, 'a'}, UTF16LE)
+	assert.EqualError(t, err, "could not decode content as utf-16le: odd number of bytes")
+}
+
+func TestNormalize(t *testing.T) {
+	content := append([]byte{0xff, 0xfe}, utf16le("Name,Description\r\nJos\r\n\"a\rb\"\r\n")...)
+
+	actual, name, err := Normalize(content, Auto)
+
+	assert.NoError(t, err)
+	assert.Equal(t, UTF16LE, name)
+	assert.Equal(t, "Name,Description\nJos\n\"a\nb\"\n", string(actual))
+}
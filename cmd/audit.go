@@ -0,0 +1,287 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/mitchellh/go-homedir"
+	"github.com/pkg/errors"
+	"github.com/rfaulhaber/forcedata/audit"
+	"github.com/rfaulhaber/forcedata/auth"
+	"github.com/rfaulhaber/forcedata/replay"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+// auditCmd represents the audit command
+var auditCmd = &cobra.Command{
+	Use:   "audit COMMAND",
+	Short: "Shows the audit log of changes made to orgs",
+	Long: `Every command appends what it does that changes an org to an audit log: authenticating, and creating,
+uploading to, closing, aborting and deleting jobs, along with the state each job it changed finished in. Each entry
+records the org and user, the object and operation, the file loaded and its hash, the records uploaded and processed,
+the job and the command line.
+
+The log is audit.jsonl in the config directory, or the audit_log setting of the config file. With "audit_chain: true"
+each entry also carries a hash chaining it to the entry before it, so that entries that are changed or removed can be
+found with audit verify.`,
+}
+
+// auditListCmd represents the audit list command
+var auditListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "Lists the entries of the audit log",
+	Args:  cobra.NoArgs,
+	Run:   runAuditList,
+}
+
+// auditShowCmd represents the audit show command
+var auditShowCmd = &cobra.Command{
+	Use:   "show ENTRY|JOBID",
+	Short: "Shows an entry of the audit log in full, or every entry of a job",
+	Args:  cobra.ExactArgs(1),
+	Run:   runAuditShow,
+}
+
+// auditVerifyCmd represents the audit verify command
+var auditVerifyCmd = &cobra.Command{
+	Use:   "verify",
+	Short: "Checks that the chained entries of the audit log haven't been changed or removed",
+	Args:  cobra.NoArgs,
+	Run:   runAuditVerify,
+}
+
+var auditListOpts struct {
+	job    string
+	action string
+	last   int
+}
+
+// secretFlags are the flags whose values are redacted from command lines written to the audit log.
+var secretFlags = []string{"--password", "--client-secret", "--mask-seed"}
+
+// auditor appends the changes commands make to orgs to the audit log. It's nil when the log isn't kept, with --replay.
+var auditor *audit.Transport
+
+func init() {
+	rootCmd.AddCommand(auditCmd)
+	auditCmd.AddCommand(auditListCmd, auditShowCmd, auditVerifyCmd)
+
+	auditListCmd.Flags().StringVar(&auditListOpts.job, "job", "", "Only lists entries of this job.")
+	auditListCmd.Flags().StringVar(&auditListOpts.action, "action", "", "Only lists entries of this action: authenticate, create, upload, state, abort or delete.")
+	auditListCmd.Flags().IntVar(&auditListOpts.last, "last", 0, "Only lists this many of the latest entries.")
+}
+
+// auditOutput is the result of audit list and show.
+type auditOutput struct {
+	Type    string        `json:"type"`
+	Entries []audit.Entry `json:"entries"`
+}
+
+// auditPath returns the path of the audit log.
+func auditPath() (string, error) {
+	if path := viper.GetString("audit_log"); path != "" {
+		return path, nil
+	}
+
+	home, err := homedir.Dir()
+
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(configDir(home), "audit.jsonl"), nil
+}
+
+// initAudit routes all HTTP traffic through the auditor, unless it's being answered from recordings with --replay,
+// which changes no org.
+func initAudit() {
+	if replayFlag != "" {
+		return
+	}
+
+	path, err := auditPath()
+
+	if err != nil {
+		fatal(err, "could not find the audit log:")
+	}
+
+	auditor = audit.NewTransport(audit.Open(path, viper.GetBool("audit_chain")), http.DefaultClient.Transport, commandLine(os.Args))
+	auditor.Error = func(err error) {
+		log.Println("warning: could not write the audit log:", err)
+	}
+
+	http.DefaultClient.Transport = auditor
+}
+
+// auditSession records the org and user of session in the audit log's entries.
+func auditSession(session auth.Session) {
+	if auditor != nil {
+		auditor.SetSession(session.ID, session.InstanceURL)
+	}
+}
+
+// auditFile records the file being loaded, and the hash of its contents, in the audit log's entries.
+func auditFile(file, hash string) {
+	if auditor != nil {
+		auditor.SetFile(file, hash)
+	}
+}
+
+// commandLine returns a command line as it's written to the audit log, with the values of secretFlags redacted.
+func commandLine(args []string) string {
+	redacted := make([]string, len(args))
+	copy(redacted, args)
+
+	for i := 0; i < len(redacted); i++ {
+		for _, flag := range secretFlags {
+			switch {
+			case redacted[i] == flag && i+1 < len(redacted):
+				i++
+				redacted[i] = replay.Redacted
+			case strings.HasPrefix(redacted[i], flag+"="):
+				redacted[i] = flag + "=" + replay.Redacted
+			}
+		}
+	}
+
+	return strings.Join(redacted, " ")
+}
+
+func readAudit() []audit.Entry {
+	path, err := auditPath()
+
+	if err != nil {
+		fatal(err)
+	}
+
+	entries, err := audit.Read(path)
+
+	if os.IsNotExist(errors.Cause(err)) {
+		return nil
+	}
+
+	if err != nil {
+		fatal(err)
+	}
+
+	return entries
+}
+
+func runAuditList(cmd *cobra.Command, args []string) {
+	entries := readAudit()
+
+	if err := audit.Verify(entries); err != nil {
+		log.Println("warning: the audit log was tampered with:", err)
+	}
+
+	var (
+		buf     bytes.Buffer
+		numbers []int
+	)
+
+	out := auditOutput{Type: "audit", Entries: []audit.Entry{}}
+
+	for i, e := range entries {
+		if auditListOpts.job != "" && e.JobID != auditListOpts.job || auditListOpts.action != "" && !strings.EqualFold(e.Action, auditListOpts.action) {
+			continue
+		}
+
+		numbers = append(numbers, i+1)
+		out.Entries = append(out.Entries, e)
+	}
+
+	if auditListOpts.last > 0 && len(out.Entries) > auditListOpts.last {
+		numbers = numbers[len(numbers)-auditListOpts.last:]
+		out.Entries = out.Entries[len(out.Entries)-auditListOpts.last:]
+	}
+
+	w := tabwriter.NewWriter(&buf, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "ENTRY\tTIME\tACTION\tORG\tUSER\tOBJECT\tOPERATION\tJOB\tSTATE\tRECORDS\tSTATUS")
+
+	for i, e := range out.Entries {
+		records := ""
+
+		if e.Records > 0 {
+			records = strconv.Itoa(e.Records)
+		} else if e.Processed > 0 {
+			records = fmt.Sprintf("%d processed, %d failed", e.Processed, e.Failed)
+		}
+
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%d\n", numbers[i], e.Time.Format("2006-01-02 15:04:05"), e.Action,
+			e.Org, e.User, e.Object, e.Operation, e.JobID, e.State, records, e.Status)
+	}
+
+	w.Flush()
+	stdWriter.Print(buf.String())
+
+	result(out)
+}
+
+func runAuditShow(cmd *cobra.Command, args []string) {
+	entries := readAudit()
+	out := auditOutput{Type: "audit", Entries: []audit.Entry{}}
+
+	if n, err := strconv.Atoi(args[0]); err == nil {
+		if n < 1 || n > len(entries) {
+			fatal(errors.Errorf("the audit log has no entry %d; it has %d", n, len(entries)))
+		}
+
+		out.Entries = append(out.Entries, entries[n-1])
+	} else {
+		for _, e := range entries {
+			if e.JobID == args[0] {
+				out.Entries = append(out.Entries, e)
+			}
+		}
+
+		if len(out.Entries) == 0 {
+			fatal(errors.Errorf("the audit log has no entries of job %s", args[0]))
+		}
+	}
+
+	for _, e := range out.Entries {
+		b, err := json.MarshalIndent(e, "", "  ")
+
+		if err != nil {
+			fatal(err)
+		}
+
+		stdWriter.Println(string(b))
+	}
+
+	result(out)
+}
+
+func runAuditVerify(cmd *cobra.Command, args []string) {
+	entries := readAudit()
+
+	if err := audit.Verify(entries); err != nil {
+		fatal(err, "the audit log was tampered with:")
+	}
+
+	chained := 0
+
+	for _, e := range entries {
+		if e.Hash != "" {
+			chained++
+		}
+	}
+
+	stdWriter.Printf("%d entries, %d of them chained; the chain is intact", len(entries), chained)
+	result(auditVerifyOutput{Type: "audit-verify", Entries: len(entries), Chained: chained})
+}
+
+// auditVerifyOutput is the result of audit verify.
+type auditVerifyOutput struct {
+	Type    string `json:"type"`
+	Entries int    `json:"entries"`
+	Chained int    `json:"chained"`
+}
@@ -63,7 +63,7 @@ func runAuthenticate(cmd *cobra.Command, args []string) {
 		b, err := ioutil.ReadFile(args[0])
 
 		if err != nil {
-			log.Fatalln("could not read file: ", err)
+			fatal(err, "could not read file:")
 		}
 
 		session, err := auth.AuthenticateFromFile(b)
@@ -73,7 +73,7 @@ func runAuthenticate(cmd *cobra.Command, args []string) {
 			case auth.MissingFieldError:
 				log.Println("A required field for authentication is missing from your file. ", err.Error())
 			default:
-				log.Fatalln("error message:", err)
+				fatal(withCode(errAuthentication, err), "error message:")
 			}
 		}
 
@@ -82,11 +82,11 @@ func runAuthenticate(cmd *cobra.Command, args []string) {
 		err = json.Unmarshal(b, &cred)
 
 		if err != nil {
-			log.Fatalln("something went wrong: ", err)
+			fatal(err, "something went wrong:")
 		}
 
 		if !auth.ValidateSession(session, cred.ClientSecret) {
-			log.Fatalln("The signature received from the server isn't valid! Your connection may not be secure!")
+			fatal(withCode(errAuthentication, errors.New("The signature received from the server isn't valid! Your connection may not be secure!")))
 		}
 
 		writeOut(session)
@@ -112,7 +112,7 @@ func writeOut(session auth.Session) {
 
 		if err != nil {
 			verbose.Println("os.Create encountered error")
-			log.Fatalln(err)
+			fatal(err)
 		}
 
 		auth.WriteSession(session, outFile)
@@ -0,0 +1,128 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"github.com/pkg/errors"
+	"github.com/rfaulhaber/forcedata/job"
+	"github.com/rfaulhaber/forcedata/journal"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// dryRunSamples is the number of rows of each chunk a dry run prints.
+const dryRunSamples = 3
+
+// dryRun reports the jobs a load would run: the config each is created with, and the size and first rows of the
+// content each would be uploaded. With --dry-run-dir, the content is written to chunk-N.csv files as well.
+func dryRun(config job.JobConfig, source string, raw, content []byte, comma rune, header []string, rows [][]string) error {
+	records := len(rows)
+	jr := journal.New("", source, raw, config)
+	jr.Split(records, flags.chunkSizeFlag)
+
+	runner := journal.Runner{Journal: jr, Content: content, Comma: comma, Header: header, Records: rows}
+
+	payloads, err := runner.Payloads()
+
+	if err != nil {
+		return err
+	}
+
+	b, err := json.MarshalIndent(config, "", "  ")
+
+	if err != nil {
+		return err
+	}
+
+	stdWriter.Printf("Dry run: nothing will be created in the org. Each job would be created with:\n%s", b)
+	stdWriter.Printf("%d records in %d jobs", records, len(payloads))
+
+	if flags.dryRunDirFlag != "" {
+		if err := os.MkdirAll(flags.dryRunDirFlag, 0755); err != nil {
+			return err
+		}
+	}
+
+	out := dryRunOutput{Type: "dry-run", Config: config, Records: records}
+
+	for i, payload := range payloads {
+		c := jr.Chunks[i]
+		chunk := dryRunChunk{Chunk: i + 1, Start: c.Start + 1, End: c.End, Rows: c.End - c.Start, Bytes: len(payload)}
+
+		stdWriter.Printf("\nJob %d: records %d to %d, %d rows, %d bytes", i+1, c.Start+1, c.End, c.End-c.Start, len(payload))
+
+		for _, line := range sampleLines(payload, runner.Comma, dryRunSamples+1) {
+			stdWriter.Println("  " + line)
+		}
+
+		if c.End-c.Start > dryRunSamples {
+			stdWriter.Printf("  ... %d more rows", c.End-c.Start-dryRunSamples)
+		}
+
+		if flags.dryRunDirFlag != "" {
+			chunk.File = filepath.Join(flags.dryRunDirFlag, fmt.Sprintf("chunk-%d.csv", i+1))
+
+			if err := ioutil.WriteFile(chunk.File, payload, 0644); err != nil {
+				return errors.Wrap(err, "could not write "+chunk.File)
+			}
+
+			stdWriter.Println("  written to", chunk.File)
+		}
+
+		out.Jobs = append(out.Jobs, chunk)
+	}
+
+	result(out)
+
+	return nil
+}
+
+// dryRunOutput is the result of a dry run with --output json or ndjson.
+type dryRunOutput struct {
+	Type    string        `json:"type"`
+	Config  job.JobConfig `json:"config"`
+	Records int           `json:"records"`
+	Jobs    []dryRunChunk `json:"jobs"`
+}
+
+// dryRunChunk is a job a dry run would run. Start and End are 1-based and inclusive.
+type dryRunChunk struct {
+	Chunk int    `json:"chunk"`
+	Start int    `json:"start"`
+	End   int    `json:"end"`
+	Rows  int    `json:"rows"`
+	Bytes int    `json:"bytes"`
+	File  string `json:"file,omitempty"`
+}
+
+// sampleLines returns the first n records of CSV content, each as a line of CSV.
+func sampleLines(content []byte, comma rune, n int) []string {
+	var lines []string
+
+	r := csv.NewReader(bytes.NewReader(content))
+	r.Comma = comma
+	r.FieldsPerRecord = -1
+
+	for len(lines) < n {
+		row, err := r.Read()
+
+		if err != nil {
+			break
+		}
+
+		var buf bytes.Buffer
+
+		w := csv.NewWriter(&buf)
+		w.Comma = comma
+		w.Write(row)
+		w.Flush()
+
+		lines = append(lines, strings.TrimSuffix(buf.String(), "\n"))
+	}
+
+	return lines
+}
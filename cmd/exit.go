@@ -0,0 +1,151 @@
+package cmd
+
+import (
+	"fmt"
+	"github.com/pkg/errors"
+	"github.com/rfaulhaber/forcedata/job"
+	"github.com/rfaulhaber/forcedata/validate"
+	"log"
+	"os"
+	"strings"
+)
+
+// Exit codes. These are documented in the README and must stay stable.
+const (
+	exitOK                   = 0
+	exitError                = 1
+	exitPartialFailure       = 2
+	exitTotalFailure         = 3
+	exitJobFailed            = 4
+	exitValidation           = 5
+	exitInvalidSession       = 10
+	exitAPIDisabled          = 11
+	exitRequestLimitExceeded = 12
+	exitInvalidJob           = 13
+	exitInvalidField         = 14
+)
+
+// Errors that say how a load went rather than what went wrong, which are given exit codes with withCode.
+var (
+	errPartialFailure = errors.New("some records failed")
+	errTotalFailure   = errors.New("every record failed")
+	errJobFailed      = errors.New("job failed or was aborted")
+	errAuthentication = errors.New("authentication failed")
+)
+
+var exitCodes = []struct {
+	err  error
+	code int
+	name string
+}{
+	{job.ErrInvalidSession, exitInvalidSession, "INVALID_SESSION"},
+	{job.ErrAPIDisabled, exitAPIDisabled, "API_DISABLED"},
+	{job.ErrRequestLimitExceeded, exitRequestLimitExceeded, "REQUEST_LIMIT_EXCEEDED"},
+	{job.ErrInvalidJob, exitInvalidJob, "INVALID_JOB"},
+	{job.ErrInvalidField, exitInvalidField, "INVALID_FIELD"},
+	{validate.ErrInvalid, exitValidation, "VALIDATION_FAILED"},
+	{errPartialFailure, exitPartialFailure, "PARTIAL_FAILURE"},
+	{errTotalFailure, exitTotalFailure, "TOTAL_FAILURE"},
+	{errJobFailed, exitJobFailed, "JOB_FAILED"},
+	{errAuthentication, exitInvalidSession, "AUTHENTICATION_FAILED"},
+}
+
+// codedError is an error that matches a sentinel with errors.Is, for its exit code, without the sentinel changing its
+// message.
+type codedError struct {
+	err      error
+	sentinel error
+}
+
+func (e *codedError) Error() string {
+	return e.err.Error()
+}
+
+func (e *codedError) Is(target error) bool {
+	return target == e.sentinel
+}
+
+func (e *codedError) Unwrap() error {
+	return e.err
+}
+
+// withCode returns err matching sentinel, so that the program exits with the code of sentinel.
+func withCode(sentinel, err error) error {
+	return &codedError{err, sentinel}
+}
+
+// exitCode maps an error to the exit code the program should terminate with.
+func exitCode(err error) int {
+	if err == nil {
+		return exitOK
+	}
+
+	for _, e := range exitCodes {
+		if errors.Is(err, e.err) {
+			return e.code
+		}
+	}
+
+	return exitError
+}
+
+// errorName returns the code an error is reported with by --output json and ndjson, which like the exit code is
+// mapped from err.
+func errorName(err error) string {
+	for _, e := range exitCodes {
+		if errors.Is(err, e.err) {
+			return e.name
+		}
+	}
+
+	return "ERROR"
+}
+
+// errorDetails returns what's known about an error beyond its message: the response to a rejected request, or the
+// problems validation found.
+func errorDetails(err error) map[string]interface{} {
+	var (
+		requestErr *job.RequestError
+		invalid    *validationError
+	)
+
+	switch {
+	case errors.As(err, &requestErr):
+		return map[string]interface{}{
+			"status":    requestErr.StatusCode,
+			"requestId": requestErr.RequestID,
+			"errors":    requestErr.Errors,
+		}
+	case errors.As(err, &invalid):
+		return map[string]interface{}{"problems": invalid.issues}
+	}
+
+	return map[string]interface{}{}
+}
+
+// fatal logs v followed by err, or writes them as JSON with --output json or ndjson, and exits with the exit code
+// mapped from err.
+func fatal(err error, v ...interface{}) {
+	if machineOutput() {
+		writeError(err, v...)
+	} else {
+		log.Println(append(v, err)...)
+	}
+
+	os.Exit(exitCode(err))
+}
+
+// warn logs v followed by err as a warning, or writes them as JSON with the code WARNING with --output json or ndjson,
+// for errors that don't stop the command.
+func warn(err error, v ...interface{}) {
+	if !machineOutput() {
+		log.Println(append(append([]interface{}{"warning:"}, v...), err)...)
+		return
+	}
+
+	writeJSON(os.Stderr, errorOutput{
+		Code:    "WARNING",
+		Message: strings.TrimSuffix(fmt.Sprintln(append(v, err)...), "\n"),
+		Details: errorDetails(err),
+	})
+}
@@ -0,0 +1,156 @@
+package cmd
+
+import (
+	"fmt"
+	"github.com/pkg/errors"
+	"github.com/rfaulhaber/forcedata/export"
+	"github.com/rfaulhaber/forcedata/mask"
+	"github.com/rfaulhaber/forcedata/pipeline"
+	"github.com/spf13/cobra"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+)
+
+// exportCmd represents the export command
+var exportCmd = &cobra.Command{
+	Use:   "export DIR",
+	Short: "Exports related records into a directory that import can load",
+	Long: `Exports the records selected by --root, and the records of each object given to --include that refer to them,
+into a directory of CSV files. Included objects are exported in the order given, each by its references to the objects
+exported before it, e.g.:
+
+    data export --root "SELECT Id FROM Account WHERE Industry = 'Banking' LIMIT 500" \
+        --include Contact,Opportunity,Case seed/
+
+The directory also holds manifest.yaml, a plan that inserts the files in order with their references remapped to the
+new records. Load it into another org with import. References to objects that weren't exported are left out, apart
+from record types.
+
+With --mask, each object's fields are masked as they would be by the mask command before the files are written. Masked
+values are the same in every file, so masking a field that identifies records doesn't break references.`,
+	Args: cobra.ExactArgs(1),
+	Run:  runExport,
+}
+
+var exportOpts struct {
+	root    string
+	include []string
+	mask    string
+	seed    string
+}
+
+func init() {
+	rootCmd.AddCommand(exportCmd)
+
+	exportCmd.Flags().StringVar(&exportOpts.root, "root", "", "SOQL query selecting the records to start from.")
+	exportCmd.Flags().StringSliceVar(&exportOpts.include, "include", nil, "Related objects to export, in order.")
+
+	exportCmd.Flags().StringVar(&exportOpts.mask, "mask", "", "YAML file of masking rules applied to the exported records.")
+	exportCmd.Flags().StringVar(&exportOpts.seed, "mask-seed", "", "Secret that keys masked values. Required with --mask.")
+
+	exportCmd.MarkFlagRequired("root")
+}
+
+func runExport(cmd *cobra.Command, args []string) {
+	session, err := getSession()
+
+	if err != nil {
+		fatal(err)
+	}
+
+	dir := args[0]
+
+	var rules *mask.Rules
+
+	if exportOpts.mask != "" {
+		if rules, err = mask.Load(exportOpts.mask); err != nil {
+			fatal(err)
+		}
+
+		if exportOpts.seed == "" {
+			fatal(errors.New("--mask-seed is required with --mask"))
+		}
+	}
+
+	objects, err := export.Export(session, exportOpts.root, exportOpts.include, dir)
+
+	if err != nil {
+		fatal(errors.Wrap(err, "export failed"))
+	}
+
+	if rules != nil {
+		if err := maskExport(dir, objects, rules, exportOpts.seed); err != nil {
+			fatal(err, "could not mask export:")
+		}
+	}
+
+	if err := export.WriteManifest(dir, session, exportOpts.root, objects); err != nil {
+		fatal(err)
+	}
+
+	var counts []string
+
+	for _, obj := range objects {
+		counts = append(counts, fmt.Sprintf("%d %s", obj.Records, obj.Name))
+	}
+
+	stdWriter.Println("Exported", strings.Join(counts, ", "))
+
+	out := exportOutput{Type: "export", Dir: dir, Objects: []exportObject{}}
+
+	for _, obj := range objects {
+		out.Objects = append(out.Objects, exportObject{obj.Name, obj.File, obj.Records})
+	}
+
+	result(out)
+}
+
+// exportOutput is the result of export with --output json or ndjson.
+type exportOutput struct {
+	Type    string         `json:"type"`
+	Dir     string         `json:"dir"`
+	Objects []exportObject `json:"objects"`
+}
+
+type exportObject struct {
+	Name    string `json:"name"`
+	File    string `json:"file"`
+	Records int    `json:"records"`
+}
+
+// maskExport masks the files of the exported objects that have rules, rewriting them in place.
+func maskExport(dir string, objects []export.Object, rules *mask.Rules, seed string) error {
+	for _, obj := range objects {
+		if !rules.Has(obj.Name) {
+			continue
+		}
+
+		m, err := rules.Masker(obj.Name, seed)
+
+		if err != nil {
+			return err
+		}
+
+		path := filepath.Join(dir, obj.File)
+		content, err := ioutil.ReadFile(path)
+
+		if err != nil {
+			return err
+		}
+
+		rejectPath := filepath.Join(dir, obj.Name+".rejects.csv")
+
+		if content, err = transformContent(content, ",", []pipeline.Stage{m}, rejectPath, nil); err != nil {
+			return err
+		}
+
+		if err := ioutil.WriteFile(path, content, 0644); err != nil {
+			return err
+		}
+
+		verbose.Println("masked", obj.File)
+	}
+
+	return nil
+}
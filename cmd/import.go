@@ -0,0 +1,53 @@
+package cmd
+
+import (
+	"github.com/rfaulhaber/forcedata/export"
+	"github.com/rfaulhaber/forcedata/job"
+	"github.com/rfaulhaber/forcedata/plan"
+	"github.com/spf13/cobra"
+	"path/filepath"
+	"time"
+)
+
+// importCmd represents the import command
+var importCmd = &cobra.Command{
+	Use:   "import DIR",
+	Short: "Loads a directory written by export",
+	Long: `Loads a directory written by export by applying its manifest.yaml plan: objects are inserted in dependency
+order, and references between them are replaced with the IDs of the new records. Rejected rows and failed results are
+written to the directory unless --results-dir is given.`,
+	Args: cobra.ExactArgs(1),
+	Run:  runImport,
+}
+
+var importOpts struct {
+	parallel   int
+	poll       time.Duration
+	resultsDir string
+}
+
+func init() {
+	rootCmd.AddCommand(importCmd)
+
+	importCmd.Flags().IntVar(&importOpts.parallel, "parallel", plan.DefaultParallel, "Most objects loaded at once.")
+	importCmd.Flags().DurationVar(&importOpts.poll, "poll", job.DefaultWatchTime, "How often running jobs are checked.")
+	importCmd.Flags().StringVar(&importOpts.resultsDir, "results-dir", "", "Directory rejected rows and failed results are written to. Defaults to DIR.")
+}
+
+func runImport(cmd *cobra.Command, args []string) {
+	dir := args[0]
+
+	p, err := plan.Load(filepath.Join(dir, export.ManifestFile))
+
+	if err != nil {
+		fatal(err)
+	}
+
+	resultsDir := importOpts.resultsDir
+
+	if resultsDir == "" {
+		resultsDir = dir
+	}
+
+	applyPlan(p, importOpts.parallel, importOpts.poll, resultsDir)
+}
@@ -0,0 +1,139 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/pkg/errors"
+	"github.com/rfaulhaber/forcedata/auth"
+	"github.com/rfaulhaber/forcedata/limits"
+	"github.com/spf13/cobra"
+	"log"
+	"text/tabwriter"
+)
+
+// limitWarnRatio is the fraction of a limit's max below which load warns about the remaining allocation.
+const limitWarnRatio = 0.1
+
+// limitsCmd represents the limits command
+var limitsCmd = &cobra.Command{
+	Use:   "limits",
+	Short: "Shows the org's Bulk API and API request limits",
+	Long: `Shows the remaining and maximum values of the org's daily Bulk API ingest batches, Bulk API query jobs and
+storage, and API requests.`,
+	Args: cobra.NoArgs,
+	Run:  runLimits,
+}
+
+func init() {
+	rootCmd.AddCommand(limitsCmd)
+}
+
+func runLimits(cmd *cobra.Command, args []string) {
+	session, err := getSession()
+
+	if err != nil {
+		fatal(err)
+	}
+
+	l, err := limits.Get(session)
+
+	if err != nil {
+		fatal(err, "could not retrieve limits:")
+	}
+
+	printLimits(l)
+}
+
+func printLimits(l limits.Limits) {
+	var buf bytes.Buffer
+
+	w := tabwriter.NewWriter(&buf, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "LIMIT\tREMAINING\tMAX")
+
+	for _, name := range limits.Relevant {
+		if limit, ok := l[name]; ok {
+			fmt.Fprintf(w, "%s\t%d\t%d\n", name, limit.Remaining, limit.Max)
+		}
+	}
+
+	w.Flush()
+	stdWriter.Print(buf.String())
+
+	out := limitsOutput{Type: "limits", Limits: []limitOutput{}}
+
+	for _, name := range limits.Relevant {
+		if limit, ok := l[name]; ok {
+			out.Limits = append(out.Limits, limitOutput{name, limit.Remaining, limit.Max})
+		}
+	}
+
+	result(out)
+}
+
+// limitsOutput is the result of the limits command with --output json or ndjson.
+type limitsOutput struct {
+	Type   string        `json:"type"`
+	Limits []limitOutput `json:"limits"`
+}
+
+type limitOutput struct {
+	Name      string `json:"name"`
+	Remaining int    `json:"remaining"`
+	Max       int    `json:"max"`
+}
+
+// checkLimits refuses a load that would exceed the org's remaining allocation unless force is set, and warns about one
+// that would come close. It returns the limits it saw so that consumption can be reported afterwards.
+func checkLimits(session auth.Session, records int, jobs int, force bool) (limits.Limits, bool) {
+	before, err := limits.Get(session)
+
+	if err != nil {
+		log.Println("warning: could not check org limits before loading:", err)
+		return nil, false
+	}
+
+	problems := before.Check(limits.IngestUsage(records, jobs), limitWarnRatio)
+
+	exceeded := false
+
+	for _, p := range problems {
+		if p.Exceeded {
+			exceeded = true
+			log.Printf("%s: this load needs about %d but only %d of %d remain", p.Name, p.Needed, p.Remaining, p.Max)
+		} else {
+			log.Printf("warning: %s: this load needs about %d, leaving %d of %d", p.Name, p.Needed, p.Remaining-p.Needed, p.Max)
+		}
+	}
+
+	if exceeded {
+		if !force {
+			fatal(errors.New("load would exceed the org's remaining limits; rerun with --force to load anyway"))
+		}
+
+		log.Println("warning: loading anyway because --force was specified")
+	}
+
+	return before, true
+}
+
+// reportConsumption prints how much of each relevant limit was used since before was retrieved, and returns it.
+func reportConsumption(session auth.Session, before limits.Limits) limits.Usage {
+	after, err := limits.Get(session)
+
+	if err != nil {
+		verbose.Println("could not retrieve limits after loading:", err)
+		return nil
+	}
+
+	consumed := limits.Consumed(before, after)
+	relevant := limits.Usage{}
+
+	for _, name := range limits.Relevant {
+		if used, ok := consumed[name]; ok {
+			stdWriter.Printf("%s consumed: %d (remaining: %d of %d)\n", name, used, after[name].Remaining, after[name].Max)
+			relevant[name] = used
+		}
+	}
+
+	return relevant
+}
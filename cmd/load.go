@@ -1,28 +1,75 @@
 package cmd
 
 import (
+	"bytes"
+	"encoding/csv"
+	"github.com/mitchellh/go-homedir"
 	"github.com/pkg/errors"
+	"github.com/rfaulhaber/forcedata/audit"
 	"github.com/rfaulhaber/forcedata/auth"
+	"github.com/rfaulhaber/forcedata/charset"
+	"github.com/rfaulhaber/forcedata/describe"
+	"github.com/rfaulhaber/forcedata/dialect"
+	"github.com/rfaulhaber/forcedata/display"
+	"github.com/rfaulhaber/forcedata/flatten"
 	"github.com/rfaulhaber/forcedata/job"
+	"github.com/rfaulhaber/forcedata/journal"
+	"github.com/rfaulhaber/forcedata/limits"
+	"github.com/rfaulhaber/forcedata/mapping"
+	"github.com/rfaulhaber/forcedata/pipeline"
+	"github.com/rfaulhaber/forcedata/report"
+	"github.com/rfaulhaber/forcedata/resolve"
+	"github.com/rfaulhaber/forcedata/transform"
+	"github.com/rfaulhaber/forcedata/validate"
+	"github.com/rfaulhaber/forcedata/xlsx"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
+	"io"
+	"io/ioutil"
 	"log"
 	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
 	"strings"
-	"io/ioutil"
-	"io"
 	"time"
-	"fmt"
+	"unicode/utf8"
 )
 
 type flagStr struct {
-	objFlag    string
-	delimFlag  string
-	watchFlag  time.Duration
-	insertFlag bool
-	updateFlag bool
-	upsertFlag bool
-	deleteFlag bool
+	objFlag            string
+	delimFlag          string
+	encodingFlag       string
+	quoteFlag          string
+	quoteChanged       bool // whether --quote was given, rather than defaulted, so --delim auto doesn't sniff it
+	commentFlag        string
+	skipRowsFlag       int
+	externalIDFlag     string
+	mappingFlag        string
+	transformsFlag     string
+	maskFlag           string
+	maskSeedFlag       string
+	rejectFileFlag     string
+	watchFlag          time.Duration
+	insertFlag         bool
+	updateFlag         bool
+	upsertFlag         bool
+	deleteFlag         bool
+	forceFlag          bool
+	skipValidationFlag bool
+	resumeFlag         bool
+	reloadFlag         bool
+	chunkSizeFlag      int
+	journalDirFlag     string
+	dryRunFlag         bool
+	dryRunDirFlag      string
+	maxFailuresFlag    int
+	maxFailureRateFlag string
+	sheetFlag          string
+	headerRowFlag      int
+	formatFlag         string
+	fieldsFlag         []string
+	summaryFlag        string
 }
 
 var flags flagStr
@@ -30,7 +77,7 @@ var flags flagStr
 // loadCmd represents the load command
 var loadCmd = &cobra.Command{
 	Use:     "load [FILES...]",
-	Short:   "Load data from a CSV file.",
+	Short:   "Load data from a CSV file or Excel workbook.",
 	Long:    `Generic data loading operation, for inserting, updating, upserting, and deleting records.`,
 	PreRunE: preRunLoad,
 	Run:     runLoad,
@@ -39,13 +86,38 @@ var loadCmd = &cobra.Command{
 
 func init() {
 	rootCmd.AddCommand(loadCmd)
-	loadCmd.Flags().StringVar(&flags.delimFlag, "delim", ",", "Delimiter used in files.")
+	loadCmd.Flags().StringVar(&flags.delimFlag, "delim", ",", "Delimiter used in files, or auto to detect it.")
+	loadCmd.Flags().StringVar(&flags.quoteFlag, "quote", `"`, "Quote character used in files, or none.")
+	loadCmd.Flags().StringVar(&flags.commentFlag, "comment", "", "Lines starting with this character are ignored.")
+	loadCmd.Flags().IntVar(&flags.skipRowsFlag, "skip-rows", 0, "Number of lines before the header to ignore.")
+	loadCmd.Flags().StringVar(&flags.formatFlag, "format", "csv", "Format of files: csv, json or ndjson. .xlsx workbooks are read as csv.")
+	loadCmd.Flags().StringSliceVar(&flags.fieldsFlag, "fields", nil, "Fields loaded from json and ndjson records (default is every key of every record)")
+	loadCmd.Flags().StringVar(&flags.sheetFlag, "sheet", "", "Name or number of the sheet of an .xlsx workbook to load (default is the first sheet)")
+	loadCmd.Flags().IntVar(&flags.headerRowFlag, "header-row", 1, "Row of an .xlsx workbook's sheet the column names are in. Rows above it are ignored.")
+	loadCmd.Flags().StringVar(&flags.encodingFlag, "encoding", charset.Auto, "Character encoding of files: auto, utf-8, utf-16le, utf-16be, windows-1252 or iso-8859-1.")
 	loadCmd.Flags().DurationVar(&flags.watchFlag, "watch", job.DefaultWatchTime, "Continuously checks server on job progress.")
 	loadCmd.Flags().StringVar(&flags.objFlag, "object", "", "Object being inserted.")
 	loadCmd.Flags().BoolVarP(&flags.insertFlag, "insert", "i", false, "Operation flag. Specifies insert job.")
 	loadCmd.Flags().BoolVarP(&flags.updateFlag, "update", "u", false, "Operation flag. Specifies update job.")
 	loadCmd.Flags().BoolVar(&flags.upsertFlag, "upsert", false, "Operation flag. Specifies upsert job.")
 	loadCmd.Flags().BoolVarP(&flags.deleteFlag, "delete", "d", false, "Operation flag. Specifies delete job.")
+	loadCmd.Flags().StringVar(&flags.externalIDFlag, "external-id", "", "External ID field used to match records. Required for upsert jobs.")
+	loadCmd.Flags().StringVar(&flags.mappingFlag, "mapping", "", "YAML file mapping source columns to fields.")
+	loadCmd.Flags().StringVar(&flags.transformsFlag, "transforms", "", "YAML file of value transformations applied to each column.")
+	loadCmd.Flags().StringVar(&flags.maskFlag, "mask", "", "YAML file of masking rules applied to the object's fields.")
+	loadCmd.Flags().StringVar(&flags.maskSeedFlag, "mask-seed", "", "Secret that keys masked values. Required with --mask.")
+	loadCmd.Flags().StringVar(&flags.rejectFileFlag, "reject-file", "rejects.csv", "File that rows rejected before upload are written to.")
+	loadCmd.Flags().BoolVar(&flags.forceFlag, "force", false, "Load even if the load would exceed the org's remaining limits.")
+	loadCmd.Flags().BoolVar(&flags.resumeFlag, "resume", false, "Continues an earlier load of the same file that didn't finish.")
+	loadCmd.Flags().BoolVar(&flags.reloadFlag, "reload", false, "Loads the file even if it was loaded before.")
+	loadCmd.Flags().IntVar(&flags.chunkSizeFlag, "chunk-size", 0, "Most records loaded by each job. Zero loads the whole file in one job.")
+	loadCmd.Flags().StringVar(&flags.journalDirFlag, "journal-dir", "", "Directory of the journals kept of each load (default is journal in the config directory)")
+	loadCmd.Flags().BoolVar(&flags.dryRunFlag, "dry-run", false, "Prepares and validates the load and reports the jobs it would run, without creating them.")
+	loadCmd.Flags().StringVar(&flags.dryRunDirFlag, "dry-run-dir", "", "Directory a dry run writes the content of each job to.")
+	loadCmd.Flags().IntVar(&flags.maxFailuresFlag, "max-failures", 0, "Aborts the load once more than this many records have failed. Implies --watch.")
+	loadCmd.Flags().StringVar(&flags.maxFailureRateFlag, "max-failure-rate", "", "Aborts the load once more than this percentage of records, e.g. 5%, have failed. Implies --watch.")
+	loadCmd.Flags().StringVar(&flags.summaryFlag, "summary", "", "Markdown (.md) or HTML (.html) file a summary of the load is written to.")
+	loadCmd.Flags().BoolVar(&flags.skipValidationFlag, "skip-validation", false, "Skips checking the file against the object's describe before loading.")
 
 	loadCmd.MarkFlagRequired("object")
 	loadCmd.Flags().Lookup("watch").NoOptDefVal = job.DefaultWatchTime.String()
@@ -57,74 +129,670 @@ func preRunLoad(cmd *cobra.Command, args []string) error {
 }
 
 func runLoad(cmd *cobra.Command, args []string) {
+	flags.quoteChanged = cmd.Flags().Changed("quote")
+
 	session, err := getSession()
 
 	if err != nil {
-		log.Fatalln(err)
+		fatal(err)
 	}
 
 	op, _ := validateFlags(flags)
 
-	delim, ok := job.GetDelimName(flags.delimFlag)
+	var content []byte
+
+	if isPipeInput() {
+		content, err = readSource(os.Stdin)
+	} else {
+		// we only support uploading one file at a time at the moment!
+		content, err = ioutil.ReadFile(args[0])
+	}
+
+	if err != nil {
+		fatal(err, "could not read source:")
+	}
+
+	raw := content
+	delim := ","
+
+	var rejecter pipeline.Stage
+
+	if strings.ToLower(flags.formatFlag) != "csv" {
+		if content, err = normalizeContent(content, flags.encodingFlag); err != nil {
+			fatal(err)
+		}
+
+		if content, rejecter, err = flattenContent(content, flags.formatFlag, flags.fieldsFlag); err != nil {
+			fatal(err)
+		}
+	} else if xlsx.IsWorkbook(content) {
+		if content, err = sheetContent(content, flags.sheetFlag, flags.headerRowFlag); err != nil {
+			fatal(err)
+		}
+	} else {
+		if flags.sheetFlag != "" || flags.headerRowFlag != 1 {
+			fatal(errors.New("--sheet and --header-row can only be given for .xlsx workbooks"))
+		}
+
+		if content, err = normalizeContent(content, flags.encodingFlag); err != nil {
+			fatal(err)
+		}
+
+		if content, delim, err = applyDialect(content, flags); err != nil {
+			fatal(err)
+		}
+	}
+
+	delimName, ok := job.GetDelimName(delim)
 
 	if !ok {
-		log.Fatalln(errors.Errorf("Invalid delimiter: %s", flags.delimFlag))
+		fatal(errors.Errorf("Invalid delimiter: %s", delim))
 	}
 
 	config := job.JobConfig{
-		Object:      flags.objFlag,
-		Operation: op,
-		Delim: delim,
-		ContentType: "CSV",
+		Object:          flags.objFlag,
+		Operation:       op,
+		Delim:           delimName,
+		ContentType:     "CSV",
+		ExternalIDField: flags.externalIDFlag,
 	}
 
-	verbose.Println("creating job...")
+	stages, err := loadStages(session, content, delim, flags)
 
-	j := job.New(config, session)
+	if err != nil {
+		fatal(err)
+	}
 
-	if err := j.Create(); err != nil {
-		log.Fatalln("could not create job:", err)
+	if rejecter != nil {
+		stages = append([]pipeline.Stage{rejecter}, stages...)
 	}
 
-	verbose.Println("uploading content...")
-	var content []byte
+	if content, err = transformContent(content, delim, stages, flags.rejectFileFlag, nil); err != nil {
+		fatal(err, "could not transform content:")
+	}
 
-	if isPipeInput() {
-		content, err = readSource(os.Stdin)
-	} else {
-		// we only support uploading one file at a time at the moment!
-		content, err = ioutil.ReadFile(args[0])
+	if !flags.skipValidationFlag {
+		verbose.Println("validating content...")
+
+		if err := validateContent(session, config, content, delim); err != nil {
+			fatal(err)
+		}
+	}
+
+	comma := csvReader(content, delim).Comma
+	header, rows, err := journal.Read(content, comma)
+
+	if err != nil {
+		fatal(err)
+	}
+
+	records := len(rows)
+	source := "stdin"
+
+	if len(args) > 0 {
+		source = args[0]
+	}
+
+	if flags.dryRunFlag {
+		if err := dryRun(config, source, raw, content, comma, header, rows); err != nil {
+			fatal(err)
+		}
+
+		return
+	}
+
+	jr, err := openJournal(session, source, raw, content, config, records)
+
+	if err != nil {
+		fatal(err)
+	}
+
+	auditFile(source, jr.Hash)
+
+	before, checked := checkLimits(session, records, len(jr.Chunks), flags.forceFlag)
+
+	runner := journal.Runner{
+		Session: session,
+		Journal: jr,
+		Content: content,
+		Comma:   comma,
+		Header:  header,
+		Records: rows,
+		Log:     verbose.Printf,
+		Progress: func(n int, c *journal.Chunk) {
+			event(chunkOutput(n, c, config))
+		},
+	}
+
+	maxRate, _ := parseRate(flags.maxFailureRateFlag)
+
+	if flags.maxFailuresFlag > 0 || maxRate > 0 {
+		runner.Stop = failureLimit(jr, records, flags.maxFailuresFlag, maxRate)
+	}
+
+	if cmd.Flags().Changed("watch") || runner.Stop != nil {
+		runner.Wait = flags.watchFlag
+		runner.Log = stdWriter.Printf
+
+		if !machineOutput() && !quietFlag {
+			watchProgress(&runner)
+		}
+	}
+
+	out := loadOutput{Type: "load", File: source, Journal: jr.Path(), Records: records}
+
+	err = runner.Run()
+
+	if flags.summaryFlag != "" {
+		out.Summary = summarize(session, jr, flags.summaryFlag)
+	}
+
+	if err != nil {
+		result(out.finish(jr))
+
+		if jobsFailed(jr) {
+			err = withCode(errJobFailed, err)
+		}
+
+		// resuming a load stopped by --max-failures or --max-failure-rate would load the records it held back
+		if jr.Stopped != "" {
+			fatal(err, "load stopped; fix the records that failed and rerun with --reload to load the file again:")
+		}
+
+		fatal(err, "load did not finish, rerun with --resume to continue it:")
+	}
+
+	// without waiting, the jobs have only been uploaded, and most of what they'll consume hasn't been yet
+	if checked && runner.Wait > 0 {
+		out.Consumed = reportConsumption(session, before)
+	} else if checked {
+		stdWriter.Println("Limits consumed aren't known until the jobs finish; load with --watch to see them")
+	}
+
+	out = out.finish(jr)
+	result(out)
+
+	if runner.Wait > 0 {
+		if err := recordFailures(jr, out.Processed, out.Failed); err != nil {
+			fatal(err)
+		}
+	}
+}
+
+// watchProgress shows the progress of a watched load's jobs on stdout, redrawn in place on a terminal and as plain lines
+// otherwise.
+func watchProgress(runner *journal.Runner) {
+	d := display.New(os.Stdout, display.IsTerminal(os.Stdout))
+
+	runner.Log = d.Log
+	runner.Uploading = d.Upload
+	runner.Progress = func(n int, c *journal.Chunk) {
+		d.Update(n, display.Status{
+			ID:        c.JobID,
+			State:     c.State,
+			Records:   c.End - c.Start,
+			Processed: c.Processed,
+			Failed:    c.Failed,
+		})
+	}
+}
+
+// summarize writes a summary of a load to path, returning path, or an empty string if it couldn't be written. A summary
+// that can't be written doesn't fail the load, which has already run.
+func summarize(session auth.Session, jr *journal.Journal, path string) string {
+	if err := writeSummary(session, jr, path); err != nil {
+		warn(err, "could not write summary:")
+		return ""
+	}
+
+	stdWriter.Println("Summary written to", path)
+
+	return path
+}
+
+// minRateSample is how many records must have been processed before --max-failure-rate is checked, so that the first
+// failure of a load doesn't count as 100%.
+const minRateSample = 100
+
+// failureLimit returns a journal.Runner Stop function that stops a load of records once more than maxFailures records,
+// or more than maxRate of the records processed, have failed. A limit of zero isn't checked. Loads stopped this way exit
+// as if a job had failed.
+func failureLimit(jr *journal.Journal, records, maxFailures int, maxRate float64) func() error {
+	sample := minRateSample
+
+	if records < sample {
+		sample = records
+	}
+
+	return func() error {
+		var processed, failed uint
+
+		for _, c := range jr.Chunks {
+			processed += c.Processed
+			failed += c.Failed
+		}
+
+		if maxFailures > 0 && failed > uint(maxFailures) {
+			return withCode(errJobFailed, errors.Errorf("%d records failed, more than the %d allowed by --max-failures", failed, maxFailures))
+		}
+
+		if maxRate > 0 && processed > 0 && processed >= uint(sample) && float64(failed)/float64(processed) > maxRate {
+			return withCode(errJobFailed, errors.Errorf("%d of %d records failed, more than the %g%% allowed by --max-failure-rate", failed, processed, maxRate*100))
+		}
+
+		return nil
+	}
+}
+
+// parseRate parses a percentage such as 5% or 2.5, returning it as a fraction. An empty string is zero.
+func parseRate(rate string) (float64, error) {
+	if rate == "" {
+		return 0, nil
+	}
+
+	percent, err := strconv.ParseFloat(strings.TrimSuffix(strings.TrimSpace(rate), "%"), 64)
+
+	if err != nil || percent <= 0 || percent > 100 {
+		return 0, errors.Errorf("--max-failure-rate must be a percentage between 0 and 100, such as 5%%, not %q", rate)
+	}
+
+	return percent / 100, nil
+}
+
+// jobsFailed reports whether any of a load's jobs failed or were aborted.
+func jobsFailed(jr *journal.Journal) bool {
+	for _, c := range jr.Chunks {
+		if c.State == "Failed" || c.State == "Aborted" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// recordFailures returns an error for the exit code of a finished load whose records failed, partly or entirely,
+// naming the files their failed results were saved to.
+func recordFailures(jr *journal.Journal, processed, failed uint) error {
+	if failed == 0 {
+		return nil
+	}
+
+	var files []string
+
+	for _, c := range jr.Chunks {
+		if c.FailedResults != "" {
+			files = append(files, c.FailedResults)
+		}
+	}
+
+	if failed >= processed {
+		return withCode(errTotalFailure, errors.Errorf("all %d records failed; see %s", failed, strings.Join(files, ", ")))
+	}
+
+	return withCode(errPartialFailure, errors.Errorf("%d of %d records failed; see %s", failed, processed, strings.Join(files, ", ")))
+}
+
+// loadOutput is the result of a load with --output json or ndjson.
+type loadOutput struct {
+	Type      string       `json:"type"`
+	File      string       `json:"file"`
+	Journal   string       `json:"journal"`
+	Records   int          `json:"records"`
+	Processed uint         `json:"processed"`
+	Failed    uint         `json:"failed"`
+	Jobs      []jobOutput  `json:"jobs"`
+	Consumed  limits.Usage `json:"consumed,omitempty"`
+	Summary   string       `json:"summary,omitempty"`
+}
+
+// finish fills in the jobs of a load's result from its journal.
+func (out loadOutput) finish(jr *journal.Journal) loadOutput {
+	out.Jobs = []jobOutput{}
+
+	for i, c := range jr.Chunks {
+		if c.JobID == "" {
+			continue
+		}
+
+		out.Jobs = append(out.Jobs, chunkOutput(i+1, c, jr.Config))
+		out.Processed += c.Processed
+		out.Failed += c.Failed
+	}
+
+	return out
+}
+
+// chunkOutput returns the job of a chunk as it's written in a load's events and result.
+func chunkOutput(n int, c *journal.Chunk, config job.JobConfig) jobOutput {
+	return jobOutput{
+		Type:      "job",
+		Chunk:     n,
+		ID:        c.JobID,
+		Object:    config.Object,
+		Operation: config.Operation,
+		State:     c.State,
+		Processed: c.Processed,
+		Failed:    c.Failed,
+	}
+}
+
+// sessionOrg returns the ID of the session's org, or its instance URL if the session has no identity URL.
+func sessionOrg(session auth.Session) string {
+	if org, _ := audit.Identity(session.ID); org != "" {
+		return org
+	}
+
+	return session.InstanceURL
+}
+
+// openJournal returns the journal of loading raw, the contents of source as read, with config. A new journal splits
+// the records into chunks; an earlier one is only continued with --resume or replaced with --reload, so that a file
+// isn't loaded twice by mistake.
+func openJournal(session auth.Session, source string, raw, content []byte, config job.JobConfig, records int) (*journal.Journal, error) {
+	dir := flags.journalDirFlag
+
+	if dir == "" {
+		home, err := homedir.Dir()
+
+		if err != nil {
+			return nil, err
+		}
+
+		dir = filepath.Join(configDir(home), "journal")
+	}
+
+	org := sessionOrg(session)
+	path := journal.Path(dir, org, raw, config)
+	jr, err := journal.Load(path)
+
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case jr != nil && flags.resumeFlag:
+		// its jobs belong to the org it was started in, and can't be checked or aborted in another
+		if jr.Org != org {
+			return nil, errors.Errorf("the earlier load of %s into %s was started in org %s, not %s; use --reload to load it into this org", source, config.Object, jr.Org, org)
+		}
+
+		if jr.Stopped != "" {
+			return nil, errors.Errorf("the earlier load of %s into %s was stopped because %s, and resuming it would load the rest anyway; fix the records that failed and use --reload to start again", source, config.Object, jr.Stopped)
+		}
+
+		if jr.ContentHash != journal.Hash(content) {
+			return nil, errors.New("the file was prepared differently than in the load being resumed; use the same options, or --reload to start again")
+		}
+
+		verbose.Println("resuming load started", jr.Created.Local().Format(time.RFC1123))
+
+		return jr, nil
+	case jr != nil && !flags.reloadFlag && jr.Submitted():
+		return nil, errors.Errorf("%s was already loaded into %s on %s; use --reload to load it again", source, config.Object, jr.Created.Local().Format(time.RFC1123))
+	case jr != nil && !flags.reloadFlag:
+		return nil, errors.Errorf("an earlier load of %s into %s did not finish; use --resume to continue it, or --reload to start again", source, config.Object)
+	case jr == nil && flags.resumeFlag:
+		return nil, errors.Errorf("there is no earlier load of %s into %s to resume", source, config.Object)
 	}
 
+	jr = journal.New(path, source, raw, config)
+	jr.Org = org
+	jr.ContentHash = journal.Hash(content)
+	jr.Split(records, flags.chunkSizeFlag)
+
+	verbose.Println("journal:", path)
+
+	return jr, jr.Save()
+}
+
+// normalizeContent converts content in the given encoding, or the detected one if it's charset.Auto, to UTF-8 with LF
+// line endings, as the job is created with.
+func normalizeContent(content []byte, encoding string) ([]byte, error) {
+	normalized, name, err := charset.Normalize(content, encoding)
+
 	if err != nil {
-		log.Fatalln("could not read source of ")
+		return nil, err
 	}
 
-	if err = j.Upload(content); err != nil {
-		log.Fatalln("could not upload content to job")
+	verbose.Println("read content as", name)
+
+	return normalized, nil
+}
+
+// flattenContent flattens JSON or NDJSON records into comma delimited CSV. Records that can't be loaded are kept in
+// the CSV, along with a stage that rejects them, or nil if every record can be loaded.
+func flattenContent(content []byte, format string, fields []string) ([]byte, pipeline.Stage, error) {
+	values, err := flatten.Decode(content, format)
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	table := flatten.Flatten(values, fields)
+
+	verbose.Printf("flattened %d records into %d columns", len(table.Rows), len(table.Header))
+
+	if content, err = table.CSV(); err != nil {
+		return nil, nil, err
 	}
 
-	if cmd.Flags().Changed("watch") {
-		go j.Watch(flags.watchFlag)
+	if len(table.Rejects) == 0 {
+		return content, nil, nil
+	}
+
+	return content, table.Rejecter(), nil
+}
+
+// sheetContent returns a sheet of an .xlsx workbook as comma delimited CSV, with the rows above its header row left
+// out.
+func sheetContent(content []byte, sheet string, headerRow int) ([]byte, error) {
+	w, err := xlsx.Open(content)
+
+	if err != nil {
+		return nil, err
+	}
+
+	i, err := w.Sheet(sheet)
+
+	if err != nil {
+		return nil, err
+	}
+
+	verbose.Printf("reading sheet %s from row %d", w.Sheets[i], headerRow)
+
+	return w.CSV(i, headerRow)
+}
+
+// applyDialect rewrites content written in the dialect given by the --delim, --quote, --comment and --skip-rows flags
+// into one the Bulk API accepts, returning it with the delimiter it's now in. Content that's already acceptable is
+// returned as it is.
+func applyDialect(content []byte, opts flagStr) ([]byte, string, error) {
+	var (
+		d   dialect.Dialect
+		err error
+	)
+
+	if d.Quote, err = dialect.ParseQuote(opts.quoteFlag); err != nil {
+		return nil, "", err
+	}
+
+	if d.Comment, err = dialect.ParseComment(opts.commentFlag); err != nil {
+		return nil, "", err
+	}
+
+	d.SkipRows = opts.skipRowsFlag
+
+	if opts.delimFlag == dialect.Auto && opts.quoteChanged {
+		d = dialect.SniffDelim(content, d)
+		verbose.Printf("detected delimiter %q", d.Delim)
+	} else if opts.delimFlag == dialect.Auto {
+		d = dialect.Sniff(content, d)
+		verbose.Printf("detected delimiter %q and quote %q", d.Delim, d.Quote)
+	} else if d.Delim, err = dialect.ParseDelim(opts.delimFlag); err != nil {
+		return nil, "", err
+	}
+
+	if d.Compatible() {
+		if d.Delim == '\t' {
+			return content, "\\t", nil
+		}
+
+		return content, string(d.Delim), nil
+	}
 
-		for {
-			select {
-			case status, ok := <-j.Status:
-				if ok {
-					printStatus(status)
-				} else {
-					return
-				}
-			case err := <-j.Error:
-				log.Fatalln("watching job reported error: ", err)
+	verbose.Println("converting content to comma delimited CSV")
+
+	converted, err := d.Convert(content)
+
+	if err != nil {
+		return nil, "", errors.Wrap(err, "could not read content")
+	}
+
+	return converted, ",", nil
+}
+
+// loadStages returns the stages content passes through before it's uploaded, based on the load flags in opts. If the
+// header has relationship columns once it's been mapped, transformed and masked, they're resolved last.
+func loadStages(session auth.Session, content []byte, delim string, opts flagStr) ([]pipeline.Stage, error) {
+	var stages []pipeline.Stage
+
+	if opts.mappingFlag != "" {
+		m, err := mapping.Load(opts.mappingFlag)
+
+		if err != nil {
+			return nil, err
+		}
+
+		stages = append(stages, m)
+	}
+
+	if opts.transformsFlag != "" {
+		t, err := transform.Load(opts.transformsFlag)
+
+		if err != nil {
+			return nil, err
+		}
+
+		stages = append(stages, t)
+	}
+
+	if opts.maskFlag != "" {
+		m, err := loadMasker(opts.maskFlag, opts.objFlag, opts.maskSeedFlag)
+
+		if err != nil {
+			return nil, err
+		}
+
+		stages = append(stages, m)
+	}
+
+	if opts.deleteFlag {
+		return stages, nil
+	}
+
+	header, err := csvReader(content, delim).Read()
+
+	if err != nil {
+		return nil, errors.Wrap(err, "could not read header")
+	}
+
+	for _, stage := range stages {
+		if header, err = stage.Header(header); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, col := range header {
+		if strings.Contains(col, ".") {
+			sobject, err := describe.Get(session, opts.objFlag)
+
+			if err != nil {
+				return nil, errors.Wrap(err, "could not describe "+opts.objFlag)
 			}
+
+			return append(stages, resolve.New(session, sobject)), nil
+		}
+	}
+
+	return stages, nil
+}
+
+// transformContent runs CSV content through stages, returning the rewritten content. Rejected rows are written to
+// rejectPath, which is only created if there are any, and passed to rejecting if it's set.
+func transformContent(content []byte, delim string, stages []pipeline.Stage, rejectPath string, rejecting func(header, row []string)) ([]byte, error) {
+	if len(stages) == 0 {
+		return content, nil
+	}
+
+	r := csvReader(content, delim)
+
+	var buf, rejects bytes.Buffer
+
+	w := csv.NewWriter(&buf)
+	w.Comma = r.Comma
+
+	if err := prefetch(content, delim, stages); err != nil {
+		return nil, err
+	}
+
+	p := pipeline.Pipeline{
+		Stages:    stages,
+		Rejects:   csv.NewWriter(&rejects),
+		Rejecting: rejecting,
+	}
+
+	p.Rejects.Comma = r.Comma
+
+	if err := p.Run(r, w); err != nil {
+		return nil, err
+	}
+
+	if p.Rejected > 0 {
+		if err := ioutil.WriteFile(rejectPath, rejects.Bytes(), 0644); err != nil {
+			return nil, errors.Wrap(err, "could not write reject file")
 		}
+
+		log.Printf("%d rows rejected before upload, see %s", p.Rejected, rejectPath)
 	}
+
+	verbose.Printf("%d rows ready to upload", p.Rows)
+
+	return buf.Bytes(), nil
+}
+
+// prefetch runs content through stages once without keeping the result, so that any Resolver among them can look up
+// the values of its columns in batches rather than a row at a time.
+func prefetch(content []byte, delim string, stages []pipeline.Stage) error {
+	for i, stage := range stages {
+		r, ok := stage.(*resolve.Resolver)
+
+		if !ok {
+			continue
+		}
+
+		collect := append(append([]pipeline.Stage{}, stages[:i]...), r.Collect())
+
+		p := pipeline.Pipeline{
+			Stages:  collect,
+			Rejects: csv.NewWriter(ioutil.Discard),
+		}
+
+		if err := p.Run(csvReader(content, delim), csv.NewWriter(ioutil.Discard)); err != nil {
+			return err
+		}
+
+		verbose.Println("looking up relationship columns...")
+
+		if err := r.Resolve(); err != nil {
+			return err
+		}
+	}
+
+	return nil
 }
 
 func validateCmdArgs(cmd *cobra.Command, args []string) error {
 	if !isPipeInput() && len(args) != 1 {
-		fmt.Println("is pipe", isPipeInput())
 		return errors.New("must either read in CSV content from stdin or specify one file to upload")
 	}
 
@@ -158,6 +826,34 @@ func validateFlags(flags flagStr) (string, error) {
 		return "", errors.New("You must specify an operation flag.")
 	}
 
+	if op == "upsert" && flags.externalIDFlag == "" {
+		return "", errors.New("You must specify --external-id for an upsert.")
+	}
+
+	if flags.maxFailuresFlag < 0 {
+		return "", errors.New("--max-failures can't be negative.")
+	}
+
+	if _, err := parseRate(flags.maxFailureRateFlag); err != nil {
+		return "", err
+	}
+
+	if flags.summaryFlag != "" {
+		if _, err := report.Format(flags.summaryFlag); err != nil {
+			return "", err
+		}
+	}
+
+	switch strings.ToLower(flags.formatFlag) {
+	case "", "csv":
+		if len(flags.fieldsFlag) > 0 {
+			return "", errors.New("You can only specify --fields with --format json or ndjson.")
+		}
+	case flatten.JSON, flatten.NDJSON:
+	default:
+		return "", errors.Errorf("Unknown format %s. The format must be csv, json or ndjson.", flags.formatFlag)
+	}
+
 	return op, nil
 }
 
@@ -172,9 +868,77 @@ func getSession() (auth.Session, error) {
 		return session, errors.New("Session info not valid. Missing the following fields: " + strings.Join(missing, ", "))
 	}
 
+	if pinned := viper.GetString("api_version"); pinned != "" {
+		version, err := parseAPIVersion(pinned)
+
+		if err != nil {
+			return session, err
+		}
+
+		session.APIVersion = version
+	} else {
+		version, err := negotiateVersion(session)
+
+		if err != nil {
+			return session, errors.Wrap(err, "could not determine API version")
+		}
+
+		session.APIVersion = version
+	}
+
+	verbose.Println("using API version", session.APIVersion)
+	auditSession(session)
+
 	return session, nil
 }
 
+// apiVersionPattern matches a REST API version as it appears in URLs, such as 43.0.
+var apiVersionPattern = regexp.MustCompile(`^\d+\.\d$`)
+
+// parseAPIVersion returns the REST API version pinned with --api-version or api_version, which may start with a v.
+func parseAPIVersion(pinned string) (string, error) {
+	version := strings.TrimPrefix(strings.TrimSpace(pinned), "v")
+
+	if !apiVersionPattern.MatchString(version) {
+		return "", withCode(validate.ErrInvalid, errors.Errorf("invalid API version %q: must be a version such as 43.0", pinned))
+	}
+
+	return version, nil
+}
+
+// negotiateVersion returns the latest API version the session's org supports, caching it per org in the config
+// directory so the org is only asked once.
+func negotiateVersion(session auth.Session) (string, error) {
+	home, err := homedir.Dir()
+
+	if err != nil {
+		return "", err
+	}
+
+	dir := configDir(home)
+	cachePath := filepath.Join(dir, "versions.json")
+
+	cache, err := auth.LoadVersionCache(cachePath)
+
+	if err != nil {
+		return "", err
+	}
+
+	version, err := auth.NegotiateVersion(session, cache)
+
+	if err != nil {
+		return "", err
+	}
+
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		verbose.Println("could not create config directory:", err)
+	} else if err := cache.Save(cachePath); err != nil {
+		verbose.Println("could not save version cache:", err)
+	}
+
+	return version, nil
+}
+
 func validSession(session auth.Session) (missing []string, ok bool) {
 	if session.AccessToken == "" {
 		missing = append(missing, "access_token")
@@ -191,10 +955,6 @@ func validSession(session auth.Session) (missing []string, ok bool) {
 	return missing, len(missing) == 0
 }
 
-func printStatus(status job.JobInfo) {
-	stdWriter.Printf("Records processed: %d\tRecords failed: %d", status.RecordsProcessed, status.RecordsFailed)
-}
-
 // reads content from source
 func readSource(source io.ReadCloser) ([]byte, error) {
 	content, err := ioutil.ReadAll(source)
@@ -206,6 +966,20 @@ func readSource(source io.ReadCloser) ([]byte, error) {
 	return content, nil
 }
 
+// csvReader returns a reader for CSV content using the delimiter given to --delim.
+func csvReader(content []byte, delim string) *csv.Reader {
+	r := csv.NewReader(bytes.NewReader(content))
+	r.FieldsPerRecord = -1
+
+	if delim == "\\t" {
+		r.Comma = '\t'
+	} else if d, _ := utf8.DecodeRuneInString(delim); d != utf8.RuneError {
+		r.Comma = d
+	}
+
+	return r
+}
+
 func isPipeInput() bool {
 	stat, err := os.Stdin.Stat()
 	return stat.Mode()&os.ModeCharDevice != 0 || stat.Size() <= 0 && err == nil
@@ -0,0 +1,127 @@
+package cmd
+
+import (
+	"github.com/pkg/errors"
+	"github.com/rfaulhaber/forcedata/charset"
+	"github.com/rfaulhaber/forcedata/describe"
+	"github.com/rfaulhaber/forcedata/mapping"
+	"github.com/spf13/cobra"
+	"io/ioutil"
+	"os"
+)
+
+// mappingCmd represents the mapping command
+var mappingCmd = &cobra.Command{
+	Use:   "mapping COMMAND",
+	Short: "Work with column mapping files",
+	Long: `Column mapping files rename the columns of a source file to field API names before it's loaded. Pass one to
+load with --mapping. A mapping file looks like:
+
+    columns:
+      Customer Name: Name       # renames a column
+      Cust No:                  # fills several fields from one column
+        - AccountNumber
+        - External_Id__c
+    constants:
+      Type: Customer            # adds a column with the same value on every row
+
+Columns that aren't listed are dropped.`,
+}
+
+// mappingInitCmd represents the mapping init command
+var mappingInitCmd = &cobra.Command{
+	Use:   "init FILE",
+	Short: "Suggests a mapping for a CSV file",
+	Long: `Suggests a mapping for a CSV file by fuzzy-matching its headers against the names and labels of the object's
+fields. Columns that couldn't be matched are written as comments. Review the result before using it.`,
+	Args: cobra.ExactArgs(1),
+	Run:  runMappingInit,
+}
+
+var mappingInitOpts struct {
+	object   string
+	delim    string
+	encoding string
+	out      string
+}
+
+func init() {
+	rootCmd.AddCommand(mappingCmd)
+	mappingCmd.AddCommand(mappingInitCmd)
+
+	mappingInitCmd.Flags().StringVar(&mappingInitOpts.object, "object", "", "Object the file will be loaded into.")
+	mappingInitCmd.Flags().StringVar(&mappingInitOpts.delim, "delim", ",", "Delimiter used in the file, or auto to detect it.")
+	mappingInitCmd.Flags().StringVar(&mappingInitOpts.encoding, "encoding", charset.Auto, "Character encoding of the file: auto, utf-8, utf-16le, utf-16be, windows-1252 or iso-8859-1.")
+	mappingInitCmd.Flags().StringVar(&mappingInitOpts.out, "out", "", "Writes the mapping to the specified file instead of stdout")
+
+	mappingInitCmd.MarkFlagRequired("object")
+}
+
+func runMappingInit(cmd *cobra.Command, args []string) {
+	content, err := ioutil.ReadFile(args[0])
+
+	if err != nil {
+		fatal(err, "could not read file:")
+	}
+
+	if content, err = normalizeContent(content, mappingInitOpts.encoding); err != nil {
+		fatal(err)
+	}
+
+	content, delim, err := applyDialect(content, flagStr{delimFlag: mappingInitOpts.delim, quoteFlag: `"`})
+
+	if err != nil {
+		fatal(err)
+	}
+
+	header, err := csvReader(content, delim).Read()
+
+	if err != nil {
+		fatal(err, "could not read header:")
+	}
+
+	session, err := getSession()
+
+	if err != nil {
+		fatal(err)
+	}
+
+	sobject, err := describe.Get(session, mappingInitOpts.object)
+
+	if err != nil {
+		fatal(errors.Wrap(err, "could not describe "+mappingInitOpts.object))
+	}
+
+	m, unmatched := mapping.Suggest(header, sobject)
+
+	for _, col := range unmatched {
+		verbose.Println("no field found for column", col)
+	}
+
+	out := os.Stdout
+
+	if mappingInitOpts.out != "" {
+		if out, err = os.Create(mappingInitOpts.out); err != nil {
+			fatal(err)
+		}
+
+		defer out.Close()
+	}
+
+	if err := m.WriteYAML(out, unmatched); err != nil {
+		fatal(err, "could not write mapping:")
+	}
+
+	if mappingInitOpts.out != "" {
+		result(mappingOutput{Type: "mapping", Object: mappingInitOpts.object, File: mappingInitOpts.out, Unmatched: append([]string{}, unmatched...)})
+	}
+}
+
+// mappingOutput is the result of mapping init with --out and --output json or ndjson. Without --out the mapping itself
+// is written to stdout.
+type mappingOutput struct {
+	Type      string   `json:"type"`
+	Object    string   `json:"object"`
+	File      string   `json:"file"`
+	Unmatched []string `json:"unmatched"`
+}
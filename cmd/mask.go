@@ -0,0 +1,115 @@
+package cmd
+
+import (
+	"github.com/pkg/errors"
+	"github.com/rfaulhaber/forcedata/charset"
+	"github.com/rfaulhaber/forcedata/mask"
+	"github.com/rfaulhaber/forcedata/pipeline"
+	"github.com/spf13/cobra"
+	"io/ioutil"
+	"os"
+)
+
+// maskCmd represents the mask command
+var maskCmd = &cobra.Command{
+	Use:   "mask FILE",
+	Short: "Masks personal data in a CSV file",
+	Long: `Replaces the values of an object's fields in a CSV file, such as query results or an export, following a rules
+file:
+
+    objects:
+      Contact:
+        FirstName: first-name
+        LastName: last-name
+        Email: email
+        Phone: phone
+        MailingStreet: street
+        MailingCity: city
+        SSN__c: hash
+        Description: null
+        AccountNumber__c: scramble
+        Birthdate:
+          shift-date: 90
+
+Masking is deterministic: with the same seed, a value is always masked the same, whatever file or field it's in, so
+duplicates and matching values still match after masking. Keep the seed secret. The masked file is written to
+standard output unless --out is given.`,
+	Args: cobra.ExactArgs(1),
+	Run:  runMask,
+}
+
+var maskOpts struct {
+	rules      string
+	object     string
+	seed       string
+	delim      string
+	out        string
+	rejectFile string
+}
+
+func init() {
+	rootCmd.AddCommand(maskCmd)
+
+	maskCmd.Flags().StringVar(&maskOpts.rules, "rules", "", "YAML file of masking rules.")
+	maskCmd.Flags().StringVar(&maskOpts.object, "object", "", "Object whose rules apply to the file.")
+	maskCmd.Flags().StringVar(&maskOpts.seed, "seed", "", "Secret that keys masked values.")
+	maskCmd.Flags().StringVar(&maskOpts.delim, "delim", ",", "Delimiter used in the file.")
+	maskCmd.Flags().StringVar(&maskOpts.out, "out", "", "File the masked records are written to.")
+	maskCmd.Flags().StringVar(&maskOpts.rejectFile, "reject-file", "rejects.csv", "File that rows which couldn't be masked are written to.")
+
+	maskCmd.MarkFlagRequired("rules")
+	maskCmd.MarkFlagRequired("object")
+	maskCmd.MarkFlagRequired("seed")
+}
+
+func runMask(cmd *cobra.Command, args []string) {
+	m, err := loadMasker(maskOpts.rules, maskOpts.object, maskOpts.seed)
+
+	if err != nil {
+		fatal(err)
+	}
+
+	content, err := ioutil.ReadFile(args[0])
+
+	if err != nil {
+		fatal(errors.Wrap(err, "could not read file"))
+	}
+
+	if content, err = normalizeContent(content, charset.Auto); err != nil {
+		fatal(err)
+	}
+
+	if content, err = transformContent(content, maskOpts.delim, []pipeline.Stage{m}, maskOpts.rejectFile, nil); err != nil {
+		fatal(err, "could not mask content:")
+	}
+
+	if maskOpts.out == "" {
+		os.Stdout.Write(content)
+		return
+	}
+
+	if err := ioutil.WriteFile(maskOpts.out, content, 0644); err != nil {
+		fatal(errors.Wrap(err, "could not write masked file"))
+	}
+
+	result(maskOutput{Type: "mask", Object: maskOpts.object, File: maskOpts.out})
+}
+
+// maskOutput is the result of mask with --out and --output json or ndjson. Without --out the masked file itself is
+// written to stdout.
+type maskOutput struct {
+	Type   string `json:"type"`
+	Object string `json:"object"`
+	File   string `json:"file"`
+}
+
+// loadMasker reads a rules file and returns the masking stage for object.
+func loadMasker(path, object, seed string) (*mask.Masker, error) {
+	rules, err := mask.Load(path)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return rules.Masker(object, seed)
+}
@@ -0,0 +1,103 @@
+package cmd
+
+import (
+	"github.com/pkg/errors"
+	"github.com/rfaulhaber/forcedata/auth"
+	"github.com/rfaulhaber/forcedata/jobtest"
+	"github.com/spf13/cobra"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// mockServerCmd represents the mock-server command
+var mockServerCmd = &cobra.Command{
+	Use:   "mock-server [OPTIONS]",
+	Short: "Runs a simulated Salesforce org for offline testing",
+	Long: `Serves an in-memory simulation of the Salesforce OAuth token endpoint and the Bulk API 2.0 ingest and query
+job lifecycles on localhost. Nothing is sent over the network.
+
+On startup a session for the simulated org is written to stdout (or the file given by --out). Use it as the config
+file of other commands to point them at the simulator, e.g.:
+
+    data mock-server --port 8080 --out mock.json &
+    data load --config mock.json --object Contact --insert contacts.csv
+
+Authenticating against the simulator with "data authenticate" also works; any username and password are accepted and
+sessions are signed with the client secret given by --client-secret.
+
+Rows can be made to fail with --fail FIELD=VALUE, which rejects every row whose FIELD has VALUE.`,
+	Args: cobra.NoArgs,
+	Run:  runMockServer,
+}
+
+var mockFlags struct {
+	port           int
+	latency        time.Duration
+	processingTime time.Duration
+	clientSecret   string
+	fail           []string
+	out            string
+}
+
+func init() {
+	rootCmd.AddCommand(mockServerCmd)
+
+	mockServerCmd.Flags().IntVar(&mockFlags.port, "port", 8080, "Port to listen on.")
+	mockServerCmd.Flags().DurationVar(&mockFlags.latency, "latency", 0, "Delay added to every request.")
+	mockServerCmd.Flags().DurationVar(&mockFlags.processingTime, "processing-time", 0, "How long jobs stay in progress once their upload is complete.")
+	mockServerCmd.Flags().StringVar(&mockFlags.clientSecret, "client-secret", jobtest.DefaultClientSecret, "Client secret used to sign sessions.")
+	mockServerCmd.Flags().StringArrayVar(&mockFlags.fail, "fail", nil, "Rejects rows where FIELD=VALUE. May be repeated.")
+	mockServerCmd.Flags().StringVar(&mockFlags.out, "out", "", "Writes the simulated org's session to the specified file instead of stdout")
+}
+
+func runMockServer(cmd *cobra.Command, args []string) {
+	sim := jobtest.New()
+	sim.Latency = mockFlags.latency
+	sim.ProcessingTime = mockFlags.processingTime
+	sim.ClientSecret = mockFlags.clientSecret
+
+	for _, f := range mockFlags.fail {
+		parts := strings.SplitN(f, "=", 2)
+
+		if len(parts) != 2 {
+			fatal(errors.Errorf("--fail must be in the form FIELD=VALUE, got %s", f))
+		}
+
+		sim.Rules = append(sim.Rules, jobtest.FailWhen(parts[0], parts[1], "FIELD_CUSTOM_VALIDATION_EXCEPTION", "Rejected by mock server: "+f))
+	}
+
+	listener, err := net.Listen("tcp", "localhost:"+strconv.Itoa(mockFlags.port))
+
+	if err != nil {
+		fatal(err, "could not listen:")
+	}
+
+	instanceURL := "http://" + listener.Addr().String()
+
+	writeMockSession(sim.Session(instanceURL))
+
+	progress(log.Printf)("mock server listening on %s", instanceURL)
+	fatal(http.Serve(listener, sim))
+}
+
+func writeMockSession(session auth.Session) {
+	if mockFlags.out == "" {
+		auth.WriteSession(session, os.Stdout)
+		return
+	}
+
+	outFile, err := os.Create(mockFlags.out)
+
+	if err != nil {
+		fatal(err)
+	}
+
+	defer outFile.Close()
+
+	auth.WriteSession(session, outFile)
+}
@@ -0,0 +1,162 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/pkg/errors"
+	"github.com/rfaulhaber/forcedata/job"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"strings"
+)
+
+// Output formats, chosen with --output. These are documented in the README, as are the objects written in each, and
+// must stay stable.
+const (
+	outputText   = "text"
+	outputJSON   = "json"
+	outputNDJSON = "ndjson"
+)
+
+var (
+	outputFlag string
+
+	// jsonWriter receives the JSON written with --output json or ndjson.
+	jsonWriter io.Writer = os.Stdout
+)
+
+// errorOutput is how errors, and warnings, are written to stderr with --output json or ndjson.
+type errorOutput struct {
+	Code    string                 `json:"code"`
+	Message string                 `json:"message"`
+	Details map[string]interface{} `json:"details"`
+}
+
+// jobOutput is the summary of a job written in results and events.
+type jobOutput struct {
+	Type      string `json:"type,omitempty"`
+	Chunk     int    `json:"chunk,omitempty"`
+	ID        string `json:"id"`
+	Object    string `json:"object"`
+	Operation string `json:"operation"`
+	State     string `json:"state"`
+	Processed uint   `json:"processed"`
+	Failed    uint   `json:"failed"`
+	Error     string `json:"error,omitempty"`
+}
+
+// infoOutput returns a job's info as it's written in results and events.
+func infoOutput(info job.JobInfo) jobOutput {
+	return jobOutput{
+		Type:      "job",
+		ID:        info.ID,
+		Object:    info.Object,
+		Operation: info.Operation,
+		State:     info.State,
+		Processed: info.RecordsProcessed,
+		Failed:    info.RecordsFailed,
+		Error:     info.ErrorMessage,
+	}
+}
+
+// messageOutput is an event carrying a progress message that has no structured form.
+type messageOutput struct {
+	Type    string `json:"type"`
+	Message string `json:"message"`
+}
+
+// setOutput applies --output, --quiet and --verbose. Text meant for people is discarded with --output json or
+// ndjson, leaving stdout to JSON, and lines logged to stderr are written as errorOutput.
+func setOutput() error {
+	switch outputFlag {
+	case outputText:
+	case outputJSON, outputNDJSON:
+		stdWriter = log.New(ioutil.Discard, "", 0)
+		log.SetOutput(warningWriter{os.Stderr})
+	default:
+		return errors.Errorf("--output must be text, json or ndjson, not %q", outputFlag)
+	}
+
+	if verboseFlag {
+		verbose = log.New(os.Stderr, "", 0)
+	}
+
+	if quietFlag {
+		stdWriter = log.New(ioutil.Discard, "", 0)
+		jsonWriter = ioutil.Discard
+	}
+
+	return nil
+}
+
+// machineOutput reports whether --output asked for JSON.
+func machineOutput() bool {
+	return outputFlag == outputJSON || outputFlag == outputNDJSON
+}
+
+// result writes the result of a command as a single JSON object with --output json or ndjson. With text output the
+// command prints its result through stdWriter itself.
+func result(v interface{}) {
+	if !machineOutput() {
+		return
+	}
+
+	writeJSON(jsonWriter, v)
+}
+
+// event writes something that happened while a command ran as a line of JSON with --output ndjson.
+func event(v interface{}) {
+	if outputFlag != outputNDJSON {
+		return
+	}
+
+	writeJSON(jsonWriter, v)
+}
+
+// progress returns a function that prints progress messages through print with text output, and writes them as
+// message events with --output ndjson.
+func progress(print func(format string, args ...interface{})) func(format string, args ...interface{}) {
+	if !machineOutput() {
+		return print
+	}
+
+	return func(format string, args ...interface{}) {
+		event(messageOutput{Type: "message", Message: fmt.Sprintf(format, args...)})
+	}
+}
+
+func writeJSON(w io.Writer, v interface{}) {
+	b, err := json.Marshal(v)
+
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return
+	}
+
+	w.Write(append(b, '\n'))
+}
+
+// writeError writes err to stderr as an errorOutput, with v, as fatal would log it, as its message.
+func writeError(err error, v ...interface{}) {
+	writeJSON(os.Stderr, errorOutput{
+		Code:    errorName(err),
+		Message: strings.TrimSuffix(fmt.Sprintln(append(v, err)...), "\n"),
+		Details: errorDetails(err),
+	})
+}
+
+// warningWriter writes each line logged to it as an errorOutput with the code WARNING.
+type warningWriter struct {
+	w io.Writer
+}
+
+func (w warningWriter) Write(p []byte) (int, error) {
+	for _, line := range bytes.Split(bytes.TrimSuffix(p, []byte("\n")), []byte("\n")) {
+		writeJSON(w.w, errorOutput{Code: "WARNING", Message: string(line), Details: map[string]interface{}{}})
+	}
+
+	return len(p), nil
+}
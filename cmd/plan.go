@@ -0,0 +1,136 @@
+package cmd
+
+import (
+	"github.com/pkg/errors"
+	"github.com/rfaulhaber/forcedata/job"
+	"github.com/rfaulhaber/forcedata/plan"
+	"github.com/spf13/cobra"
+	"log"
+	"time"
+)
+
+// planCmd represents the plan command
+var planCmd = &cobra.Command{
+	Use:   "plan COMMAND",
+	Short: "Load several related files in dependency order",
+	Long: `A plan loads several files into related objects, creating parents before the children that refer to them. A
+plan file looks like:
+
+    steps:
+      - name: accounts
+        object: Account
+        operation: insert
+        file: accounts.csv
+        key: Legacy Id              # identifies rows to later steps; not uploaded unless it's a field
+      - name: contacts
+        object: Contact
+        operation: insert
+        file: contacts.csv
+        mapping: contacts.yaml      # optional column mapping
+        references:
+          AccountId: accounts       # holds keys of the accounts step
+
+Each step takes the settings of a job (object, operation, externalIdFieldName, columnDelimiter) plus its file. A
+reference column's keys are replaced with the IDs of the records the referenced step loaded. Steps run once the steps
+they reference or list under dependsOn are done, in parallel where they can; set "ordered: true" to run them one at a
+time in the order listed instead.`,
+}
+
+// planApplyCmd represents the plan apply command
+var planApplyCmd = &cobra.Command{
+	Use:   "apply PLAN",
+	Short: "Runs every step of a plan",
+	Long: `Runs every step of a plan. A step whose job fails or is aborted stops the steps that depend on it, which are
+skipped. Rows whose reference columns name a key that wasn't loaded are written to NAME.rejects.csv, and records that
+failed to load to NAME.failed.csv, in the results directory.`,
+	Args: cobra.ExactArgs(1),
+	Run:  runPlanApply,
+}
+
+var planApplyOpts struct {
+	parallel   int
+	poll       time.Duration
+	resultsDir string
+}
+
+func init() {
+	rootCmd.AddCommand(planCmd)
+	planCmd.AddCommand(planApplyCmd)
+
+	planApplyCmd.Flags().IntVar(&planApplyOpts.parallel, "parallel", plan.DefaultParallel, "Most steps run at once.")
+	planApplyCmd.Flags().DurationVar(&planApplyOpts.poll, "poll", job.DefaultWatchTime, "How often running jobs are checked.")
+	planApplyCmd.Flags().StringVar(&planApplyOpts.resultsDir, "results-dir", ".", "Directory rejected rows and failed results are written to.")
+}
+
+func runPlanApply(cmd *cobra.Command, args []string) {
+	p, err := plan.Load(args[0])
+
+	if err != nil {
+		fatal(err)
+	}
+
+	applyPlan(p, planApplyOpts.parallel, planApplyOpts.poll, planApplyOpts.resultsDir)
+}
+
+// applyPlan runs every step of a plan, printing the result of each, and exits with an error if any didn't complete.
+func applyPlan(p *plan.Plan, parallel int, poll time.Duration, resultsDir string) {
+	session, err := getSession()
+
+	if err != nil {
+		fatal(err)
+	}
+
+	runner := plan.Runner{
+		Session:      session,
+		Parallel:     parallel,
+		PollInterval: poll,
+		ResultsDir:   resultsDir,
+		Log:          progress(log.Printf),
+	}
+
+	results := runner.Run(p)
+
+	incomplete := 0
+	out := planOutput{Type: "plan", Steps: []stepOutput{}}
+
+	for _, res := range results {
+		step := stepOutput{Name: res.Step.Name, State: res.State, Job: res.Info.ID, Rejected: res.Rejected}
+
+		switch res.State {
+		case plan.StateDone:
+			stdWriter.Printf("%s\t%s\tprocessed: %d\tfailed: %d\trejected: %d", res.Step.Name, res.State, res.Info.RecordsProcessed, res.Info.RecordsFailed, res.Rejected)
+			step.Processed, step.Failed = res.Info.RecordsProcessed, res.Info.RecordsFailed
+		default:
+			incomplete++
+			stdWriter.Printf("%s\t%s\t%s", res.Step.Name, res.State, res.Err)
+
+			if res.Err != nil {
+				step.Error = res.Err.Error()
+			}
+		}
+
+		out.Steps = append(out.Steps, step)
+	}
+
+	result(out)
+
+	if incomplete > 0 {
+		fatal(errors.Errorf("%d of %d steps did not complete", incomplete, len(results)))
+	}
+}
+
+// planOutput is the result of plan apply and import with --output json or ndjson.
+type planOutput struct {
+	Type  string       `json:"type"`
+	Steps []stepOutput `json:"steps"`
+}
+
+type stepOutput struct {
+	Name      string `json:"name"`
+	State     string `json:"state"`
+	Job       string `json:"job,omitempty"`
+	Processed uint   `json:"processed"`
+	Failed    uint   `json:"failed"`
+	Rejected  int    `json:"rejected"`
+	Error     string `json:"error,omitempty"`
+}
@@ -0,0 +1,260 @@
+package cmd
+
+import (
+	"fmt"
+	"github.com/pkg/errors"
+	"github.com/rfaulhaber/forcedata/auth"
+	"github.com/rfaulhaber/forcedata/job"
+	"github.com/rfaulhaber/forcedata/journal"
+	"github.com/rfaulhaber/forcedata/report"
+	"github.com/rfaulhaber/forcedata/retry"
+	"github.com/spf13/cobra"
+	"io/ioutil"
+	"path/filepath"
+	"time"
+)
+
+// reportCmd represents the report command
+var reportCmd = &cobra.Command{
+	Use:   "report JOBID",
+	Short: "Summarizes what a job did",
+	Long: `Prints a summary of a job in Markdown: its config, how long it took to process, how many records succeeded
+and failed, its failures grouped by error code and field with sample rows, and links to its result files. With
+--summary the summary is written to a Markdown (.md) or HTML (.html) file instead, to attach to a change ticket.`,
+	Args: cobra.ExactArgs(1),
+	Run:  runReport,
+}
+
+var reportOpts struct {
+	summary string
+}
+
+func init() {
+	rootCmd.AddCommand(reportCmd)
+
+	reportCmd.Flags().StringVar(&reportOpts.summary, "summary", "", "Markdown (.md) or HTML (.html) file the summary is written to.")
+}
+
+// reportOutput is the result of report.
+type reportOutput struct {
+	Type     string          `json:"type"`
+	Summary  string          `json:"summary,omitempty"`
+	Jobs     []jobOutput     `json:"jobs"`
+	Failures []failureOutput `json:"failures"`
+}
+
+// failureOutput is a group of failures of a report.
+type failureOutput struct {
+	Code    string `json:"code"`
+	Fields  string `json:"fields"`
+	Message string `json:"message"`
+	Count   int    `json:"count"`
+}
+
+func runReport(cmd *cobra.Command, args []string) {
+	if reportOpts.summary != "" {
+		if _, err := report.Format(reportOpts.summary); err != nil {
+			fatal(err)
+		}
+	}
+
+	session, err := getSession()
+
+	if err != nil {
+		fatal(err)
+	}
+
+	j := job.New(job.JobConfig{}, session)
+	j.SetInfo(job.JobInfo{ID: args[0]})
+
+	info, err := j.GetInfo()
+
+	if err != nil {
+		fatal(errors.Wrap(err, "could not get job "+args[0]))
+	}
+
+	r := &report.Report{
+		Title:     "Job " + info.ID,
+		Generated: time.Now(),
+		Config: job.JobConfig{
+			Object:          info.Object,
+			Operation:       info.Operation,
+			ContentType:     info.ContentType,
+			Delim:           info.ColumnDelimiter,
+			ExternalIDField: info.ExternalIdFieldName,
+		},
+	}
+
+	rj, failed, err := reportJob(session, r.Config, 0, info, "")
+
+	if err != nil {
+		fatal(err)
+	}
+
+	r.Jobs = append(r.Jobs, rj)
+
+	if failed != nil {
+		r.Failures = report.Group(failed)
+	}
+
+	out := reportResult(r, reportOpts.summary)
+
+	if reportOpts.summary != "" {
+		if err := r.Write(reportOpts.summary); err != nil {
+			fatal(err)
+		}
+
+		stdWriter.Println("Summary written to", reportOpts.summary)
+		result(out)
+
+		return
+	}
+
+	b, err := r.Render(report.Markdown)
+
+	if err != nil {
+		fatal(err)
+	}
+
+	stdWriter.Print(string(b))
+	result(out)
+}
+
+// writeSummary writes a summary of a load to path, from its journal. Failed results the load saved are read from their
+// files, and the rest are fetched from the org.
+func writeSummary(session auth.Session, jr *journal.Journal, path string) error {
+	r := &report.Report{
+		Title:     fmt.Sprintf("Load of %s into %s", jr.File, jr.Config.Object),
+		Generated: time.Now(),
+		File:      jr.File,
+		Config:    jr.Config,
+	}
+
+	var failed []*retry.Results
+
+	for i, c := range jr.Chunks {
+		if c.JobID == "" {
+			continue
+		}
+
+		j := job.New(jr.Config, session)
+		j.SetInfo(job.JobInfo{ID: c.JobID})
+
+		info, err := j.GetInfo()
+
+		if err != nil {
+			return errors.Wrap(err, "could not get job "+c.JobID)
+		}
+
+		rj, res, err := reportJob(session, jr.Config, i+1, info, c.FailedResults)
+
+		if err != nil {
+			return err
+		}
+
+		if c.FailedResults != "" {
+			saved := report.Link{Name: fmt.Sprintf("Chunk %d failed records, saved", i+1), URL: relativeTo(path, c.FailedResults)}
+			rj.Results = append([]report.Link{saved}, rj.Results...)
+		}
+
+		r.Jobs = append(r.Jobs, rj)
+
+		if res != nil {
+			failed = append(failed, res)
+		}
+	}
+
+	r.Failures = report.Group(failed...)
+
+	return r.Write(path)
+}
+
+// reportJob returns a job of a report, linking to its results in the org, and its failed results, if it has any.
+// They're read from failedFile if it's set, and fetched from the org otherwise.
+func reportJob(session auth.Session, config job.JobConfig, chunk int, info job.JobInfo, failedFile string) (report.Job, *retry.Results, error) {
+	j := job.New(config, session)
+	j.SetInfo(info)
+
+	rj := report.Job{Chunk: chunk, Info: info}
+	name := "Job " + info.ID
+
+	if chunk > 0 {
+		name = fmt.Sprintf("Chunk %d (job %s)", chunk, info.ID)
+	}
+
+	switch info.State {
+	case "JobComplete":
+		rj.Results = append(rj.Results,
+			report.Link{Name: name + " successful results", URL: j.ResultsURL("successfulResults")},
+			report.Link{Name: name + " failed results", URL: j.ResultsURL("failedResults")})
+	case "Failed", "Aborted":
+		rj.Results = append(rj.Results, report.Link{Name: name + " unprocessed records", URL: j.ResultsURL("unprocessedrecords")})
+	}
+
+	if info.State != "JobComplete" || info.RecordsFailed == 0 {
+		return rj, nil, nil
+	}
+
+	var (
+		b   []byte
+		err error
+	)
+
+	if failedFile != "" {
+		b, err = ioutil.ReadFile(failedFile)
+	} else {
+		b, err = j.GetFailure()
+	}
+
+	if err != nil {
+		return rj, nil, errors.Wrapf(err, "could not get the failed results of job %s", info.ID)
+	}
+
+	delim, _ := job.GetDelim(config.Delim)
+	res, err := retry.Parse(b, csvReader(nil, delim).Comma)
+
+	return rj, res, err
+}
+
+// relativeTo returns path relative to the directory of a summary written to summary, so that links in the summary work
+// wherever it's moved along with the files. Paths that can't be made relative are left alone.
+func relativeTo(summary, path string) string {
+	if path == "" {
+		return ""
+	}
+
+	dir, err := filepath.Abs(filepath.Dir(summary))
+
+	if err != nil {
+		return path
+	}
+
+	abs, err := filepath.Abs(path)
+
+	if err != nil {
+		return path
+	}
+
+	if rel, err := filepath.Rel(dir, abs); err == nil {
+		return filepath.ToSlash(rel)
+	}
+
+	return path
+}
+
+// reportResult returns a report as it's written with --output json or ndjson.
+func reportResult(r *report.Report, summary string) reportOutput {
+	out := reportOutput{Type: "report", Summary: summary, Jobs: []jobOutput{}, Failures: []failureOutput{}}
+
+	for _, j := range r.Jobs {
+		info := infoOutput(j.Info)
+		info.Chunk = j.Chunk
+		out.Jobs = append(out.Jobs, info)
+	}
+
+	for _, f := range r.Failures {
+		out.Failures = append(out.Failures, failureOutput{Code: f.Code, Fields: f.Fields, Message: f.Message, Count: f.Count})
+	}
+
+	return out
+}
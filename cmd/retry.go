@@ -0,0 +1,174 @@
+package cmd
+
+import (
+	"github.com/pkg/errors"
+	"github.com/rfaulhaber/forcedata/job"
+	"github.com/rfaulhaber/forcedata/retry"
+	"github.com/spf13/cobra"
+	"io/ioutil"
+	"strings"
+	"time"
+)
+
+// retryCmd represents the retry command
+var retryCmd = &cobra.Command{
+	Use:   "retry JOBID",
+	Short: "Loads the records a job failed to load again",
+	Long: `Fetches the failed records of a finished job and loads them again with a new job, using the same object,
+operation, delimiter and external ID. --code retries only records that failed with the given error codes, and
+--mapping and --transforms fix records up before they're loaded, as they do for load.
+
+Records that fail because they were locked (UNABLE_TO_LOCK_ROW) are retried again automatically, waiting 10 seconds
+and then twice as long before each further attempt. Records that still fail are written to --results.`,
+	Args: cobra.ExactArgs(1),
+	Run:  runRetry,
+}
+
+var retryOpts struct {
+	codes        []string
+	mapping      string
+	transforms   string
+	rejectFile   string
+	results      string
+	lockAttempts int
+	poll         time.Duration
+}
+
+func init() {
+	rootCmd.AddCommand(retryCmd)
+
+	retryCmd.Flags().StringSliceVar(&retryOpts.codes, "code", nil, "Only retries records that failed with these error codes.")
+	retryCmd.Flags().StringVar(&retryOpts.mapping, "mapping", "", "YAML file mapping the failed records' columns to fields.")
+	retryCmd.Flags().StringVar(&retryOpts.transforms, "transforms", "", "YAML file of value transformations applied to each column.")
+	retryCmd.Flags().StringVar(&retryOpts.rejectFile, "reject-file", "rejects.csv", "File that rows rejected before upload are written to.")
+	retryCmd.Flags().StringVar(&retryOpts.results, "results", "", "File records that still fail are written to (default JOBID.failed.csv).")
+	retryCmd.Flags().IntVar(&retryOpts.lockAttempts, "lock-attempts", retry.DefaultPolicy.Attempts, "Most times records that failed because they were locked are retried.")
+	retryCmd.Flags().DurationVar(&retryOpts.poll, "poll", job.DefaultWatchTime, "How often running jobs are checked.")
+}
+
+func runRetry(cmd *cobra.Command, args []string) {
+	session, err := getSession()
+
+	if err != nil {
+		fatal(err)
+	}
+
+	jobID := args[0]
+
+	config, results, err := retry.Failed(session, jobID)
+
+	if err != nil {
+		fatal(err)
+	}
+
+	if len(retryOpts.codes) > 0 {
+		results, _ = results.Filter(retryOpts.codes)
+	}
+
+	out := retryOutput{Type: "retry", Job: jobID, Records: len(results.Rows), Jobs: []jobOutput{}}
+
+	if len(results.Rows) == 0 {
+		stdWriter.Println("No failed records to retry")
+		result(out)
+		return
+	}
+
+	for _, c := range results.Counts() {
+		verbose.Printf("%d records failed with %s", c.Count, c.Code)
+	}
+
+	delim, _ := job.GetDelim(config.Delim)
+	comma := csvReader(nil, delim).Comma
+
+	content, err := results.Records(comma)
+
+	if err != nil {
+		fatal(err)
+	}
+
+	opts := flagStr{
+		objFlag:        config.Object,
+		mappingFlag:    retryOpts.mapping,
+		transformsFlag: retryOpts.transforms,
+		deleteFlag:     strings.HasSuffix(strings.ToLower(config.Operation), "delete"),
+	}
+
+	stages, err := loadStages(session, content, delim, opts)
+
+	if err != nil {
+		fatal(err)
+	}
+
+	if content, err = transformContent(content, delim, stages, retryOpts.rejectFile, nil); err != nil {
+		fatal(err, "could not transform content:")
+	}
+
+	policy := retry.DefaultPolicy
+	policy.Attempts = retryOpts.lockAttempts
+
+	runner := retry.Runner{
+		Session:      session,
+		Config:       config,
+		Policy:       policy,
+		PollInterval: retryOpts.poll,
+		Log:          progress(stdWriter.Printf),
+	}
+
+	stdWriter.Printf("Retrying %d records of job %s", len(results.Rows), jobID)
+
+	infos, failed, err := runner.Run(content)
+
+	for _, info := range infos {
+		out.Jobs = append(out.Jobs, infoOutput(info))
+	}
+
+	if failed != nil && len(failed.Rows) > 0 {
+		out.Failed, out.Results = len(failed.Rows), retryOpts.results
+
+		if out.Results == "" {
+			out.Results = jobID + ".failed.csv"
+		}
+
+		b, werr := failed.Bytes(comma)
+
+		if werr == nil {
+			werr = ioutil.WriteFile(out.Results, b, 0644)
+		}
+
+		if werr != nil {
+			result(out)
+			fatal(errors.Wrap(werr, "could not write results"))
+		}
+	}
+
+	if err != nil {
+		result(out)
+		fatal(err)
+	}
+
+	if out.Failed == 0 {
+		stdWriter.Println("All records loaded")
+		result(out)
+		return
+	}
+
+	stdWriter.Printf("%d records still failed, see %s", out.Failed, out.Results)
+	result(out)
+
+	if out.Failed >= out.Records {
+		fatal(withCode(errTotalFailure, errors.Errorf("all %d records still failed; see %s", out.Failed, out.Results)))
+	}
+
+	fatal(withCode(errPartialFailure, errors.Errorf("%d of %d records still failed; see %s", out.Failed, out.Records, out.Results)))
+}
+
+// retryOutput is the result of retry with --output json or ndjson. Records are the failed records retried, and Failed
+// those that still failed, which were written to Results.
+type retryOutput struct {
+	Type    string      `json:"type"`
+	Job     string      `json:"job"`
+	Records int         `json:"records"`
+	Jobs    []jobOutput `json:"jobs"`
+	Failed  int         `json:"failed"`
+	Results string      `json:"results,omitempty"`
+}
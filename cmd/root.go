@@ -5,16 +5,21 @@ import (
 	"os"
 
 	"github.com/mitchellh/go-homedir"
+	"github.com/rfaulhaber/forcedata/replay"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 	"io/ioutil"
 	"log"
+	"net/http"
 )
 
 var (
-	cfgFile     string
-	quietFlag   bool
-	verboseFlag bool
+	cfgFile        string
+	quietFlag      bool
+	verboseFlag    bool
+	apiVersionFlag string
+	recordFlag     string
+	replayFlag     string
 
 	verbose   = log.New(ioutil.Discard, "", 0)
 	stdWriter = log.New(os.Stdout, "", 0)
@@ -25,33 +30,34 @@ var rootCmd = &cobra.Command{
 	Use:   "data [OPTIONS] COMMAND",
 	Short: "CLI tool for the Salesforce Bulk API",
 	Long:  `A CLI tool that allows Salesforce developers to do data loads from the terminal.`,
-	PreRun: func(cmd *cobra.Command, args []string) {
-		if verboseFlag {
-			verbose = log.New(os.Stderr, "", 0)
-		}
-
-		if quietFlag {
-			stdWriter = log.New(ioutil.Discard, "", 0)
-		}
-
-		log.SetFlags(0)
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		return setOutput()
 	},
+	SilenceErrors: true,
 }
 
 // Execute adds all child commands to the root command and sets flags appropriately.
 // This is called by main.main(). It only needs to happen once to the rootCmd.
 func Execute() {
 	if err := rootCmd.Execute(); err != nil {
-		fmt.Println(err)
-		os.Exit(1)
+		fatal(err)
 	}
 }
 
 func init() {
-	cobra.OnInitialize(initConfig)
+	log.SetFlags(0)
+
+	cobra.OnInitialize(initConfig, initTransport, initAudit)
 	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "Config file (default is ./config.json)")
 	rootCmd.PersistentFlags().BoolVarP(&quietFlag, "quiet", "q", false, "Suppresses all output to stdout")
 	rootCmd.PersistentFlags().BoolVarP(&verboseFlag, "verbose", "v", false, "Prints debug logs to stderr.")
+	rootCmd.PersistentFlags().StringVar(&outputFlag, "output", outputText, "Output format: text, or json or ndjson for scripts")
+	rootCmd.PersistentFlags().StringVar(&apiVersionFlag, "api-version", "", "Pins the REST API version (e.g. 43.0) instead of using the latest one the org supports")
+
+	rootCmd.PersistentFlags().StringVar(&recordFlag, "record", "", "Records every HTTP request and response to the specified directory, with secrets redacted")
+	rootCmd.PersistentFlags().StringVar(&replayFlag, "replay", "", "Answers HTTP requests from recordings in the specified directory instead of the network")
+
+	viper.BindPFlag("api_version", rootCmd.PersistentFlags().Lookup("api-version"))
 }
 
 // initConfig reads in config file and ENV variables if set.
@@ -68,7 +74,7 @@ func initConfig() {
 		}
 
 		viper.AddConfigPath(".")
-		viper.AddConfigPath(home + "/.config/forcedata")
+		viper.AddConfigPath(configDir(home))
 		viper.AddConfigPath("/etc/forcedata")
 		viper.SetConfigName("config")
 	}
@@ -78,3 +84,38 @@ func initConfig() {
 	// If a config file is found, read it in.
 	viper.ReadInConfig()
 }
+
+// configDir returns the directory forcedata keeps its user-level files in.
+func configDir(home string) string {
+	return home + "/.config/forcedata"
+}
+
+// initTransport routes all HTTP traffic through a recorder or player if --record or --replay were specified.
+func initTransport() {
+	if recordFlag != "" && replayFlag != "" {
+		fmt.Println("--record and --replay cannot be used together")
+		os.Exit(1)
+	}
+
+	if recordFlag != "" {
+		recorder, err := replay.NewRecorder(recordFlag, http.DefaultTransport)
+
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+
+		http.DefaultClient.Transport = recorder
+	}
+
+	if replayFlag != "" {
+		player, err := replay.NewPlayer(replayFlag)
+
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+
+		http.DefaultClient.Transport = player
+	}
+}
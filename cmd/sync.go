@@ -0,0 +1,428 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/csv"
+	"github.com/pkg/errors"
+	"github.com/rfaulhaber/forcedata/auth"
+	"github.com/rfaulhaber/forcedata/charset"
+	"github.com/rfaulhaber/forcedata/diff"
+	"github.com/rfaulhaber/forcedata/job"
+	"github.com/rfaulhaber/forcedata/limits"
+	"github.com/spf13/cobra"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Files written by sync to the plan directory.
+const (
+	syncInsertsFile = "sync-inserts.csv"
+	syncUpdatesFile = "sync-updates.csv"
+	syncDeletesFile = "sync-deletes.csv"
+	syncChangesFile = "sync-changes.csv"
+)
+
+// syncCmd represents the sync command
+var syncCmd = &cobra.Command{
+	Use:   "sync FILE",
+	Short: "Loads only the records of a file that differ from the org",
+	Long: `Compares a CSV file with the records already in the org, matching them on the --key column, and loads only the
+difference: rows whose key isn't in the org are inserted, and rows whose values differ are updated. With --delete,
+records with a key that isn't in the file are deleted as well; records without a key, and records matching rows that
+were rejected, are never touched.
+
+Only the fields in the file, after any mapping, transforms and masking, are compared. Numbers, booleans and dates are
+compared by value, so 1.0 and 1 aren't a change. A value that's blank in the file clears the field.
+
+With --plan-only, nothing is loaded. The rows that would be inserted, updated and deleted are written to
+sync-inserts.csv, sync-updates.csv and sync-deletes.csv, and every changed field with its old and new value to
+sync-changes.csv, in --plan-dir.`,
+	Args: cobra.ExactArgs(1),
+	Run:  runSync,
+}
+
+var (
+	syncOpts flagStr
+
+	syncFlags struct {
+		key      string
+		deletes  bool
+		planOnly bool
+		planDir  string
+		poll     time.Duration
+	}
+)
+
+func init() {
+	rootCmd.AddCommand(syncCmd)
+	syncCmd.Flags().StringVar(&syncOpts.objFlag, "object", "", "Object the file is synced with.")
+	syncCmd.Flags().StringVar(&syncFlags.key, "key", "", "Column, after mapping, whose values identify records in the org; usually an external ID.")
+	syncCmd.Flags().BoolVar(&syncFlags.deletes, "delete", false, "Deletes records whose key isn't in the file.")
+	syncCmd.Flags().BoolVar(&syncFlags.planOnly, "plan-only", false, "Writes the changes to files instead of loading them.")
+	syncCmd.Flags().StringVar(&syncFlags.planDir, "plan-dir", ".", "Directory the changes, and records that failed to load, are written to.")
+	syncCmd.Flags().DurationVar(&syncFlags.poll, "poll", job.DefaultWatchTime, "How often running jobs are checked.")
+	syncCmd.Flags().StringVar(&syncOpts.delimFlag, "delim", ",", "Delimiter used in the file, or auto to detect it.")
+	syncCmd.Flags().StringVar(&syncOpts.quoteFlag, "quote", `"`, "Quote character used in the file, or none.")
+	syncCmd.Flags().StringVar(&syncOpts.commentFlag, "comment", "", "Lines starting with this character are ignored.")
+	syncCmd.Flags().IntVar(&syncOpts.skipRowsFlag, "skip-rows", 0, "Number of lines before the header to ignore.")
+	syncCmd.Flags().StringVar(&syncOpts.encodingFlag, "encoding", charset.Auto, "Character encoding of the file: auto, utf-8, utf-16le, utf-16be, windows-1252 or iso-8859-1.")
+	syncCmd.Flags().StringVar(&syncOpts.mappingFlag, "mapping", "", "YAML file mapping source columns to fields.")
+	syncCmd.Flags().StringVar(&syncOpts.transformsFlag, "transforms", "", "YAML file of value transformations applied to each column.")
+	syncCmd.Flags().StringVar(&syncOpts.maskFlag, "mask", "", "YAML file of masking rules applied to the object's fields.")
+	syncCmd.Flags().StringVar(&syncOpts.maskSeedFlag, "mask-seed", "", "Secret that keys masked values. Required with --mask.")
+	syncCmd.Flags().StringVar(&syncOpts.rejectFileFlag, "reject-file", "rejects.csv", "File that rows rejected before comparing are written to.")
+	syncCmd.Flags().BoolVar(&syncOpts.forceFlag, "force", false, "Load even if the load would exceed the org's remaining limits.")
+
+	syncCmd.MarkFlagRequired("object")
+	syncCmd.MarkFlagRequired("key")
+}
+
+func runSync(cmd *cobra.Command, args []string) {
+	syncOpts.quoteChanged = cmd.Flags().Changed("quote")
+
+	session, err := getSession()
+
+	if err != nil {
+		fatal(err)
+	}
+
+	content, err := ioutil.ReadFile(args[0])
+
+	if err != nil {
+		fatal(err, "could not read file:")
+	}
+
+	if content, err = normalizeContent(content, syncOpts.encodingFlag); err != nil {
+		fatal(err)
+	}
+
+	content, delim, err := applyDialect(content, syncOpts)
+
+	if err != nil {
+		fatal(err)
+	}
+
+	stages, err := loadStages(session, content, delim, syncOpts)
+
+	if err != nil {
+		fatal(err)
+	}
+
+	// rows rejected on their way to being compared are still in the file, so the records they match mustn't be deleted
+	var (
+		kept    []string
+		unkeyed int
+	)
+
+	rejecting := func(header, row []string) {
+		for i, h := range header {
+			if strings.EqualFold(h, syncFlags.key) && i < len(row) {
+				kept = append(kept, row[i])
+				return
+			}
+		}
+
+		unkeyed++
+	}
+
+	if content, err = transformContent(content, delim, stages, syncOpts.rejectFileFlag, rejecting); err != nil {
+		fatal(err, "could not transform content:")
+	}
+
+	if unkeyed > 0 && syncFlags.deletes {
+		fatal(errors.Errorf("%d rows were rejected before they had a %s column, so the records they match can't be kept from being deleted; fix them or sync without --delete", unkeyed, syncFlags.key))
+	}
+
+	records, err := csvReader(content, delim).ReadAll()
+
+	if err != nil {
+		fatal(err, "could not read content:")
+	}
+
+	if len(records) == 0 {
+		fatal(errors.New("file is empty"))
+	}
+
+	header, rows := records[0], records[1:]
+
+	verbose.Println("querying", syncOpts.objFlag, "records...")
+
+	org, err := diff.Org(session, syncOpts.objFlag, syncFlags.key, header)
+
+	if err != nil {
+		fatal(err)
+	}
+
+	d, err := diff.Compare(header, rows, syncFlags.key, org, kept)
+
+	if err != nil {
+		fatal(err)
+	}
+
+	if !syncFlags.deletes {
+		if len(d.Deletes) > 0 {
+			verbose.Printf("%d records in the org aren't in the file; use --delete to delete them", len(d.Deletes))
+		}
+
+		d.Deletes = nil
+	}
+
+	stdWriter.Printf("%d to insert, %d to update, %d to delete, %d unchanged", len(d.Inserts), len(d.Updates), len(d.Deletes), d.Unchanged)
+
+	out := syncOutput{
+		Type:      "sync",
+		Inserts:   len(d.Inserts),
+		Updates:   len(d.Updates),
+		Deletes:   len(d.Deletes),
+		Unchanged: d.Unchanged,
+		Files:     []string{},
+		Jobs:      []jobOutput{},
+	}
+
+	if syncFlags.planOnly {
+		if out.Files, err = writeSyncPlan(d, syncFlags.key, syncFlags.planDir); err != nil {
+			fatal(err)
+		}
+
+		result(out)
+		return
+	}
+
+	applySync(session, d, out)
+}
+
+// syncOutput is the result of a sync with --output json or ndjson. Files are the plan files written by --plan-only.
+type syncOutput struct {
+	Type      string       `json:"type"`
+	Inserts   int          `json:"inserts"`
+	Updates   int          `json:"updates"`
+	Deletes   int          `json:"deletes"`
+	Unchanged int          `json:"unchanged"`
+	Files     []string     `json:"files"`
+	Jobs      []jobOutput  `json:"jobs"`
+	Consumed  limits.Usage `json:"consumed,omitempty"`
+}
+
+// syncJob is one of the jobs a sync runs.
+type syncJob struct {
+	operation string
+	header    []string
+	rows      [][]string
+}
+
+// syncJobs returns the jobs that make the changes of d, leaving out those with nothing to do.
+func syncJobs(d *diff.Diff) []syncJob {
+	updateHeader, updateRows := d.UpdateRows()
+
+	deletes := make([][]string, len(d.Deletes))
+
+	for i, id := range d.Deletes {
+		deletes[i] = []string{id}
+	}
+
+	var jobs []syncJob
+
+	for _, j := range []syncJob{
+		{"insert", d.Header, d.Inserts},
+		{"update", updateHeader, updateRows},
+		{"delete", []string{"Id"}, deletes},
+	} {
+		if len(j.rows) > 0 {
+			jobs = append(jobs, j)
+		}
+	}
+
+	return jobs
+}
+
+// applySync runs a job for each kind of change in turn, and exits with an error if any didn't complete.
+func applySync(session auth.Session, d *diff.Diff, out syncOutput) {
+	jobs := syncJobs(d)
+
+	if len(jobs) == 0 {
+		stdWriter.Println("Nothing to load")
+		result(out)
+		return
+	}
+
+	count := 0
+
+	for _, j := range jobs {
+		count += len(j.rows)
+	}
+
+	before, checked := checkLimits(session, count, len(jobs), syncOpts.forceFlag)
+
+	incomplete, unfinished := 0, 0
+
+	for _, sj := range jobs {
+		config := job.JobConfig{
+			Object:      syncOpts.objFlag,
+			Operation:   sj.operation,
+			Delim:       "COMMA",
+			ContentType: "CSV",
+		}
+
+		info, err := runSyncJob(session, config, sj)
+		j := infoOutput(info)
+
+		if err != nil {
+			incomplete++
+
+			// jobs that weren't created or uploaded to have nothing to process
+			switch info.State {
+			case "JobComplete", "Failed", "Aborted":
+			default:
+				if info.ID != "" {
+					unfinished++
+				}
+			}
+
+			stdWriter.Printf("%s\t%s", sj.operation, err)
+			j.Object, j.Operation, j.Error = config.Object, config.Operation, err.Error()
+		} else {
+			stdWriter.Printf("%s\t%s\tprocessed: %d\tfailed: %d", sj.operation, info.ID, info.RecordsProcessed, info.RecordsFailed)
+		}
+
+		event(j)
+		out.Jobs = append(out.Jobs, j)
+	}
+
+	// a job that couldn't be checked until it finished may still consume more
+	if checked && unfinished == 0 {
+		out.Consumed = reportConsumption(session, before)
+	} else if checked {
+		stdWriter.Println("Limits consumed aren't known until the jobs finish")
+	}
+
+	result(out)
+
+	if incomplete > 0 {
+		fatal(errors.Errorf("%d of %d jobs did not complete", incomplete, len(jobs)))
+	}
+}
+
+// runSyncJob loads the rows of a sync job and waits for it to finish, writing records that failed to the plan
+// directory.
+func runSyncJob(session auth.Session, config job.JobConfig, sj syncJob) (job.JobInfo, error) {
+	content, err := csvContent(sj.header, sj.rows)
+
+	if err != nil {
+		return job.JobInfo{}, err
+	}
+
+	j := job.New(config, session)
+
+	if err := j.Create(); err != nil {
+		return job.JobInfo{}, errors.Wrap(err, "could not create job")
+	}
+
+	if err := j.Upload(content); err != nil {
+		return job.JobInfo{}, errors.Wrap(err, "could not upload content to job")
+	}
+
+	info, err := j.Wait(syncFlags.poll)
+
+	if err != nil {
+		// the job was created and may still be running
+		return job.JobInfo{ID: j.ID(), State: info.State}, errors.Wrap(err, "could not check job")
+	}
+
+	if info.State != "JobComplete" {
+		return info, errors.Errorf("job %s %s: %s", info.ID, strings.ToLower(info.State), info.ErrorMessage)
+	}
+
+	if info.RecordsFailed > 0 {
+		failed, err := j.GetFailure()
+
+		if err != nil {
+			return info, err
+		}
+
+		path := filepath.Join(syncFlags.planDir, "sync-"+sj.operation+".failed.csv")
+
+		if err := ioutil.WriteFile(path, failed, 0644); err != nil {
+			return info, errors.Wrap(err, "could not write "+path)
+		}
+	}
+
+	return info, nil
+}
+
+// writeSyncPlan writes the changes of d to files in dir for review, skipping kinds of change there are none of, and
+// returns the files written.
+func writeSyncPlan(d *diff.Diff, key, dir string) ([]string, error) {
+	changes := [][]string{}
+	k := 0
+
+	for i, h := range d.Header {
+		if strings.EqualFold(h, key) {
+			k = i
+		}
+	}
+
+	for _, u := range d.Updates {
+		for _, c := range u.Changes {
+			changes = append(changes, []string{u.Row[k], u.ID, c.Field, c.Old, c.New})
+		}
+	}
+
+	files := map[string]syncJob{
+		syncChangesFile: {header: []string{d.Header[k], "Id", "Field", "Old Value", "New Value"}, rows: changes},
+	}
+
+	for _, j := range syncJobs(d) {
+		switch j.operation {
+		case "insert":
+			files[syncInsertsFile] = j
+		case "update":
+			files[syncUpdatesFile] = j
+		case "delete":
+			files[syncDeletesFile] = j
+		}
+	}
+
+	written := []string{}
+
+	for _, name := range []string{syncInsertsFile, syncUpdatesFile, syncDeletesFile, syncChangesFile} {
+		j, ok := files[name]
+
+		if !ok || len(j.rows) == 0 {
+			continue
+		}
+
+		content, err := csvContent(j.header, j.rows)
+
+		if err != nil {
+			return written, err
+		}
+
+		path := filepath.Join(dir, name)
+
+		if err := ioutil.WriteFile(path, content, 0644); err != nil {
+			return written, errors.Wrap(err, "could not write "+path)
+		}
+
+		stdWriter.Println("Wrote", path)
+		written = append(written, path)
+	}
+
+	return written, nil
+}
+
+// csvContent returns a header and rows as comma delimited CSV.
+func csvContent(header []string, rows [][]string) ([]byte, error) {
+	var buf bytes.Buffer
+
+	w := csv.NewWriter(&buf)
+	w.Write(header)
+	w.WriteAll(rows)
+
+	if err := w.Error(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,151 @@
+package cmd
+
+import (
+	"github.com/pkg/errors"
+	"github.com/rfaulhaber/forcedata/auth"
+	"github.com/rfaulhaber/forcedata/charset"
+	"github.com/rfaulhaber/forcedata/describe"
+	"github.com/rfaulhaber/forcedata/job"
+	"github.com/rfaulhaber/forcedata/validate"
+	"github.com/spf13/cobra"
+	"io/ioutil"
+	"log"
+)
+
+// maxReportedIssues caps how many validation problems are printed.
+const maxReportedIssues = 100
+
+// validateCmd represents the validate command
+var validateCmd = &cobra.Command{
+	Use:   "validate FILE",
+	Short: "Checks a CSV file against an object's describe without loading it",
+	Long: `Checks that a CSV file's headers map to fields of the object that can be written for the chosen operation, that
+required fields are present, and that values match their field's type, maximum length and restricted picklist values.
+Problems are reported by row and column. Nothing is created in the org.
+
+The same checks run before every load unless --skip-validation is given to load.`,
+	Args:    cobra.ExactArgs(1),
+	PreRunE: preRunValidate,
+	Run:     runValidate,
+}
+
+var validateOpts flagStr
+
+func init() {
+	rootCmd.AddCommand(validateCmd)
+	validateCmd.Flags().StringVar(&validateOpts.delimFlag, "delim", ",", "Delimiter used in the file, or auto to detect it.")
+	validateCmd.Flags().StringVar(&validateOpts.quoteFlag, "quote", `"`, "Quote character used in the file, or none.")
+	validateCmd.Flags().StringVar(&validateOpts.commentFlag, "comment", "", "Lines starting with this character are ignored.")
+	validateCmd.Flags().IntVar(&validateOpts.skipRowsFlag, "skip-rows", 0, "Number of lines before the header to ignore.")
+	validateCmd.Flags().StringVar(&validateOpts.encodingFlag, "encoding", charset.Auto, "Character encoding of the file: auto, utf-8, utf-16le, utf-16be, windows-1252 or iso-8859-1.")
+	validateCmd.Flags().StringVar(&validateOpts.objFlag, "object", "", "Object the file would be loaded into.")
+	validateCmd.Flags().StringVar(&validateOpts.externalIDFlag, "external-id", "", "External ID field used to match records. Required for upsert jobs.")
+	validateCmd.Flags().BoolVarP(&validateOpts.insertFlag, "insert", "i", false, "Operation flag. Validates for an insert job.")
+	validateCmd.Flags().BoolVarP(&validateOpts.updateFlag, "update", "u", false, "Operation flag. Validates for an update job.")
+	validateCmd.Flags().BoolVar(&validateOpts.upsertFlag, "upsert", false, "Operation flag. Validates for an upsert job.")
+	validateCmd.Flags().BoolVarP(&validateOpts.deleteFlag, "delete", "d", false, "Operation flag. Validates for a delete job.")
+
+	validateCmd.MarkFlagRequired("object")
+}
+
+func preRunValidate(cmd *cobra.Command, args []string) error {
+	_, err := validateFlags(validateOpts)
+	return err
+}
+
+func runValidate(cmd *cobra.Command, args []string) {
+	validateOpts.quoteChanged = cmd.Flags().Changed("quote")
+
+	session, err := getSession()
+
+	if err != nil {
+		fatal(err)
+	}
+
+	op, _ := validateFlags(validateOpts)
+
+	content, err := ioutil.ReadFile(args[0])
+
+	if err != nil {
+		fatal(err, "could not read file:")
+	}
+
+	if content, err = normalizeContent(content, validateOpts.encodingFlag); err != nil {
+		fatal(err)
+	}
+
+	content, delim, err := applyDialect(content, validateOpts)
+
+	if err != nil {
+		fatal(err)
+	}
+
+	config := job.JobConfig{
+		Object:          validateOpts.objFlag,
+		Operation:       op,
+		ExternalIDField: validateOpts.externalIDFlag,
+	}
+
+	if err := validateContent(session, config, content, delim); err != nil {
+		fatal(err)
+	}
+
+	stdWriter.Println("No problems found.")
+	result(validateOutput{Type: "validation", Object: config.Object, Problems: []validate.Issue{}})
+}
+
+// validateOutput is the result of validate with --output json or ndjson when no problems were found. Problems that
+// were found are the details of the error written instead.
+type validateOutput struct {
+	Type     string           `json:"type"`
+	Object   string           `json:"object"`
+	Problems []validate.Issue `json:"problems"`
+}
+
+// validateContent checks content against the describe of the config's object, printing a report of every problem
+// found. The returned error wraps validate.ErrInvalid if there were any.
+func validateContent(session auth.Session, config job.JobConfig, content []byte, delim string) error {
+	sobject, err := describe.Get(session, config.Object)
+
+	if err != nil {
+		return errors.Wrap(err, "could not describe "+config.Object)
+	}
+
+	v := validate.New(sobject, config.Operation, config.ExternalIDField)
+
+	if err := validate.CSV(csvReader(content, delim), v); err != nil {
+		return err
+	}
+
+	issues := v.Issues()
+
+	if err := v.Err(); err != nil && machineOutput() {
+		return &validationError{err, issues}
+	}
+
+	for i, issue := range issues {
+		if i == maxReportedIssues {
+			log.Printf("... and %d more", len(issues)-maxReportedIssues)
+			break
+		}
+
+		log.Println(issue)
+	}
+
+	return v.Err()
+}
+
+// validationError is returned by validateContent with --output json or ndjson, where problems are reported as the
+// details of the error rather than printed.
+type validationError struct {
+	err    error
+	issues []validate.Issue
+}
+
+func (e *validationError) Error() string {
+	return e.err.Error()
+}
+
+func (e *validationError) Unwrap() error {
+	return e.err
+}
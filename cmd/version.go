@@ -20,5 +20,13 @@ func init() {
 }
 
 func printVersion(cmd *cobra.Command, args []string) {
+	if machineOutput() {
+		result(struct {
+			Type    string `json:"type"`
+			Version string `json:"version"`
+		}{"version", version})
+		return
+	}
+
 	fmt.Println("ForceData v" + version)
 }
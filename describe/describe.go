@@ -0,0 +1,127 @@
+package describe
+
+import (
+	"encoding/json"
+	"github.com/pkg/errors"
+	"github.com/rfaulhaber/forcedata/auth"
+	"github.com/rfaulhaber/forcedata/job"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// SObject is the describe metadata of an object, limited to what forcedata uses.
+type SObject struct {
+	Name       string  `json:"name"`
+	Label      string  `json:"label"`
+	KeyPrefix  string  `json:"keyPrefix"`
+	Createable bool    `json:"createable"`
+	Updateable bool    `json:"updateable"`
+	Deletable  bool    `json:"deletable"`
+	Fields     []Field `json:"fields"`
+}
+
+// Field is the describe metadata of a single field.
+type Field struct {
+	Name               string          `json:"name"`
+	Label              string          `json:"label"`
+	Type               string          `json:"type"`
+	Length             int             `json:"length"`
+	Precision          int             `json:"precision"`
+	Scale              int             `json:"scale"`
+	Nillable           bool            `json:"nillable"`
+	DefaultedOnCreate  bool            `json:"defaultedOnCreate"`
+	Createable         bool            `json:"createable"`
+	Updateable         bool            `json:"updateable"`
+	ExternalID         bool            `json:"externalId"`
+	IDLookup           bool            `json:"idLookup"`
+	Unique             bool            `json:"unique"`
+	RestrictedPicklist bool            `json:"restrictedPicklist"`
+	PicklistValues     []PicklistValue `json:"picklistValues"`
+	ReferenceTo        []string        `json:"referenceTo"`
+	RelationshipName   string          `json:"relationshipName"`
+}
+
+// PicklistValue is one of the values of a picklist field.
+type PicklistValue struct {
+	Value  string `json:"value"`
+	Label  string `json:"label"`
+	Active bool   `json:"active"`
+}
+
+// Get retrieves the describe metadata of an object.
+func Get(session auth.Session, object string) (SObject, error) {
+	req, err := http.NewRequest("GET", session.DataURL()+"/sobjects/"+url.PathEscape(object)+"/describe", nil)
+
+	if err != nil {
+		return SObject{}, errors.Wrap(err, "could not generate describe request")
+	}
+
+	req.Header.Add("Accept", "application/json")
+	req.Header.Add("Authorization", "Bearer "+session.AccessToken)
+
+	resp, err := http.DefaultClient.Do(req)
+
+	if err != nil {
+		return SObject{}, errors.Wrap(err, "describe request failed")
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return SObject{}, errors.Wrap(job.NewRequestError(resp), "describe "+object)
+	}
+
+	body, err := ioutil.ReadAll(resp.Body)
+
+	if err != nil {
+		return SObject{}, errors.Wrap(err, "could not read describe response")
+	}
+
+	var sobject SObject
+
+	if err := json.Unmarshal(body, &sobject); err != nil {
+		return SObject{}, errors.Wrap(err, "could not parse describe response")
+	}
+
+	return sobject, nil
+}
+
+// Field returns the field with the given API name, ignoring case as Salesforce does.
+func (o SObject) Field(name string) (Field, bool) {
+	for _, f := range o.Fields {
+		if strings.EqualFold(f.Name, name) {
+			return f, true
+		}
+	}
+
+	return Field{}, false
+}
+
+// Relationship returns the reference field whose relationship name is name, e.g. the AccountId field for "Account".
+func (o SObject) Relationship(name string) (Field, bool) {
+	for _, f := range o.Fields {
+		if f.RelationshipName != "" && strings.EqualFold(f.RelationshipName, name) {
+			return f, true
+		}
+	}
+
+	return Field{}, false
+}
+
+// Required returns true if a value must be provided for the field when creating a record.
+func (f Field) Required() bool {
+	return f.Createable && !f.Nillable && !f.DefaultedOnCreate && f.Type != "boolean"
+}
+
+// IsPicklistValue returns true if value is one of the field's active picklist values.
+func (f Field) IsPicklistValue(value string) bool {
+	for _, p := range f.PicklistValues {
+		if p.Active && p.Value == value {
+			return true
+		}
+	}
+
+	return false
+}
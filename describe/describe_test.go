@@ -0,0 +1,71 @@
+package describe
+
+import (
+	"errors"
+	"github.com/rfaulhaber/forcedata/auth"
+	"github.com/rfaulhaber/forcedata/job"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestGet(t *testing.T) {
+	var actualPath string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		actualPath = r.URL.Path
+
+		w.Write([]byte(`{
+			"name": "Contact",
+			"createable": true,
+			"fields": [
+				{"name": "Id", "type": "id", "length": 18, "nillable": false, "createable": false},
+				{"name": "LastName", "type": "string", "length": 80, "nillable": false, "createable": true, "updateable": true},
+				{"name": "AccountId", "type": "reference", "nillable": true, "createable": true, "referenceTo": ["Account"], "relationshipName": "Account"}
+			]
+		}`))
+	}))
+	defer server.Close()
+
+	result, err := Get(auth.Session{InstanceURL: server.URL, APIVersion: "44.0"}, "Contact")
+
+	assert.NoError(t, err)
+	assert.Equal(t, "/services/data/v44.0/sobjects/Contact/describe", actualPath)
+	assert.Len(t, result.Fields, 3)
+
+	lastName, ok := result.Field("lastname")
+
+	assert.True(t, ok)
+	assert.True(t, lastName.Required())
+	assert.Equal(t, 80, lastName.Length)
+
+	accountID, ok := result.Relationship("Account")
+
+	assert.True(t, ok)
+	assert.Equal(t, "AccountId", accountID.Name)
+	assert.False(t, accountID.Required())
+}
+
+func TestGetError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(404)
+		w.Write([]byte(`[{"errorCode":"NOT_FOUND","message":"The requested resource does not exist"}]`))
+	}))
+	defer server.Close()
+
+	_, err := Get(auth.Session{InstanceURL: server.URL}, "Nope__c")
+
+	var reqErr *job.RequestError
+
+	assert.True(t, errors.As(err, &reqErr))
+	assert.Equal(t, "NOT_FOUND", reqErr.Code())
+}
+
+func TestField_IsPicklistValue(t *testing.T) {
+	f := Field{PicklistValues: []PicklistValue{{Value: "Open", Active: true}, {Value: "Legacy", Active: false}}}
+
+	assert.True(t, f.IsPicklistValue("Open"))
+	assert.False(t, f.IsPicklistValue("Legacy"))
+	assert.False(t, f.IsPicklistValue("open"))
+}
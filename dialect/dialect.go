@@ -0,0 +1,331 @@
+// Package dialect reads CSV files written with other delimiters, quote characters, comments and leading junk rows,
+// and rewrites them in a dialect the Bulk API accepts.
+package dialect
+
+import (
+	"bytes"
+	"encoding/csv"
+	"github.com/pkg/errors"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// Auto is given in place of a delimiter to have it sniffed from the content.
+const Auto = "auto"
+
+// sniffLines is how many lines Sniff looks at.
+const sniffLines = 20
+
+// salesforceDelims are the column delimiters a job can be created with.
+const salesforceDelims = ",;|^`\t"
+
+// candidates are the delimiters Sniff chooses between, in order of preference when they score the same.
+var candidates = []rune{',', ';', '\t', '|', '^', '`'}
+
+// quotes are the quote characters Sniff chooses between.
+var quotes = []rune{'"', '\''}
+
+// Default is the dialect the Bulk API expects.
+var Default = Dialect{Delim: ',', Quote: '"'}
+
+// Dialect describes how a CSV file is written.
+type Dialect struct {
+	// Delim separates fields.
+	Delim rune
+
+	// Quote surrounds fields containing delimiters, quotes or line breaks, and is doubled to escape it within one.
+	// Zero means fields are never quoted.
+	Quote rune
+
+	// Comment starts lines that are ignored. Zero means there are no comments.
+	Comment rune
+
+	// SkipRows is the number of lines before the header that are ignored.
+	SkipRows int
+}
+
+// ParseDelim parses a delimiter flag. Tab may be given as a tab character, the two characters `\t`, or "tab".
+func ParseDelim(delim string) (rune, error) {
+	switch strings.ToLower(delim) {
+	case "\\t", "tab":
+		return '\t', nil
+	}
+
+	return parseRune("delimiter", delim)
+}
+
+// ParseQuote parses a quote flag. An empty string or "none" means fields aren't quoted.
+func ParseQuote(quote string) (rune, error) {
+	if quote == "" || strings.ToLower(quote) == "none" {
+		return 0, nil
+	}
+
+	return parseRune("quote", quote)
+}
+
+// ParseComment parses a comment flag. An empty string means there are no comments.
+func ParseComment(comment string) (rune, error) {
+	if comment == "" {
+		return 0, nil
+	}
+
+	return parseRune("comment", comment)
+}
+
+func parseRune(name, s string) (rune, error) {
+	r, size := utf8.DecodeRuneInString(s)
+
+	if r == utf8.RuneError || size != len(s) || r == '\n' || r == '\r' {
+		return 0, errors.Errorf("invalid %s %q: must be a single character", name, s)
+	}
+
+	return r, nil
+}
+
+// Sniff guesses the delimiter and quote character of content from its first lines, after skipping the dialect's
+// SkipRows and comment lines. The delimiter is the candidate that appears the same number of times on the most lines.
+// The dialect's other settings are kept.
+func Sniff(content []byte, d Dialect) Dialect {
+	d.Quote = sniffQuote(sample(content, d))
+
+	return SniffDelim(content, d)
+}
+
+// SniffDelim guesses the delimiter of content as Sniff does, for a file whose quote character is known to be the
+// dialect's.
+func SniffDelim(content []byte, d Dialect) Dialect {
+	lines := sample(content, d)
+
+	d.Delim = Default.Delim
+
+	bestLines, bestCount := 0, 0
+
+	for _, delim := range candidates {
+		matching, count := consistency(lines, delim, d.Quote)
+
+		if matching > bestLines || matching == bestLines && count > bestCount {
+			bestLines, bestCount = matching, count
+			d.Delim = delim
+		}
+	}
+
+	return d
+}
+
+// sample returns the first lines of content that a reader would see as records.
+func sample(content []byte, d Dialect) []string {
+	var lines []string
+
+	for i, line := range strings.Split(string(content), "\n") {
+		if i < d.SkipRows || line == "" || d.Comment != 0 && strings.HasPrefix(line, string(d.Comment)) {
+			continue
+		}
+
+		lines = append(lines, line)
+
+		if len(lines) == sniffLines {
+			break
+		}
+	}
+
+	return lines
+}
+
+// sniffQuote returns the quote character that starts the most fields in lines, preferring a double quote.
+func sniffQuote(lines []string) rune {
+	best, bestCount := Default.Quote, 0
+
+	for _, q := range quotes {
+		count := 0
+
+		for _, line := range lines {
+			if strings.HasPrefix(line, string(q)) {
+				count++
+			}
+
+			for _, delim := range candidates {
+				count += strings.Count(line, string(delim)+string(q))
+			}
+		}
+
+		if count > bestCount {
+			best, bestCount = q, count
+		}
+	}
+
+	return best
+}
+
+// consistency returns the number of lines with the same count of delim outside quotes as the first line, and that
+// count. Delimiters that aren't in the first line match no lines.
+func consistency(lines []string, delim, quote rune) (int, int) {
+	if len(lines) == 0 {
+		return 0, 0
+	}
+
+	want := countOutside(lines[0], delim, quote)
+
+	if want == 0 {
+		return 0, 0
+	}
+
+	matching := 0
+
+	for _, line := range lines {
+		if countOutside(line, delim, quote) == want {
+			matching++
+		}
+	}
+
+	return matching, want
+}
+
+func countOutside(line string, delim, quote rune) int {
+	count := 0
+	quoted := false
+
+	for _, r := range line {
+		switch {
+		case quote != 0 && r == quote:
+			quoted = !quoted
+		case r == delim && !quoted:
+			count++
+		}
+	}
+
+	return count
+}
+
+// Compatible returns true if content in the dialect can be uploaded as it is.
+func (d Dialect) Compatible() bool {
+	return strings.ContainsRune(salesforceDelims, d.Delim) && d.Quote == '"' && d.Comment == 0 && d.SkipRows == 0
+}
+
+// Convert rewrites content in the dialect as comma delimited CSV quoted with double quotes.
+func (d Dialect) Convert(content []byte) ([]byte, error) {
+	r := d.NewReader(content)
+
+	var buf bytes.Buffer
+
+	w := csv.NewWriter(&buf)
+
+	for {
+		record, err := r.Read()
+
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		if err := w.Write(record); err != nil {
+			return nil, err
+		}
+	}
+
+	w.Flush()
+
+	return buf.Bytes(), w.Error()
+}
+
+// Reader reads records from content in a dialect.
+type Reader struct {
+	dialect Dialect
+	content []rune
+	pos     int
+	line    int
+}
+
+// NewReader returns a reader of content in the dialect. Line endings must already be LF.
+func (d Dialect) NewReader(content []byte) *Reader {
+	r := &Reader{dialect: d, content: []rune(string(content))}
+
+	for ; r.line < d.SkipRows && r.pos < len(r.content); r.pos++ {
+		if r.content[r.pos] == '\n' {
+			r.line++
+		}
+	}
+
+	return r
+}
+
+// Read returns the next record, or io.EOF if there are none left. Blank and comment lines are skipped.
+func (r *Reader) Read() ([]string, error) {
+	for r.pos < len(r.content) {
+		c := r.content[r.pos]
+
+		if c == '\n' || r.dialect.Comment != 0 && c == r.dialect.Comment {
+			r.skipLine()
+			continue
+		}
+
+		return r.record()
+	}
+
+	return nil, io.EOF
+}
+
+func (r *Reader) skipLine() {
+	for r.pos < len(r.content) && r.content[r.pos] != '\n' {
+		r.pos++
+	}
+
+	r.pos++
+	r.line++
+}
+
+func (r *Reader) record() ([]string, error) {
+	var (
+		record []string
+		field  []rune
+	)
+
+	start := r.line + 1
+	quoted := false
+	wasQuoted := false
+
+	for ; r.pos < len(r.content); r.pos++ {
+		c := r.content[r.pos]
+
+		switch {
+		case quoted && c == r.dialect.Quote:
+			if r.pos+1 < len(r.content) && r.content[r.pos+1] == r.dialect.Quote {
+				field = append(field, c)
+				r.pos++
+			} else {
+				quoted = false
+			}
+		case quoted:
+			if c == '\n' {
+				r.line++
+			}
+
+			field = append(field, c)
+		case c == r.dialect.Quote && r.dialect.Quote != 0 && len(field) == 0 && !wasQuoted:
+			quoted = true
+			wasQuoted = true
+		case c == r.dialect.Delim:
+			record = append(record, string(field))
+			field = field[:0]
+			wasQuoted = false
+		case c == '\n':
+			r.pos++
+			r.line++
+
+			return append(record, string(field)), nil
+		case wasQuoted:
+			return nil, errors.Errorf("line %d: unexpected %q after closing quote", r.line+1, c)
+		default:
+			field = append(field, c)
+		}
+	}
+
+	if quoted {
+		return nil, errors.Errorf("line %d: quoted field not closed", start)
+	}
+
+	return append(record, string(field)), nil
+}
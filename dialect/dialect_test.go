@@ -0,0 +1,119 @@
+package dialect
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestParseDelim(t *testing.T) {
+	for _, delim := range []string{"\t", "\\t", "tab", "TAB"} {
+		r, err := ParseDelim(delim)
+
+		assert.NoError(t, err)
+		assert.Equal(t, '\t', r)
+	}
+
+	r, err := ParseDelim(";")
+	assert.NoError(t, err)
+	assert.Equal(t, ';', r)
+
+	_, err = ParseDelim(";;")
+	assert.EqualError(t, err, `invalid delimiter ";;": must be a single character`)
+
+	q, err := ParseQuote("none")
+	assert.NoError(t, err)
+	assert.Equal(t, rune(0), q)
+}
+
+func TestSniff(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		in      Dialect
+		want    Dialect
+	}{
+		{"comma", "Name,Phone\nacme,555\n", Dialect{}, Dialect{Delim: ',', Quote: '"'}},
+		{"semicolon with commas in values", "Name;Amount\nacme;1,5\nglobex;2,25\n", Dialect{}, Dialect{Delim: ';', Quote: '"'}},
+		{"tab", "Name\tPhone\tCity\nacme\t555\tParis, TX\n", Dialect{}, Dialect{Delim: '\t', Quote: '"'}},
+		{"pipe with single quotes", "'Name'|'Notes'\n'acme'|'a|b'\n", Dialect{}, Dialect{Delim: '|', Quote: '\''}},
+		{"quoted delimiters", "Name,Notes\n\"a;b;c\",x\n\"d;e;f\",y\n", Dialect{}, Dialect{Delim: ',', Quote: '"'}},
+		{
+			"skipped rows",
+			"Report; generated; today\n\nName,Phone\nacme,555\n",
+			Dialect{SkipRows: 2},
+			Dialect{Delim: ',', Quote: '"', SkipRows: 2},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.want, Sniff([]byte(test.content), test.in))
+		})
+	}
+}
+
+func TestSniffDelim(t *testing.T) {
+	d := SniffDelim([]byte("Name;Notes\n'acme';\"5\" pipe\"\n"), Dialect{Quote: '\''})
+
+	assert.Equal(t, Dialect{Delim: ';', Quote: '\''}, d)
+}
+
+func TestDialect_Compatible(t *testing.T) {
+	assert.True(t, Default.Compatible())
+	assert.True(t, Dialect{Delim: '\t', Quote: '"'}.Compatible())
+	assert.False(t, Dialect{Delim: ':', Quote: '"'}.Compatible())
+	assert.False(t, Dialect{Delim: ',', Quote: '\''}.Compatible())
+	assert.False(t, Dialect{Delim: ',', Quote: '"', Comment: '#'}.Compatible())
+	assert.False(t, Dialect{Delim: ',', Quote: '"', SkipRows: 1}.Compatible())
+}
+
+func TestDialect_Convert(t *testing.T) {
+	tests := []struct {
+		name    string
+		dialect Dialect
+		content string
+		want    string
+	}{
+		{
+			"single quotes",
+			Dialect{Delim: ';', Quote: '\''},
+			"Name;Notes\n'O''Brien';'say \"hi\"; bye'\n",
+			"Name,Notes\nO'Brien,\"say \"\"hi\"\"; bye\"\n",
+		},
+		{
+			"comments and skipped rows",
+			Dialect{Delim: '|', Quote: '"', Comment: '#', SkipRows: 2},
+			"Exported 2018-07-01\nby admin\nName|City\n# a comment\nacme|Paris, TX\n\n",
+			"Name,City\nacme,\"Paris, TX\"\n",
+		},
+		{
+			"no quoting",
+			Dialect{Delim: ':'},
+			"Name:Notes\n\"acme\":x\n",
+			"Name,Notes\n\"\"\"acme\"\"\",x\n",
+		},
+		{
+			"line breaks in quotes",
+			Dialect{Delim: ',', Quote: '\''},
+			"Name,Street\nacme,'1 Main\nSuite 2'",
+			"Name,Street\nacme,\"1 Main\nSuite 2\"\n",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			actual, err := test.dialect.Convert([]byte(test.content))
+
+			assert.NoError(t, err)
+			assert.Equal(t, test.want, string(actual))
+		})
+	}
+}
+
+func TestDialect_ConvertErrors(t *testing.T) {
+	_, err := Default.Convert([]byte("Name\n\"acme\n"))
+	assert.EqualError(t, err, "line 2: quoted field not closed")
+
+	_, err = Default.Convert([]byte("Name,City\n\"acme\"x,y\n"))
+	assert.EqualError(t, err, `line 2: unexpected 'x' after closing quote`)
+}
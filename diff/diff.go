@@ -0,0 +1,244 @@
+// Package diff compares the records of a file with those already in an org, so that only the records that changed need
+// to be loaded.
+package diff
+
+import (
+	"fmt"
+	"github.com/pkg/errors"
+	"github.com/rfaulhaber/forcedata/auth"
+	"github.com/rfaulhaber/forcedata/query"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Blank is written to a field to clear it in an update. The Bulk API ignores empty values.
+const Blank = "#N/A"
+
+// dateLayouts are the forms dates and datetimes are compared in, so that the same moment written differently isn't a
+// change.
+var dateLayouts = []string{
+	"2006-01-02",
+	"2006-01-02T15:04:05Z07:00",
+	"2006-01-02T15:04:05.000Z07:00",
+	"2006-01-02T15:04:05.000-0700",
+}
+
+// Diff is what has to be loaded to bring an org in line with a file.
+type Diff struct {
+	// Header is the header of the file.
+	Header []string
+
+	// Inserts are the rows of the file whose key isn't in the org.
+	Inserts [][]string
+
+	// Updates are the rows of the file whose key is in the org with different values.
+	Updates []Update
+
+	// Deletes are the IDs of the records in the org whose key isn't in the file.
+	Deletes []string
+
+	// Unchanged counts the rows of the file that match the org.
+	Unchanged int
+}
+
+// Update is a row of the file that changes a record.
+type Update struct {
+	ID      string
+	Row     []string
+	Changes []Change
+}
+
+// Change is a single field of a record that's different in the file.
+type Change struct {
+	Field string
+	Old   string
+	New   string
+}
+
+// Org returns the records of object that have a key, with the given fields.
+func Org(session auth.Session, object, key string, fields []string) ([]query.Record, error) {
+	selected := []string{"Id"}
+
+	for _, f := range fields {
+		if !strings.EqualFold(f, "Id") {
+			selected = append(selected, f)
+		}
+	}
+
+	soql := fmt.Sprintf("SELECT %s FROM %s WHERE %s != null", strings.Join(selected, ", "), object, key)
+
+	records, err := query.All(session, soql)
+
+	if err != nil {
+		return nil, errors.Wrap(err, "could not query "+object)
+	}
+
+	return records, nil
+}
+
+// Compare finds the rows of a file that are new or changed compared to records from the org, matching them on the key
+// column. Keys are matched ignoring case, as external IDs are by default. Values are compared as numbers, booleans or
+// dates where both sides can be read as one, so that 1.0 and 1 aren't a change.
+//
+// kept are the keys of rows of the file that were rejected before comparing. The records they match are still in the
+// file, so they're never deleted.
+func Compare(header []string, rows [][]string, key string, records []query.Record, kept []string) (*Diff, error) {
+	k := indexOf(header, key)
+
+	if k < 0 {
+		return nil, errors.Errorf("key column %s not found", key)
+	}
+
+	d := &Diff{Header: header}
+	org := map[string]query.Record{}
+
+	for _, r := range records {
+		org[strings.ToLower(r.String(header[k]))] = r
+	}
+
+	seen := map[string]bool{}
+	keep := map[string]bool{}
+
+	for _, value := range kept {
+		keep[strings.ToLower(strings.TrimSpace(value))] = true
+	}
+
+	for i, row := range rows {
+		if k >= len(row) || strings.TrimSpace(row[k]) == "" {
+			return nil, errors.Errorf("row %d has no %s", i+2, header[k])
+		}
+
+		value := strings.ToLower(strings.TrimSpace(row[k]))
+
+		if seen[value] {
+			return nil, errors.Errorf("row %d: %s %q appears more than once", i+2, header[k], row[k])
+		}
+
+		seen[value] = true
+
+		record, ok := org[value]
+
+		if !ok {
+			d.Inserts = append(d.Inserts, row)
+			continue
+		}
+
+		var changes []Change
+
+		for j, field := range header {
+			if j == k || strings.EqualFold(field, "Id") {
+				continue
+			}
+
+			var v string
+
+			if j < len(row) {
+				v = row[j]
+			}
+
+			if old := record.String(field); !Equal(v, old) {
+				changes = append(changes, Change{Field: field, Old: old, New: v})
+			}
+		}
+
+		if len(changes) == 0 {
+			d.Unchanged++
+		} else {
+			d.Updates = append(d.Updates, Update{ID: record.String("Id"), Row: row, Changes: changes})
+		}
+	}
+
+	for _, r := range records {
+		if value := strings.ToLower(r.String(header[k])); !seen[value] && !keep[value] {
+			d.Deletes = append(d.Deletes, r.String("Id"))
+		}
+	}
+
+	return d, nil
+}
+
+// UpdateRows returns the header and rows of an update job making the changes: each changed row with the ID of its
+// record, and Blank in fields it clears.
+func (d *Diff) UpdateRows() ([]string, [][]string) {
+	header := d.Header
+	id := indexOf(header, "Id")
+	offset := 0
+
+	if id < 0 {
+		header = append([]string{"Id"}, d.Header...)
+		offset = 1
+	}
+
+	rows := make([][]string, len(d.Updates))
+
+	for i, u := range d.Updates {
+		row := make([]string, len(header))
+		copy(row[offset:], u.Row)
+
+		for _, c := range u.Changes {
+			if strings.TrimSpace(c.New) == "" {
+				row[offset+indexOf(d.Header, c.Field)] = Blank
+			}
+		}
+
+		// the new Id column, or the file's own
+		row[id+offset] = u.ID
+		rows[i] = row
+	}
+
+	return header, rows
+}
+
+// Equal reports whether a value from a file is the same as a value from the org.
+func Equal(value, org string) bool {
+	value, org = strings.TrimSpace(value), strings.TrimSpace(org)
+
+	if value == org {
+		return true
+	}
+
+	if value == "" || org == "" {
+		return false
+	}
+
+	if a, err := strconv.ParseBool(value); err == nil {
+		if b, err := strconv.ParseBool(org); err == nil {
+			return a == b
+		}
+	}
+
+	if a, err := strconv.ParseFloat(value, 64); err == nil {
+		if b, err := strconv.ParseFloat(org, 64); err == nil {
+			return a == b
+		}
+	}
+
+	if a, ok := parseTime(value); ok {
+		if b, ok := parseTime(org); ok {
+			return a.Equal(b)
+		}
+	}
+
+	return false
+}
+
+func parseTime(value string) (time.Time, bool) {
+	for _, layout := range dateLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, true
+		}
+	}
+
+	return time.Time{}, false
+}
+
+func indexOf(header []string, name string) int {
+	for i, h := range header {
+		if strings.EqualFold(h, name) {
+			return i
+		}
+	}
+
+	return -1
+}
@@ -0,0 +1,114 @@
+package diff
+
+import (
+	"github.com/rfaulhaber/forcedata/jobtest"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestCompare(t *testing.T) {
+	sim := jobtest.New()
+	server := sim.Start()
+	defer server.Close()
+
+	ids := sim.Insert("Account",
+		jobtest.Record{"Legacy_Id__c": "A1", "Name": "Acme", "NumberOfEmployees": "12", "Active__c": "true"},
+		jobtest.Record{"Legacy_Id__c": "a2", "Name": "Globex", "NumberOfEmployees": "40", "Active__c": "true"},
+		jobtest.Record{"Legacy_Id__c": "A3", "Name": "Initech", "NumberOfEmployees": "", "Active__c": "false"},
+		jobtest.Record{"Name": "Unkeyed"},
+	)
+
+	header := []string{"Legacy_Id__c", "Name", "NumberOfEmployees", "Active__c"}
+
+	records, err := Org(sim.Session(server.URL), "Account", "Legacy_Id__c", header)
+
+	assert.NoError(t, err)
+	assert.Len(t, records, 3)
+
+	rows := [][]string{
+		{"A1", "Acme", "12.0", "TRUE"},
+		{"A2", "Globex Corp", "", "true"},
+		{"A4", "Hooli", "5", "true"},
+	}
+
+	d, err := Compare(header, rows, "legacy_id__c", records, nil)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, d.Unchanged)
+	assert.Equal(t, [][]string{{"A4", "Hooli", "5", "true"}}, d.Inserts)
+	assert.Equal(t, []string{ids[2]}, d.Deletes)
+
+	assert.Equal(t, []Update{{
+		ID:  ids[1],
+		Row: rows[1],
+		Changes: []Change{
+			{Field: "Name", Old: "Globex", New: "Globex Corp"},
+			{Field: "NumberOfEmployees", Old: "40", New: ""},
+		},
+	}}, d.Updates)
+
+	updateHeader, updateRows := d.UpdateRows()
+
+	assert.Equal(t, []string{"Id", "Legacy_Id__c", "Name", "NumberOfEmployees", "Active__c"}, updateHeader)
+	assert.Equal(t, [][]string{{ids[1], "A2", "Globex Corp", Blank, "true"}}, updateRows)
+}
+
+func TestCompare_Kept(t *testing.T) {
+	sim := jobtest.New()
+	server := sim.Start()
+	defer server.Close()
+
+	ids := sim.Insert("Account",
+		jobtest.Record{"Legacy_Id__c": "A1", "Name": "Acme"},
+		jobtest.Record{"Legacy_Id__c": "A2", "Name": "Globex"},
+		jobtest.Record{"Legacy_Id__c": "A3", "Name": "Initech"},
+	)
+
+	header := []string{"Legacy_Id__c", "Name"}
+
+	records, err := Org(sim.Session(server.URL), "Account", "Legacy_Id__c", header)
+	assert.NoError(t, err)
+
+	// A2 was rejected on its way to being compared, so it's still in the file and mustn't be deleted
+	d, err := Compare(header, [][]string{{"A1", "Acme"}}, "Legacy_Id__c", records, []string{" a2 "})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, d.Unchanged)
+	assert.Equal(t, []string{ids[2]}, d.Deletes)
+}
+
+func TestCompare_Errors(t *testing.T) {
+	header := []string{"Key", "Name"}
+
+	_, err := Compare(header, nil, "Legacy_Id__c", nil, nil)
+	assert.EqualError(t, err, "key column Legacy_Id__c not found")
+
+	_, err = Compare(header, [][]string{{"1", "a"}, {"", "b"}}, "Key", nil, nil)
+	assert.EqualError(t, err, "row 3 has no Key")
+
+	_, err = Compare(header, [][]string{{"k1", "a"}, {"K1", "b"}}, "Key", nil, nil)
+	assert.EqualError(t, err, `row 3: Key "K1" appears more than once`)
+}
+
+func TestEqual(t *testing.T) {
+	testCases := []struct {
+		value, org string
+		equal      bool
+	}{
+		{"Acme", "Acme", true},
+		{" Acme ", "Acme", true},
+		{"acme", "Acme", false},
+		{"", "", true},
+		{"", "Acme", false},
+		{"1", "1.0", true},
+		{"1,000", "1000", false},
+		{"TRUE", "true", true},
+		{"0", "false", true},
+		{"2018-07-04T13:05:00Z", "2018-07-04T13:05:00.000+0000", true},
+		{"2018-07-04", "2018-07-05", false},
+	}
+
+	for _, tc := range testCases {
+		assert.Equal(t, tc.equal, Equal(tc.value, tc.org), "%q, %q", tc.value, tc.org)
+	}
+}
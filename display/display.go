@@ -0,0 +1,266 @@
+// Package display shows how the jobs of a watched load are going: how much of each job's content has been uploaded,
+// its state, how many records it has processed and failed, how fast, and about how long it has left. On a terminal each
+// job has a line that's redrawn in place. Elsewhere, such as when output is redirected to a file, a job's line is
+// written when its state changes and every so often while it runs, so that logs get plain lines rather than redraws.
+package display
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// DefaultEvery is how often the line of a running job is written again when not on a terminal.
+const DefaultEvery = 30 * time.Second
+
+// redraw is the least time between redraws on a terminal, so that an upload doesn't redraw for every write.
+const redraw = 100 * time.Millisecond
+
+// Status is the state of a chunk's job.
+type Status struct {
+	ID    string
+	State string
+
+	// Records is how many records the job was given.
+	Records   int
+	Processed uint
+	Failed    uint
+}
+
+// Display shows the progress of the jobs of a load, a line for each.
+type Display struct {
+	// Every is how often the lines of running jobs are written again when not on a terminal. Lines are always written
+	// when a job's state changes.
+	Every time.Duration
+
+	w       io.Writer
+	tty     bool
+	now     func() time.Time
+	lines   []*line
+	drawn   int
+	drawnAt time.Time
+}
+
+// line is what's shown of a chunk's job.
+type line struct {
+	Status
+
+	n         int
+	sent      int
+	size      int
+	uploading bool
+
+	// started is when the job was first seen uploaded or running, and base how many records it had processed then, so
+	// that jobs reattached to part way through aren't counted as having processed their records instantly.
+	started  time.Time
+	base     uint
+	finished time.Time
+
+	written   time.Time
+	writtenAs string
+}
+
+// New returns a display writing to w, which redraws its lines in place if tty is set.
+func New(w io.Writer, tty bool) *Display {
+	return &Display{Every: DefaultEvery, w: w, tty: tty, now: time.Now}
+}
+
+// IsTerminal reports whether f is a terminal rather than a file or pipe.
+func IsTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// Upload shows that sent of the total bytes of chunk n's content have been uploaded.
+func (d *Display) Upload(n int, sent, total int) {
+	l := d.line(n)
+	changed := !l.uploading || sent == total
+
+	l.uploading = true
+	l.sent, l.size = sent, total
+
+	d.show(l, changed)
+}
+
+// Update shows the status of chunk n's job.
+func (d *Display) Update(n int, s Status) {
+	l := d.line(n)
+	now := d.now()
+
+	if l.started.IsZero() && s.State != "Open" {
+		l.started, l.base = now, s.Processed
+	}
+
+	if l.finished.IsZero() && finished(s.State) {
+		l.finished = now
+	}
+
+	changed := s.State != l.State || s.Processed != l.Processed || s.Failed != l.Failed
+
+	l.Status = s
+	l.uploading = l.uploading && s.State == "Open"
+
+	d.show(l, changed)
+}
+
+// Log writes a message. On a terminal it's written above the lines of the jobs.
+func (d *Display) Log(format string, args ...interface{}) {
+	if !d.tty {
+		fmt.Fprintf(d.w, format+"\n", args...)
+		return
+	}
+
+	var buf bytes.Buffer
+
+	d.clear(&buf)
+	fmt.Fprintf(&buf, format+"\n", args...)
+	d.w.Write(buf.Bytes())
+
+	d.drawn = 0
+	d.draw()
+}
+
+// line returns chunk n's line, adding it in order if it's new.
+func (d *Display) line(n int) *line {
+	i := 0
+
+	for ; i < len(d.lines) && d.lines[i].n <= n; i++ {
+		if d.lines[i].n == n {
+			return d.lines[i]
+		}
+	}
+
+	l := &line{n: n}
+
+	d.lines = append(d.lines, nil)
+	copy(d.lines[i+1:], d.lines[i:])
+	d.lines[i] = l
+
+	return l
+}
+
+// show redraws the lines on a terminal, or writes the changed line elsewhere. Unchanged lines are only shown again
+// once enough time has passed.
+func (d *Display) show(l *line, changed bool) {
+	now := d.now()
+
+	if d.tty {
+		if changed || now.Sub(d.drawnAt) >= redraw {
+			d.draw()
+		}
+
+		return
+	}
+
+	if state := l.state(); state != l.writtenAs || now.Sub(l.written) >= d.Every {
+		fmt.Fprintln(d.w, l.text(now))
+		l.written, l.writtenAs = now, state
+	}
+}
+
+// draw redraws every line over the ones drawn before.
+func (d *Display) draw() {
+	var buf bytes.Buffer
+
+	d.clear(&buf)
+
+	now := d.now()
+
+	for _, l := range d.lines {
+		buf.WriteString(l.text(now))
+		buf.WriteByte('\n')
+	}
+
+	d.w.Write(buf.Bytes())
+	d.drawn, d.drawnAt = len(d.lines), now
+}
+
+// clear moves the cursor back to the first line drawn and clears the lines from it on.
+func (d *Display) clear(buf *bytes.Buffer) {
+	if d.drawn > 0 {
+		fmt.Fprintf(buf, "\x1b[%dA", d.drawn)
+	}
+
+	buf.WriteString("\r\x1b[J")
+}
+
+func (l *line) state() string {
+	if l.uploading {
+		return "Uploading"
+	}
+
+	return l.State
+}
+
+func (l *line) text(now time.Time) string {
+	s := fmt.Sprintf("chunk %d: job %s %s", l.n, l.ID, l.state())
+
+	if l.uploading {
+		return s + fmt.Sprintf(", %s of %s sent", size(l.sent), size(l.size))
+	}
+
+	s += fmt.Sprintf(", %d of %d processed, %d failed", l.Processed, l.Records, l.Failed)
+
+	if !l.finished.IsZero() {
+		if l.finished.After(l.started) {
+			s += ", took " + duration(l.finished.Sub(l.started))
+		}
+
+		return s
+	}
+
+	if rate := l.rate(now); rate > 0 {
+		s += fmt.Sprintf(", %.1f records/s", rate)
+
+		if left := l.Records - int(l.Processed); left > 0 {
+			s += ", about " + duration(time.Duration(float64(left)/rate*float64(time.Second))) + " left"
+		}
+	}
+
+	return s
+}
+
+// rate returns how many records a second the job has processed since it started, or zero if that isn't known yet.
+func (l *line) rate(now time.Time) float64 {
+	elapsed := now.Sub(l.started).Seconds()
+
+	if l.started.IsZero() || elapsed <= 0 || l.Processed <= l.base {
+		return 0
+	}
+
+	return float64(l.Processed-l.base) / elapsed
+}
+
+func finished(state string) bool {
+	return state == "JobComplete" || state == "Failed" || state == "Aborted"
+}
+
+// size returns a number of bytes as people read them, such as 1.5 MB.
+func size(n int) string {
+	if n < 1024 {
+		return fmt.Sprintf("%d B", n)
+	}
+
+	f := float64(n) / 1024
+
+	for _, unit := range []string{"KB", "MB"} {
+		if f < 1024 {
+			return fmt.Sprintf("%.1f %s", f, unit)
+		}
+
+		f /= 1024
+	}
+
+	return fmt.Sprintf("%.1f GB", f)
+}
+
+// duration returns a duration rounded to the second, or to the hundredth of a second if it's shorter.
+func duration(d time.Duration) string {
+	if d < time.Second {
+		return d.Round(10 * time.Millisecond).String()
+	}
+
+	return d.Round(time.Second).String()
+}
@@ -0,0 +1,88 @@
+package display
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testDisplay returns a display writing to buf whose time only moves when advanced.
+func testDisplay(buf *bytes.Buffer, tty bool) (*Display, func(time.Duration)) {
+	now := time.Date(2019, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	d := New(buf, tty)
+	d.now = func() time.Time { return now }
+
+	return d, func(d time.Duration) { now = now.Add(d) }
+}
+
+func TestDisplay_Plain(t *testing.T) {
+	var buf bytes.Buffer
+
+	d, advance := testDisplay(&buf, false)
+	d.Every = 5 * time.Second
+
+	d.Update(1, Status{ID: "750A", State: "Open", Records: 1000})
+	d.Upload(1, 1024, 3*1024*1024)
+	d.Upload(1, 2*1024*1024, 3*1024*1024)
+	d.Upload(1, 3*1024*1024, 3*1024*1024)
+	d.Update(1, Status{ID: "750A", State: "UploadComplete", Records: 1000})
+
+	advance(4 * time.Second)
+	d.Update(1, Status{ID: "750A", State: "InProgress", Records: 1000, Processed: 200, Failed: 1})
+
+	advance(4 * time.Second)
+	d.Update(1, Status{ID: "750A", State: "InProgress", Records: 1000, Processed: 400, Failed: 1})
+
+	advance(4 * time.Second)
+	d.Update(1, Status{ID: "750A", State: "InProgress", Records: 1000, Processed: 600, Failed: 2})
+	d.Log("chunk %d: aborting job %s", 1, "750A")
+	d.Update(1, Status{ID: "750A", State: "Aborted", Records: 1000, Processed: 600, Failed: 2})
+
+	assert.Equal(t, []string{
+		"chunk 1: job 750A Open, 0 of 1000 processed, 0 failed",
+		"chunk 1: job 750A Uploading, 1.0 KB of 3.0 MB sent",
+		"chunk 1: job 750A UploadComplete, 0 of 1000 processed, 0 failed",
+		"chunk 1: job 750A InProgress, 200 of 1000 processed, 1 failed, 50.0 records/s, about 16s left",
+		"chunk 1: job 750A InProgress, 600 of 1000 processed, 2 failed, 50.0 records/s, about 8s left",
+		"chunk 1: aborting job 750A",
+		"chunk 1: job 750A Aborted, 600 of 1000 processed, 2 failed, took 12s",
+	}, strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n"))
+}
+
+func TestDisplay_Terminal(t *testing.T) {
+	var buf bytes.Buffer
+
+	d, advance := testDisplay(&buf, true)
+
+	d.Update(2, Status{ID: "750B", State: "Open", Records: 5})
+	d.Update(1, Status{ID: "750A", State: "JobComplete", Records: 5, Processed: 5})
+
+	assert.Equal(t, "\r\x1b[Jchunk 2: job 750B Open, 0 of 5 processed, 0 failed\n"+
+		"\x1b[1A\r\x1b[Jchunk 1: job 750A JobComplete, 5 of 5 processed, 0 failed\n"+
+		"chunk 2: job 750B Open, 0 of 5 processed, 0 failed\n", buf.String())
+
+	buf.Reset()
+
+	d.Upload(2, 10, 100)
+	d.Upload(2, 50, 100)
+	advance(time.Second)
+	d.Upload(2, 90, 100)
+
+	assert.Equal(t, 2, strings.Count(buf.String(), "\x1b[2A"))
+	assert.True(t, strings.HasSuffix(buf.String(), "chunk 2: job 750B Uploading, 90 B of 100 B sent\n"))
+
+	buf.Reset()
+
+	d.Log("chunk %d: reattached to job %s", 1, "750A")
+
+	assert.True(t, strings.HasPrefix(buf.String(), "\x1b[2A\r\x1b[Jchunk 1: reattached to job 750A\n\r\x1b[Jchunk 1: job 750A"))
+}
+
+func TestSize(t *testing.T) {
+	assert.Equal(t, "512 B", size(512))
+	assert.Equal(t, "1.5 KB", size(1536))
+	assert.Equal(t, "2.0 GB", size(2*1024*1024*1024))
+}
@@ -0,0 +1,334 @@
+// Package export copies a slice of an org's records, following relationships from a set of root records, into a
+// directory of CSV files that can be loaded into another org with their relationships intact.
+package export
+
+import (
+	"encoding/csv"
+	"fmt"
+	"github.com/pkg/errors"
+	"github.com/rfaulhaber/forcedata/auth"
+	"github.com/rfaulhaber/forcedata/describe"
+	"github.com/rfaulhaber/forcedata/plan"
+	"github.com/rfaulhaber/forcedata/query"
+	"gopkg.in/yaml.v2"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ManifestFile is the name of the manifest written to an export directory. It's a plan that loads the export.
+const ManifestFile = "manifest.yaml"
+
+// BatchSize is the number of IDs filtered on in a single query.
+const BatchSize = 200
+
+// KeyColumn holds the ID each record had in the source org. It's the key of every step of the manifest, and isn't
+// loaded.
+const KeyColumn = "Id"
+
+var fromPattern = regexp.MustCompile(`(?i)\bFROM\s+(\w+)`)
+
+// skippedTypes are field types that can't be exported as CSV values.
+var skippedTypes = map[string]bool{
+	"address":  true,
+	"location": true,
+	"base64":   true,
+}
+
+// keptReferences are objects that references are kept to even though they aren't exported, because their IDs are the
+// same in every sandbox of an org.
+var keptReferences = map[string]bool{
+	"RecordType": true,
+}
+
+// Object is what was exported for one object.
+type Object struct {
+	Name    string
+	File    string
+	Records int
+
+	// Fields are the columns of the object's file, after the Id column.
+	Fields []string
+
+	// References maps reference fields to the exported object they point to.
+	References map[string]string
+}
+
+// Export exports the records selected by root, a SOQL query, and the records of the included objects related to them
+// into dir, returning what was exported in order. Included objects are fetched in the order given, each by its
+// references to objects exported before it. References to objects that aren't exported are left out, apart from
+// record types, as are references to the same object or to objects exported later, so that the export can be loaded
+// in order.
+func Export(session auth.Session, root string, include []string, dir string) ([]Object, error) {
+	m := fromPattern.FindStringSubmatch(root)
+
+	if m == nil {
+		return nil, errors.New("root query has no FROM clause")
+	}
+
+	records, err := query.All(session, root)
+
+	if err != nil {
+		return nil, errors.Wrap(err, "root query")
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+
+	// ids holds the IDs of the records exported for each object
+	ids := map[string][]string{}
+
+	var objects []Object
+
+	for i, name := range append([]string{m[1]}, include...) {
+		sobject, err := describe.Get(session, name)
+
+		if err != nil {
+			return nil, errors.Wrap(err, "could not describe "+name)
+		}
+
+		obj := Object{Name: sobject.Name, File: sobject.Name + ".csv", References: map[string]string{}}
+
+		if _, ok := ids[obj.Name]; ok {
+			return nil, errors.Errorf("%s is exported more than once", obj.Name)
+		}
+
+		for _, f := range sobject.Fields {
+			if f.Type == "id" || !f.Createable || skippedTypes[f.Type] {
+				continue
+			}
+
+			if f.Type == "reference" {
+				if len(f.ReferenceTo) != 1 {
+					continue
+				}
+
+				target := f.ReferenceTo[0]
+
+				if _, ok := ids[target]; ok {
+					obj.References[f.Name] = target
+				} else if !keptReferences[target] {
+					continue
+				}
+			}
+
+			obj.Fields = append(obj.Fields, f.Name)
+		}
+
+		if i == 0 {
+			records, err = fetch(session, obj, "Id", recordIDs(records))
+		} else if len(obj.References) == 0 {
+			return nil, errors.Errorf("%s has no references to %s", obj.Name, strings.Join(exported(objects), ", "))
+		} else {
+			records, err = fetchRelated(session, obj, ids)
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		unlinkOutside(obj, records, ids)
+
+		if err := write(filepath.Join(dir, obj.File), obj, records); err != nil {
+			return nil, err
+		}
+
+		obj.Records = len(records)
+		ids[obj.Name] = recordIDs(records)
+		objects = append(objects, obj)
+	}
+
+	return objects, nil
+}
+
+func exported(objects []Object) []string {
+	names := make([]string, len(objects))
+
+	for i, o := range objects {
+		names[i] = o.Name
+	}
+
+	return names
+}
+
+func recordIDs(records []query.Record) []string {
+	ids := make([]string, len(records))
+
+	for i, r := range records {
+		ids[i] = r.String("Id")
+	}
+
+	return ids
+}
+
+// unlinkOutside blanks references to records that weren't exported, which a record can have when it was exported
+// for a different reference.
+func unlinkOutside(obj Object, records []query.Record, ids map[string][]string) {
+	for field, target := range obj.References {
+		exported := map[string]bool{}
+
+		for _, id := range ids[target] {
+			exported[id] = true
+		}
+
+		for _, r := range records {
+			if v := r.String(field); v != "" && !exported[v] {
+				r[field] = nil
+			}
+		}
+	}
+}
+
+// fetchRelated returns the records of obj that refer to any of the records already exported, each once.
+func fetchRelated(session auth.Session, obj Object, ids map[string][]string) ([]query.Record, error) {
+	var (
+		records []query.Record
+		fields  []string
+	)
+
+	seen := map[string]bool{}
+
+	for field := range obj.References {
+		fields = append(fields, field)
+	}
+
+	sort.Strings(fields)
+
+	for _, field := range fields {
+		related, err := fetch(session, obj, field, ids[obj.References[field]])
+
+		if err != nil {
+			return nil, err
+		}
+
+		for _, r := range related {
+			if id := r.String("Id"); !seen[id] {
+				seen[id] = true
+				records = append(records, r)
+			}
+		}
+	}
+
+	return records, nil
+}
+
+// fetch returns the records of obj whose field is one of values, querying them in batches.
+func fetch(session auth.Session, obj Object, field string, values []string) ([]query.Record, error) {
+	var records []query.Record
+
+	fields := strings.Join(append([]string{"Id"}, obj.Fields...), ", ")
+
+	for i := 0; i < len(values); i += BatchSize {
+		end := i + BatchSize
+
+		if end > len(values) {
+			end = len(values)
+		}
+
+		soql := fmt.Sprintf("SELECT %s FROM %s WHERE %s IN %s", fields, obj.Name, field, query.In(values[i:end]))
+
+		batch, err := query.All(session, soql)
+
+		if err != nil {
+			return nil, errors.Wrap(err, "could not export "+obj.Name)
+		}
+
+		records = append(records, batch...)
+	}
+
+	return records, nil
+}
+
+func write(path string, obj Object, records []query.Record) error {
+	f, err := os.Create(path)
+
+	if err != nil {
+		return err
+	}
+
+	defer f.Close()
+
+	w := csv.NewWriter(f)
+	header := append([]string{KeyColumn}, obj.Fields...)
+
+	if err := w.Write(header); err != nil {
+		return err
+	}
+
+	for _, r := range records {
+		row := make([]string, len(header))
+
+		for i, field := range header {
+			row[i] = r.String(field)
+		}
+
+		if err := w.Write(row); err != nil {
+			return err
+		}
+	}
+
+	w.Flush()
+
+	if err := w.Error(); err != nil {
+		return errors.Wrap(err, "could not write "+path)
+	}
+
+	return nil
+}
+
+// Manifest returns a plan that inserts exported objects in order, replacing references between them with the IDs of
+// the new records.
+func Manifest(objects []Object) *plan.Plan {
+	p := &plan.Plan{}
+
+	for _, obj := range objects {
+		step := &plan.Step{
+			Name:    obj.Name,
+			File:    obj.File,
+			Key:     KeyColumn,
+			Exclude: []string{KeyColumn},
+		}
+
+		step.Object = obj.Name
+		step.Operation = "insert"
+
+		if len(obj.References) > 0 {
+			step.References = obj.References
+		}
+
+		p.Steps = append(p.Steps, step)
+	}
+
+	return p
+}
+
+// WriteManifest writes the manifest of an export to dir, with a comment saying where it came from.
+func WriteManifest(dir string, session auth.Session, root string, objects []Object) error {
+	b, err := yaml.Marshal(Manifest(objects))
+
+	if err != nil {
+		return err
+	}
+
+	var header strings.Builder
+
+	fmt.Fprintf(&header, "# Exported from %s on %s\n", session.InstanceURL, time.Now().UTC().Format(time.RFC3339))
+	fmt.Fprintf(&header, "# Root: %s\n", root)
+
+	for _, obj := range objects {
+		fmt.Fprintf(&header, "# %s: %d records\n", obj.Name, obj.Records)
+	}
+
+	path := filepath.Join(dir, ManifestFile)
+
+	if err := ioutil.WriteFile(path, append([]byte(header.String()), b...), 0644); err != nil {
+		return errors.Wrap(err, "could not write manifest")
+	}
+
+	return nil
+}
@@ -0,0 +1,97 @@
+package export
+
+import (
+	"github.com/rfaulhaber/forcedata/jobtest"
+	"github.com/rfaulhaber/forcedata/plan"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestExportImport(t *testing.T) {
+	source := jobtest.New()
+	server := source.Start()
+	defer server.Close()
+
+	accounts := source.Insert("Account",
+		jobtest.Record{"Name": "Acme", "Industry": "Banking", "OwnerId": "005MOCK00000000AAA"},
+		jobtest.Record{"Name": "Globex", "Industry": "Technology"},
+	)
+	contacts := source.Insert("Contact",
+		jobtest.Record{"LastName": "Smith", "AccountId": accounts[0]},
+		jobtest.Record{"LastName": "Jones", "AccountId": accounts[1]},
+	)
+	source.Insert("Case",
+		jobtest.Record{"Subject": "Broken", "AccountId": accounts[0], "ContactId": contacts[0]},
+		jobtest.Record{"Subject": "Outside", "AccountId": accounts[1], "ContactId": contacts[1]},
+		jobtest.Record{"Subject": "Elsewhere", "AccountId": accounts[1], "ContactId": contacts[0]},
+	)
+
+	dir, err := ioutil.TempDir("", "export")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	session := source.Session(server.URL)
+	root := "SELECT Id FROM Account WHERE Industry = 'Banking'"
+
+	objects, err := Export(session, root, []string{"Contact", "Case"}, dir)
+
+	assert.NoError(t, err)
+	assert.Len(t, objects, 3)
+	assert.Equal(t, map[string]string{}, objects[0].References)
+	assert.Equal(t, map[string]string{"AccountId": "Account"}, objects[1].References)
+	assert.Equal(t, map[string]string{"AccountId": "Account", "ContactId": "Contact"}, objects[2].References)
+	assert.Equal(t, []int{1, 1, 2}, []int{objects[0].Records, objects[1].Records, objects[2].Records})
+
+	content, err := ioutil.ReadFile(filepath.Join(dir, "Case.csv"))
+	assert.NoError(t, err)
+	assert.Equal(t, "Id,Subject,Status,AccountId,ContactId,External_Id__c\n"+
+		source.Records("Case")[0]["Id"]+",Broken,,"+accounts[0]+","+contacts[0]+",\n"+
+		source.Records("Case")[2]["Id"]+",Elsewhere,,,"+contacts[0]+",\n", string(content))
+
+	assert.NoError(t, WriteManifest(dir, session, root, objects))
+
+	p, err := plan.Load(filepath.Join(dir, ManifestFile))
+	assert.NoError(t, err)
+
+	target := jobtest.New()
+	targetServer := target.Start()
+	defer targetServer.Close()
+
+	r := plan.Runner{Session: target.Session(targetServer.URL), PollInterval: time.Millisecond, ResultsDir: dir}
+
+	for _, res := range r.Run(p) {
+		assert.Equal(t, plan.StateDone, res.State, res.Step.Name)
+	}
+
+	newAccount := target.Records("Account")[0]
+	newContact := target.Records("Contact")[0]
+	newCases := target.Records("Case")
+
+	assert.Equal(t, "Acme", newAccount["Name"])
+	assert.Equal(t, "", newAccount["OwnerId"])
+	assert.Equal(t, newAccount["Id"], newContact["AccountId"])
+	assert.Len(t, newCases, 2)
+	assert.Equal(t, newAccount["Id"], newCases[0]["AccountId"])
+	assert.Equal(t, newContact["Id"], newCases[1]["ContactId"])
+	assert.Equal(t, "", newCases[1]["AccountId"])
+}
+
+func TestExport_Errors(t *testing.T) {
+	sim := jobtest.New()
+	server := sim.Start()
+	defer server.Close()
+
+	dir, err := ioutil.TempDir("", "export")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	_, err = Export(sim.Session(server.URL), "SELECT Id", nil, dir)
+	assert.EqualError(t, err, "root query has no FROM clause")
+
+	_, err = Export(sim.Session(server.URL), "SELECT Id FROM Contact", []string{"Account"}, dir)
+	assert.EqualError(t, err, "Account has no references to Contact")
+}
@@ -0,0 +1,414 @@
+// Package flatten turns JSON records into the CSV the Bulk API loads. Nested objects become dotted relationship
+// columns, so {"Owner": {"Email": "x"}} is loaded into Owner.Email, and records holding arrays, which have no column
+// to go in, are rejected.
+package flatten
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"github.com/pkg/errors"
+	"github.com/rfaulhaber/forcedata/pipeline"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Formats records can be read from.
+const (
+	JSON   = "json"
+	NDJSON = "ndjson"
+)
+
+// Null is written for JSON nulls. The Bulk API sets fields to null for it, where it leaves a blank value unchanged.
+const Null = "#N/A"
+
+// RecordColumn is added to the CSV of a table with rejected rows. It holds the 1-based number of each row's record, so
+// that a Rejecter finds the rejected rows by their number rather than by their position, which blank lines would throw
+// off.
+const RecordColumn = "forcedata__Record"
+
+// Table is a set of flattened records.
+type Table struct {
+	Header []string
+	Rows   [][]string
+
+	// Rejects holds the reason each rejected row, by index into Rows, can't be loaded. Rejected rows are kept in Rows,
+	// with arrays written as JSON, so that they can be written to a reject file.
+	Rejects map[int]string
+}
+
+// object is a JSON object with its keys in the order they were read.
+type object struct {
+	keys   []string
+	values map[string]interface{}
+}
+
+// field is a flattened value.
+type field struct {
+	name  string
+	value string
+}
+
+// Decode reads the records of content in the given format: a JSON array of objects, or a single object, for JSON, and
+// an object on each line for NDJSON.
+func Decode(content []byte, format string) ([]interface{}, error) {
+	switch strings.ToLower(format) {
+	case JSON:
+		return decodeJSON(content)
+	case NDJSON:
+		return decodeNDJSON(content)
+	}
+
+	return nil, errors.Errorf("%q is not a JSON format; use json or ndjson", format)
+}
+
+func decodeJSON(content []byte) ([]interface{}, error) {
+	d := newDecoder(content)
+
+	value, err := decodeValue(d)
+
+	if err != nil {
+		return nil, errors.Wrap(err, "could not read JSON")
+	}
+
+	if _, err := d.Token(); err != io.EOF {
+		return nil, errors.New("could not read JSON: content continues after the first value; use --format ndjson for a record on each line")
+	}
+
+	if values, ok := value.([]interface{}); ok {
+		return values, nil
+	}
+
+	return []interface{}{value}, nil
+}
+
+func decodeNDJSON(content []byte) ([]interface{}, error) {
+	var values []interface{}
+
+	for i, line := range bytes.Split(content, []byte("\n")) {
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+
+		value, err := decodeValue(newDecoder(line))
+
+		if err != nil {
+			return nil, errors.Wrapf(err, "could not read line %d", i+1)
+		}
+
+		values = append(values, value)
+	}
+
+	return values, nil
+}
+
+func newDecoder(content []byte) *json.Decoder {
+	d := json.NewDecoder(bytes.NewReader(content))
+	d.UseNumber()
+
+	return d
+}
+
+// decodeValue reads the next JSON value, keeping the order of object keys, which json.Unmarshal would lose.
+func decodeValue(d *json.Decoder) (interface{}, error) {
+	tok, err := d.Token()
+
+	if err == io.EOF {
+		return nil, errors.New("unexpected end of JSON")
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	switch tok {
+	case json.Delim('{'):
+		obj := &object{values: make(map[string]interface{})}
+
+		for d.More() {
+			key, err := d.Token()
+
+			if err != nil {
+				return nil, err
+			}
+
+			value, err := decodeValue(d)
+
+			if err != nil {
+				return nil, err
+			}
+
+			name := key.(string)
+
+			if _, ok := obj.values[name]; !ok {
+				obj.keys = append(obj.keys, name)
+			}
+
+			obj.values[name] = value
+		}
+
+		_, err := d.Token()
+
+		return obj, err
+	case json.Delim('['):
+		values := []interface{}{}
+
+		for d.More() {
+			value, err := decodeValue(d)
+
+			if err != nil {
+				return nil, err
+			}
+
+			values = append(values, value)
+		}
+
+		_, err := d.Token()
+
+		return values, err
+	}
+
+	return tok, nil
+}
+
+// Flatten flattens records into a table. The header is fields if any are given, matched to keys case-insensitively,
+// and otherwise every key of every record, in the order they first appear. Records holding arrays are rejected, unless
+// fields are given and the arrays aren't among them.
+func Flatten(values []interface{}, fields []string) *Table {
+	t := &Table{Header: fields, Rejects: make(map[int]string)}
+
+	var (
+		flattened [][]field
+		arrays    [][]string
+	)
+
+	for i, value := range values {
+		obj, ok := value.(*object)
+
+		if !ok {
+			t.Rejects[i] = "record is not a JSON object"
+		}
+
+		var (
+			f []field
+			a []string
+		)
+
+		if ok {
+			flattenObject(obj, "", &f, &a)
+		}
+
+		flattened = append(flattened, f)
+		arrays = append(arrays, a)
+	}
+
+	if len(fields) == 0 {
+		t.Header = union(flattened)
+	}
+
+	columns := make(map[string]int)
+
+	for i, name := range t.Header {
+		columns[strings.ToLower(name)] = i
+	}
+
+	for i, f := range flattened {
+		row := make([]string, len(t.Header))
+
+		for _, v := range f {
+			if col, ok := columns[strings.ToLower(v.name)]; ok {
+				row[col] = v.value
+			}
+		}
+
+		t.Rows = append(t.Rows, row)
+
+		var loaded []string
+
+		for _, name := range arrays[i] {
+			if t.loads(name) {
+				loaded = append(loaded, name)
+			}
+		}
+
+		switch len(loaded) {
+		case 0:
+		case 1:
+			t.Rejects[i] = loaded[0] + " is an array, which can't be loaded into a field"
+		default:
+			t.Rejects[i] = strings.Join(loaded, ", ") + " are arrays, which can't be loaded into fields"
+		}
+	}
+
+	return t
+}
+
+// loads reports whether the header has a column for a key, or for a key nested within it.
+func (t *Table) loads(key string) bool {
+	for _, name := range t.Header {
+		if strings.EqualFold(name, key) || len(name) > len(key) && strings.EqualFold(name[:len(key)+1], key+".") {
+			return true
+		}
+	}
+
+	return false
+}
+
+func flattenObject(obj *object, prefix string, fields *[]field, arrays *[]string) {
+	for _, key := range obj.keys {
+		name := prefix + key
+
+		switch value := obj.values[key].(type) {
+		case *object:
+			flattenObject(value, name+".", fields, arrays)
+		case []interface{}:
+			b, _ := json.Marshal(plain(value))
+			*fields = append(*fields, field{name, string(b)})
+			*arrays = append(*arrays, name)
+		default:
+			*fields = append(*fields, field{name, text(value)})
+		}
+	}
+}
+
+// union returns the names of every flattened field, in the order they first appear, compared case-insensitively.
+func union(flattened [][]field) []string {
+	var names []string
+
+	seen := make(map[string]bool)
+
+	for _, f := range flattened {
+		for _, v := range f {
+			if key := strings.ToLower(v.name); !seen[key] {
+				seen[key] = true
+				names = append(names, v.name)
+			}
+		}
+	}
+
+	return names
+}
+
+// text returns a JSON scalar as Salesforce expects it. Numbers are written without exponents.
+func text(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return Null
+	case string:
+		return v
+	case bool:
+		return strconv.FormatBool(v)
+	case json.Number:
+		s := v.String()
+
+		if !strings.ContainsAny(s, "eE") {
+			return s
+		}
+
+		f, err := v.Float64()
+
+		if err != nil {
+			return s
+		}
+
+		return strconv.FormatFloat(f, 'f', -1, 64)
+	}
+
+	return ""
+}
+
+// plain converts decoded values back into ones json.Marshal writes, for arrays written to reject files.
+func plain(value interface{}) interface{} {
+	switch v := value.(type) {
+	case *object:
+		m := make(map[string]interface{})
+
+		for key, value := range v.values {
+			m[key] = plain(value)
+		}
+
+		return m
+	case []interface{}:
+		values := make([]interface{}, len(v))
+
+		for i, value := range v {
+			values[i] = plain(value)
+		}
+
+		return values
+	}
+
+	return value
+}
+
+// CSV writes the table as comma delimited CSV, rejected rows included. If there are any, each row is numbered in a
+// RecordColumn for a Rejecter to remove.
+func (t *Table) CSV() ([]byte, error) {
+	var buf bytes.Buffer
+
+	w := csv.NewWriter(&buf)
+
+	header, rows := t.Header, t.Rows
+
+	if len(t.Rejects) > 0 {
+		header = append(append([]string{}, header...), RecordColumn)
+		rows = make([][]string, len(t.Rows))
+
+		for i, row := range t.Rows {
+			rows[i] = append(append([]string{}, row...), strconv.Itoa(i+1))
+		}
+	}
+
+	if err := w.Write(header); err != nil {
+		return nil, err
+	}
+
+	if err := w.WriteAll(rows); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// Rejecter is a pipeline stage that rejects the rows of a table that can't be loaded, and removes the RecordColumn
+// from the rest. It must be the first stage the table's CSV passes through.
+type Rejecter struct {
+	table  *Table
+	column int
+}
+
+// Rejecter returns a stage rejecting the table's rejected rows.
+func (t *Table) Rejecter() *Rejecter {
+	return &Rejecter{table: t}
+}
+
+// Header finds the RecordColumn and removes it.
+func (r *Rejecter) Header(header []string) ([]string, error) {
+	r.column = len(header) - 1
+
+	if r.column < 0 || header[r.column] != RecordColumn {
+		return nil, errors.Errorf("content has no %s column", RecordColumn)
+	}
+
+	return header[:r.column], nil
+}
+
+// Row rejects the row if its record was rejected, with the record's number.
+func (r *Rejecter) Row(row []string) ([]string, error) {
+	if r.column >= len(row) {
+		return nil, errors.Errorf("row has no %s", RecordColumn)
+	}
+
+	n, err := strconv.Atoi(row[r.column])
+
+	if err != nil {
+		return nil, errors.Errorf("%s %q isn't a record number", RecordColumn, row[r.column])
+	}
+
+	if reason, ok := r.table.Rejects[n-1]; ok {
+		return nil, pipeline.Reject("record %d: %s", n, reason)
+	}
+
+	return row[:r.column], nil
+}
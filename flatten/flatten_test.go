@@ -0,0 +1,91 @@
+package flatten
+
+import (
+	"bytes"
+	"encoding/csv"
+	"github.com/rfaulhaber/forcedata/pipeline"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+const testNDJSON = `{"Name": "Acme", "NumberOfEmployees": 1.2e3, "Owner": {"Email": "a@example.com"}}
+{"Name": "Globex", "IsActive": false, "Phone": null}
+
+{"name": "Initech", "Tags": ["a", "b"], "Contacts": [{"LastName": "Smith"}]}
+"Hooli"
+`
+
+func TestFlatten(t *testing.T) {
+	values, err := Decode([]byte(testNDJSON), "NDJSON")
+	assert.NoError(t, err)
+	assert.Len(t, values, 4)
+
+	table := Flatten(values, nil)
+	assert.Equal(t, []string{"Name", "NumberOfEmployees", "Owner.Email", "IsActive", "Phone", "Tags", "Contacts"}, table.Header)
+	assert.Equal(t, [][]string{
+		{"Acme", "1200", "a@example.com", "", "", "", ""},
+		{"Globex", "", "", "false", Null, "", ""},
+		{"Initech", "", "", "", "", `["a","b"]`, `[{"LastName":"Smith"}]`},
+		{"", "", "", "", "", "", ""},
+	}, table.Rows)
+	assert.Equal(t, map[int]string{
+		2: "Tags, Contacts are arrays, which can't be loaded into fields",
+		3: "record is not a JSON object",
+	}, table.Rejects)
+
+	content, err := table.CSV()
+	assert.NoError(t, err)
+
+	var out, rejects bytes.Buffer
+
+	p := pipeline.Pipeline{Stages: []pipeline.Stage{table.Rejecter()}, Rejects: csv.NewWriter(&rejects)}
+	assert.NoError(t, p.Run(csv.NewReader(bytes.NewReader(content)), csv.NewWriter(&out)))
+	assert.Equal(t, 2, p.Rows)
+	assert.Equal(t, 2, p.Rejected)
+	assert.Contains(t, rejects.String(), "record 3: Tags, Contacts are arrays")
+	assert.Contains(t, rejects.String(), "record 4: record is not a JSON object")
+}
+
+func TestRejecter_BlankRows(t *testing.T) {
+	values, err := Decode([]byte(`[{}, {"a": [1, 2]}, {"a": "x"}]`), JSON)
+	assert.NoError(t, err)
+
+	table := Flatten(values, nil)
+
+	content, err := table.CSV()
+	assert.NoError(t, err)
+	assert.Equal(t, "a,forcedata__Record\n,1\n\"[1,2]\",2\nx,3\n", string(content))
+
+	var out, rejects bytes.Buffer
+
+	p := pipeline.Pipeline{Stages: []pipeline.Stage{table.Rejecter()}, Rejects: csv.NewWriter(&rejects)}
+	assert.NoError(t, p.Run(csv.NewReader(bytes.NewReader(content)), csv.NewWriter(&out)))
+	assert.Equal(t, "a\n\nx\n", out.String())
+	assert.Equal(t, "a,forcedata__Record,forcedata__Error\n\"[1,2]\",2,\"record 2: a is an array, which can't be loaded into a field\"\n", rejects.String())
+}
+
+func TestFlatten_Fields(t *testing.T) {
+	values, err := Decode([]byte(`[{"Name": "Acme", "Owner": {"email": "a@example.com"}, "Tags": ["a"]},
+{"name": "Globex", "Owner": [{"Email": "b@example.com"}]}]`), JSON)
+	assert.NoError(t, err)
+
+	table := Flatten(values, []string{"Name", "Owner.Email"})
+	assert.Equal(t, []string{"Name", "Owner.Email"}, table.Header)
+	assert.Equal(t, [][]string{{"Acme", "a@example.com"}, {"Globex", ""}}, table.Rows)
+	assert.Equal(t, map[int]string{1: "Owner is an array, which can't be loaded into a field"}, table.Rejects)
+}
+
+func TestDecode(t *testing.T) {
+	values, err := Decode([]byte(`{"Name": "Acme"}`), JSON)
+	assert.NoError(t, err)
+	assert.Len(t, values, 1)
+
+	_, err = Decode([]byte("{\"Name\": \"Acme\"}\n{\"Name\": \"Globex\"}\n"), JSON)
+	assert.EqualError(t, err, "could not read JSON: content continues after the first value; use --format ndjson for a record on each line")
+
+	_, err = Decode([]byte("{\"Name\": \"Acme\"}\n{\"Name\": \n"), NDJSON)
+	assert.EqualError(t, err, "could not read line 2: unexpected end of JSON")
+
+	_, err = Decode(nil, "xml")
+	assert.EqualError(t, err, `"xml" is not a JSON format; use json or ndjson`)
+}
@@ -0,0 +1,153 @@
+package job
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"strings"
+)
+
+// Error codes the Bulk API returns that callers commonly need to tell apart.
+const (
+	CodeInvalidSession       = "INVALID_SESSION_ID"
+	CodeInvalidJob           = "INVALIDJOB"
+	CodeInvalidJobState      = "INVALIDJOBSTATE"
+	CodeInvalidField         = "INVALID_FIELD"
+	CodeAPIDisabled          = "API_DISABLED_FOR_ORG"
+	CodeRequestLimitExceeded = "REQUEST_LIMIT_EXCEEDED"
+)
+
+// Sentinel errors for matching with errors.Is. A JobError or RequestError matches a sentinel if it carries the same
+// error code.
+var (
+	ErrInvalidSession       = JobError{ErrorCode: CodeInvalidSession}
+	ErrInvalidJob           = JobError{ErrorCode: CodeInvalidJob}
+	ErrInvalidJobState      = JobError{ErrorCode: CodeInvalidJobState}
+	ErrInvalidField         = JobError{ErrorCode: CodeInvalidField}
+	ErrAPIDisabled          = JobError{ErrorCode: CodeAPIDisabled}
+	ErrRequestLimitExceeded = JobError{ErrorCode: CodeRequestLimitExceeded}
+)
+
+// Header the server uses to identify a request, useful when opening a case with Salesforce support.
+const requestIDHeader = "X-Request-Id"
+
+// JobError is a single error reported by the Bulk API.
+type JobError struct {
+	Message   string   `json:"message"`
+	ErrorCode string   `json:"errorCode"`
+	Fields    []string `json:"fields"`
+}
+
+func (e JobError) Error() string {
+	return e.Message
+}
+
+// Is reports whether target is a JobError with the same error code, so that errors.Is(err, ErrInvalidSession) works.
+func (e JobError) Is(target error) bool {
+	switch t := target.(type) {
+	case JobError:
+		return t.ErrorCode == e.ErrorCode
+	case *JobError:
+		return t != nil && t.ErrorCode == e.ErrorCode
+	default:
+		return false
+	}
+}
+
+// RequestError is returned when the server rejects a request. It keeps every error in the response body along with
+// the HTTP status and request ID.
+type RequestError struct {
+	StatusCode int
+	RequestID  string
+	Errors     []JobError
+}
+
+func (e *RequestError) Error() string {
+	if len(e.Errors) == 0 {
+		return "server responded with " + http.StatusText(e.StatusCode)
+	}
+
+	messages := make([]string, len(e.Errors))
+
+	for i, jobErr := range e.Errors {
+		if jobErr.ErrorCode != "" {
+			messages[i] = jobErr.ErrorCode + ": " + jobErr.Message
+		} else {
+			messages[i] = jobErr.Message
+		}
+	}
+
+	return strings.Join(messages, "; ")
+}
+
+// Is reports whether any of the returned errors matches target.
+func (e *RequestError) Is(target error) bool {
+	for _, jobErr := range e.Errors {
+		if jobErr.Is(target) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// As sets a *JobError target to the first returned error.
+func (e *RequestError) As(target interface{}) bool {
+	if t, ok := target.(*JobError); ok && len(e.Errors) > 0 {
+		*t = e.Errors[0]
+		return true
+	}
+
+	return false
+}
+
+// Code returns the error code of the first returned error, or an empty string if there isn't one.
+func (e *RequestError) Code() string {
+	if len(e.Errors) == 0 {
+		return ""
+	}
+
+	return e.Errors[0].ErrorCode
+}
+
+// HasCode returns true if any of the returned errors has the given code.
+func (e *RequestError) HasCode(code string) bool {
+	for _, jobErr := range e.Errors {
+		if jobErr.ErrorCode == code {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Fields returns every field named by the returned errors.
+func (e *RequestError) Fields() []string {
+	var fields []string
+
+	for _, jobErr := range e.Errors {
+		fields = append(fields, jobErr.Fields...)
+	}
+
+	return fields
+}
+
+// NewRequestError reads an error response. If the body isn't a list of errors, its raw contents become the message.
+func NewRequestError(resp *http.Response) *RequestError {
+	reqErr := &RequestError{
+		StatusCode: resp.StatusCode,
+		RequestID:  resp.Header.Get(requestIDHeader),
+	}
+
+	body, err := ioutil.ReadAll(resp.Body)
+
+	if err != nil || len(body) == 0 {
+		return reqErr
+	}
+
+	if err := json.Unmarshal(body, &reqErr.Errors); err != nil {
+		reqErr.Errors = []JobError{{Message: strings.TrimSpace(string(body))}}
+	}
+
+	return reqErr
+}
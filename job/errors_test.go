@@ -0,0 +1,66 @@
+package job
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRequestError_Is(t *testing.T) {
+	err := &RequestError{
+		StatusCode: 400,
+		Errors: []JobError{
+			{ErrorCode: "INVALID_FIELD", Message: "No such column 'Nmae'", Fields: []string{"Nmae"}},
+			{ErrorCode: "INVALID_SESSION_ID", Message: "Session expired or invalid"},
+		},
+	}
+
+	assert.True(t, errors.Is(err, ErrInvalidField))
+	assert.True(t, errors.Is(err, ErrInvalidSession))
+	assert.False(t, errors.Is(err, ErrAPIDisabled))
+	assert.Equal(t, "INVALID_FIELD", err.Code())
+	assert.Equal(t, []string{"Nmae"}, err.Fields())
+	assert.Equal(t, "INVALID_FIELD: No such column 'Nmae'; INVALID_SESSION_ID: Session expired or invalid", err.Error())
+}
+
+func TestRequestError_As(t *testing.T) {
+	var err error = &RequestError{
+		StatusCode: 403,
+		Errors:     []JobError{{ErrorCode: "REQUEST_LIMIT_EXCEEDED", Message: "TotalRequests Limit exceeded."}},
+	}
+
+	var jobErr JobError
+
+	assert.True(t, errors.As(err, &jobErr))
+	assert.Equal(t, "REQUEST_LIMIT_EXCEEDED", jobErr.ErrorCode)
+}
+
+func TestNewRequestError(t *testing.T) {
+	testCases := []struct {
+		body     string
+		expected []JobError
+	}{
+		{`[{"errorCode":"API_DISABLED_FOR_ORG","message":"API is not enabled for this Organization or Partner"}]`, []JobError{{ErrorCode: "API_DISABLED_FOR_ORG", Message: "API is not enabled for this Organization or Partner"}}},
+		{"Service Unavailable\n", []JobError{{Message: "Service Unavailable"}}},
+		{"", nil},
+	}
+
+	for _, tc := range testCases {
+		recorder := httptest.NewRecorder()
+		recorder.Header().Set("X-Request-Id", "REQ123")
+		recorder.WriteHeader(503)
+		recorder.WriteString(tc.body)
+
+		err := NewRequestError(recorder.Result())
+
+		assert.Equal(t, 503, err.StatusCode)
+		assert.Equal(t, "REQ123", err.RequestID)
+		assert.Equal(t, tc.expected, err.Errors)
+
+		if tc.expected == nil {
+			assert.Equal(t, "server responded with "+http.StatusText(503), err.Error())
+		}
+	}
+}
@@ -13,7 +13,6 @@ import (
 
 const (
 	DefaultWatchTime = 5 * time.Second
-	latestVersion    = "43.0"
 )
 
 var delimMap = map[string]string{
@@ -31,6 +30,18 @@ func GetDelimName(delim string) (string, bool) {
 	return name, ok
 }
 
+// Returns the delimiter with the given name, e.g. "," for "COMMA", and true if the name is valid. Otherwise returns
+// false.
+func GetDelim(name string) (string, bool) {
+	for delim, n := range delimMap {
+		if n == name {
+			return delim, true
+		}
+	}
+
+	return "", false
+}
+
 type JobInfo struct {
 	ApexProcessingTime      uint    `json:"apexProcessingTime"`
 	APIActiveProcessingTime int     `json:"apiActiveProcessingTime"`
@@ -41,6 +52,7 @@ type JobInfo struct {
 	ContentURL              string  `json:"contentUrl"`
 	CreatedByID             string  `json:"createdById"`
 	CreatedDate             string  `json:"createdDate"`
+	ErrorMessage            string  `json:"errorMessage"`
 	ExternalIdFieldName     string  `json:"externalIdFieldName"`
 	ID                      string  `json:"id"`
 	JobType                 string  `json:"jobType"`
@@ -55,21 +67,12 @@ type JobInfo struct {
 	TotalProcessingTime     uint    `json:"totalProcessingTime"`
 }
 
-type JobError struct {
-	Message   string   `json:"message"`
-	ErrorCode string   `json:"errorCode"`
-	Fields    []string `json:"fields"`
-}
-
-func (e JobError) Error() string {
-	return e.Message
-}
-
 type JobConfig struct {
-	Object      string `json:"object"`
-	Operation   string `json:"operation"`
-	ContentType string `json:"contentType"`
-	Delim       string `json:"columnDelimiter"`
+	Object          string `json:"object" yaml:"object"`
+	Operation       string `json:"operation" yaml:"operation"`
+	ContentType     string `json:"contentType" yaml:"contentType,omitempty"`
+	Delim           string `json:"columnDelimiter" yaml:"columnDelimiter,omitempty"`
+	ExternalIDField string `json:"externalIdFieldName,omitempty" yaml:"externalIdFieldName,omitempty"`
 }
 
 type Job struct {
@@ -110,18 +113,16 @@ func (j *Job) Create() error {
 		return errors.Wrap(err, "creating job returned error")
 	}
 
-	var responseBody bytes.Buffer
+	defer response.Body.Close()
 
-	bodyReader := io.TeeReader(response.Body, &responseBody)
+	if response.StatusCode >= 300 {
+		return NewRequestError(response)
+	}
 
-	info, err := getJobInfo(bodyReader)
+	info, err := getJobInfo(response.Body)
 
 	if err != nil {
-		if jobErr, ok := checkJobError(err, &responseBody); ok {
-			return jobErr
-		} else {
-			return errors.Wrap(err, "server returned error creating job")
-		}
+		return errors.Wrap(err, "server returned error creating job")
 	}
 
 	j.info = info
@@ -131,14 +132,28 @@ func (j *Job) Create() error {
 // Uploads files to the job created. Must call Create() first. Sets the job to "Closed" when finished. Takes in CSV
 // content as a Reader.
 func (j *Job) Upload(content []byte) error {
+	return j.UploadWithProgress(content, nil)
+}
+
+// UploadWithProgress uploads content as Upload does, calling progress, if it isn't nil, with the number of bytes sent
+// so far and the total as the content is sent.
+func (j *Job) UploadWithProgress(content []byte, progress func(sent, total int)) error {
 	endpoint := j.batchURL()
 
-	req, err := http.NewRequest("PUT", endpoint, bytes.NewReader(content))
+	var body io.Reader = bytes.NewReader(content)
+
+	if progress != nil {
+		body = &progressReader{bytes.NewReader(content), len(content), progress}
+	}
+
+	req, err := http.NewRequest("PUT", endpoint, body)
 
 	if err != nil {
 		return errors.Wrap(err, "could not generate upload request")
 	}
 
+	req.ContentLength = int64(len(content))
+
 	req.Header.Add("Content-Type", "text/csv")
 	req.Header.Add("Accept", "application/json")
 	req.Header.Add("Authorization", "Bearer "+j.session.AccessToken)
@@ -151,17 +166,30 @@ func (j *Job) Upload(content []byte) error {
 		return errors.Wrap(err, "upload response error")
 	}
 
+	defer resp.Body.Close()
+
 	if resp.StatusCode != 201 {
-		jobError, err := getJobError(resp.Body)
+		return errors.Wrapf(NewRequestError(resp), "upload: server responded with %d", resp.StatusCode)
+	}
 
-		if err != nil {
-			return errors.Wrap(err, "could not read upload response body")
-		}
+	return j.uploadComplete()
+}
+
+// progressReader reports how much of its content has been read.
+type progressReader struct {
+	r        *bytes.Reader
+	total    int
+	progress func(sent, total int)
+}
+
+func (p *progressReader) Read(b []byte) (int, error) {
+	n, err := p.r.Read(b)
 
-		return errors.Errorf("upload: server responded with %d, error: code: %s, message: %s", resp.StatusCode, jobError[0].ErrorCode, jobError[0].Message)
+	if n > 0 {
+		p.progress(p.total-p.r.Len(), p.total)
 	}
 
-	return j.uploadComplete()
+	return n, err
 }
 
 // Continuously makes request to get job info from the server.  Writes progress to the Status channel. If server reports
@@ -194,6 +222,12 @@ func (j *Job) GetInfo() (JobInfo, error) {
 		return JobInfo{}, err
 	}
 
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return JobInfo{}, NewRequestError(resp)
+	}
+
 	info, err := getJobInfo(resp.Body)
 
 	if err != nil {
@@ -223,23 +257,50 @@ func (j *Job) Delete() error {
 		return errors.Wrap(err, "delete request failed")
 	}
 
+	defer resp.Body.Close()
+
 	if resp.StatusCode != 204 {
-		return errors.New("something went wrong with deleting job")
+		return errors.Wrap(NewRequestError(resp), "delete")
 	}
 
 	return nil
 }
 
+// Returns the successfully processed records of a finished job as CSV, with each record's ID in an sf__Id column and
+// whether it was created in sf__Created.
 func (j *Job) GetSuccess() ([]byte, error) {
-	panic("not implemented")
+	return j.getResults("successfulResults")
 }
 
+// Returns the records of a finished job that failed as CSV, with the reason in an sf__Error column and the record's ID
+// in sf__Id, if it has one.
 func (j *Job) GetFailure() ([]byte, error) {
-	panic("not implemented")
+	return j.getResults("failedResults")
 }
 
+// Returns the records of a job that weren't processed, because the job failed or was aborted, as CSV.
 func (j *Job) GetUnprocessed() ([]byte, error) {
-	panic("not implemented")
+	return j.getResults("unprocessedrecords")
+}
+
+// Waits for the job to finish, checking the server at the given interval, and returns its final info. A job is
+// finished once its state is "JobComplete", "Failed" or "Aborted".
+func (j *Job) Wait(d time.Duration) (JobInfo, error) {
+	for {
+		info, err := j.GetInfo()
+
+		if err != nil {
+			return info, err
+		}
+
+		switch info.State {
+		case "JobComplete", "Failed", "Aborted":
+			j.info = info
+			return info, nil
+		}
+
+		time.Sleep(d)
+	}
 }
 
 func (j *Job) SetInfo(info JobInfo) {
@@ -269,18 +330,16 @@ func (j *Job) setState(state string) error {
 		return err
 	}
 
-	var responseBody bytes.Buffer
+	defer resp.Body.Close()
 
-	bodyReader := io.TeeReader(resp.Body, &responseBody)
+	if resp.StatusCode >= 300 {
+		return NewRequestError(resp)
+	}
 
-	_, err = getJobInfo(bodyReader)
+	_, err = getJobInfo(resp.Body)
 
 	if err != nil {
-		if jobErr, ok := checkJobError(err, &responseBody); ok {
-			return jobErr
-		} else {
-			return errors.Wrap(err, "response error from setting state to "+state)
-		}
+		return errors.Wrap(err, "response error from setting state to "+state)
 	}
 
 	return nil
@@ -291,69 +350,80 @@ func (j *Job) batchURL() string {
 }
 
 func (j *Job) ingestURL() string {
-	return j.session.InstanceURL + "/services/data/v" + latestVersion + "/jobs/ingest/"
+	return j.session.DataURL() + "/jobs/ingest/"
 }
 
 func (j *Job) ingestURLWithID() string {
 	return j.ingestURL() + j.info.ID
 }
 
-func (j *Job) jsonRequest(method string, url string, body []byte) (*http.Response, error) {
-	req, err := http.NewRequest(method, url, bytes.NewReader(body))
+// ResultsURL returns the URL of the job's results of the given kind: successfulResults, failedResults or
+// unprocessedrecords.
+func (j *Job) ResultsURL(name string) string {
+	return j.ingestURLWithID() + "/" + name + "/"
+}
+
+func (j *Job) getResults(name string) ([]byte, error) {
+	req, err := http.NewRequest("GET", j.ResultsURL(name), nil)
 
 	if err != nil {
 		return nil, errors.Wrap(err, "request generation failed")
 	}
 
-	req.Header.Add("Content-Type", "application/json")
-	req.Header.Add("Accept", "application/json")
+	req.Header.Add("Accept", "text/csv")
 	req.Header.Add("Authorization", "Bearer "+j.session.AccessToken)
 
 	resp, err := http.DefaultClient.Do(req)
 
 	if err != nil {
-		return nil, errors.Wrap(err, method+" response returned error")
+		return nil, errors.Wrap(err, name+" request failed")
 	}
 
-	return resp, nil
-}
+	defer resp.Body.Close()
 
-func getJobInfo(b io.Reader) (JobInfo, error) {
-	var info JobInfo
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.Wrap(NewRequestError(resp), name)
+	}
 
-	err := readJSONBody(b, &info)
+	content, err := ioutil.ReadAll(resp.Body)
 
 	if err != nil {
-		return info, errors.Wrap(err, "tried parsing job info")
+		return nil, errors.Wrap(err, "could not read "+name)
 	}
 
-	return info, nil
+	return content, nil
 }
 
-func checkJobError(err error, reader io.Reader) (JobError, bool) {
-	if _, ok := errors.Cause(err).(*json.UnmarshalTypeError); ok {
-		jobErr, err := getJobError(reader)
+func (j *Job) jsonRequest(method string, url string, body []byte) (*http.Response, error) {
+	req, err := http.NewRequest(method, url, bytes.NewReader(body))
 
-		if err != nil {
-			return JobError{}, false
-		}
+	if err != nil {
+		return nil, errors.Wrap(err, "request generation failed")
+	}
+
+	req.Header.Add("Content-Type", "application/json")
+	req.Header.Add("Accept", "application/json")
+	req.Header.Add("Authorization", "Bearer "+j.session.AccessToken)
+
+	resp, err := http.DefaultClient.Do(req)
 
-		return jobErr[0], true
-	} else {
-		return JobError{}, false
+	if err != nil {
+		return nil, errors.Wrap(err, method+" response returned error")
 	}
+
+	return resp, nil
 }
 
-func getJobError(b io.Reader) ([]JobError, error) {
-	var e []JobError
+func getJobInfo(b io.Reader) (JobInfo, error) {
+	var info JobInfo
 
-	err := readJSONBody(b, &e)
+	err := readJSONBody(b, &info)
 
 	if err != nil {
-		return e, errors.Wrap(err, "tried parsing job error")
+		return info, errors.Wrap(err, "tried parsing job info")
 	}
 
-	return e, nil
+	return info, nil
 }
 
 func readJSONBody(b io.Reader, v interface{}) error {
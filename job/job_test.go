@@ -2,7 +2,9 @@ package job
 
 import (
 	"encoding/json"
+	"errors"
 	"github.com/rfaulhaber/forcedata/auth"
+	"github.com/rfaulhaber/forcedata/jobtest"
 	"github.com/stretchr/testify/assert"
 	"io/ioutil"
 	"net/http"
@@ -94,13 +96,15 @@ func TestJob_Create(t *testing.T) {
 	for i, tc := range testCases {
 		server := httptest.NewServer(tc.handler)
 
-		job := New(JobConfig{"Contact", "insert", "CSV", "COMMA"}, makeSession(server.URL))
+		job := New(JobConfig{"Contact", "insert", "CSV", "COMMA", ""}, makeSession(server.URL))
 
 		err := job.Create()
 
 		if i == 1 {
 			assert.Error(t, err)
-			assert.IsType(t, err, JobError{})
+			assert.IsType(t, err, &RequestError{})
+			assert.Equal(t, 401, err.(*RequestError).StatusCode)
+			assert.True(t, err.(*RequestError).HasCode("TEST_CASE"))
 		} else {
 			assert.NoError(t, err)
 			assert.True(t, job.info.ID == "123ID321")
@@ -153,7 +157,7 @@ func TestJob_Upload(t *testing.T) {
 		}
 	}))
 
-	job := New(JobConfig{"Contact", "insert", "CSV", "COMMA"}, makeSession(server.URL))
+	job := New(JobConfig{"Contact", "insert", "CSV", "COMMA", ""}, makeSession(server.URL))
 	job.info.ID = "123ID321"
 	job.info.ContentURL = "services/data/v43.0/jobs/batches"
 
@@ -164,6 +168,7 @@ func TestJob_Upload(t *testing.T) {
 	assert.Equal(t, 2, callCount)
 	assert.Equal(t, testBody, actualBody)
 	assert.Equal(t, []byte(`{"state":"UploadComplete"}`), actualCloseBody)
+	assert.Equal(t, "/services/data/v43.0/jobs/ingest/123ID321", actualCloseURL)
 	assert.Equal(t, "PUT", actualMethod)
 }
 
@@ -200,7 +205,7 @@ func TestJob_UploadError(t *testing.T) {
 		}
 	}))
 
-	job := New(JobConfig{"Contact", "insert", "CSV", "COMMA"}, makeSession(server.URL))
+	job := New(JobConfig{"Contact", "insert", "CSV", "COMMA", ""}, makeSession(server.URL))
 	job.info.ID = "123ID321"
 	job.info.ContentURL = "services/data/v43.0/jobs/batches"
 
@@ -208,7 +213,9 @@ func TestJob_UploadError(t *testing.T) {
 
 	assert.Error(t, err)
 	assert.Equal(t, 1, callCount)
-	assert.Equal(t, err.Error(), "upload: server responded with 401, error: code: ERROR_CODE, message: test message")
+	assert.Equal(t, testBody, actualBody)
+	assert.Equal(t, job.batchURL(), server.URL+actualEndpoint)
+	assert.Equal(t, err.Error(), "upload: server responded with 401: ERROR_CODE: test message")
 }
 
 func TestJob_UploadCloseError(t *testing.T) {
@@ -245,28 +252,178 @@ func TestJob_UploadCloseError(t *testing.T) {
 		}
 	}))
 
-	job := New(JobConfig{"Contact", "insert", "CSV", "COMMA"}, makeSession(server.URL))
+	job := New(JobConfig{"Contact", "insert", "CSV", "COMMA", ""}, makeSession(server.URL))
 	job.info.ID = "123ID321"
 	job.info.ContentURL = "services/data/v43.0/jobs/batches"
 
 	err := job.Upload(testBody)
 
 	assert.Error(t, err)
-	assert.Equal(t, testErrorMessage, err.Error())
+	assert.Equal(t, testErrorCode+": "+testErrorMessage, err.Error())
 	assert.Equal(t, "PATCH", actualMethod)
 	assert.Equal(t, "/services/data/v43.0/jobs/ingest/123ID321", actualURL)
 }
 
 func TestJob_Abort(t *testing.T) {
+	sim := jobtest.New()
+	server := sim.Start()
+	defer server.Close()
+
+	job := New(JobConfig{"Contact", "insert", "CSV", "COMMA", ""}, sim.Session(server.URL))
+
+	assert.NoError(t, job.Create())
+	assert.NoError(t, job.Abort())
+
+	info, err := job.GetInfo()
 
+	assert.NoError(t, err)
+	assert.Equal(t, "Aborted", info.State)
+	assert.True(t, errors.Is(job.Abort(), ErrInvalidJobState))
 }
 
 func TestJob_Complete(t *testing.T) {
+	sim := jobtest.New()
+	server := sim.Start()
+	defer server.Close()
+
+	job := New(JobConfig{"Contact", "insert", "CSV", "COMMA", ""}, sim.Session(server.URL))
+
+	assert.NoError(t, job.Create())
+	assert.NoError(t, job.Complete())
+
+	info, err := job.GetInfo()
 
+	assert.NoError(t, err)
+	assert.Equal(t, "Failed", info.State)
+}
+
+func TestJob_Watch(t *testing.T) {
+	sim := jobtest.New()
+	sim.ProcessingTime = 20 * time.Millisecond
+	sim.Rules = []jobtest.Rule{jobtest.FailWhen("LastName", "", "REQUIRED_FIELD_MISSING", "Required fields are missing: [LastName]")}
+
+	server := sim.Start()
+	defer server.Close()
+
+	job := New(JobConfig{"Contact", "insert", "CSV", "COMMA", ""}, sim.Session(server.URL))
+
+	assert.NoError(t, job.Create())
+	assert.NoError(t, job.Upload([]byte("FirstName,LastName\nPerson,One\nPerson,\n")))
+
+	go job.Watch(5 * time.Millisecond)
+
+	for range job.Status {
+	}
+
+	info, err := job.GetInfo()
+
+	assert.NoError(t, err)
+	assert.Equal(t, "JobComplete", info.State)
+	assert.Equal(t, uint(2), info.RecordsProcessed)
+	assert.Equal(t, uint(1), info.RecordsFailed)
+	assert.Len(t, sim.Records("Contact"), 1)
+}
+
+func TestJob_UploadWithProgress(t *testing.T) {
+	sim := jobtest.New()
+	server := sim.Start()
+	defer server.Close()
+
+	job := New(JobConfig{"Contact", "insert", "CSV", "COMMA", ""}, sim.Session(server.URL))
+	content := []byte("FirstName,LastName\nPerson,One\nPerson,Two\n")
+
+	var sent []int
+
+	assert.NoError(t, job.Create())
+	assert.NoError(t, job.UploadWithProgress(content, func(n, total int) {
+		assert.Equal(t, len(content), total)
+		sent = append(sent, n)
+	}))
+
+	assert.NotEmpty(t, sent)
+	assert.Equal(t, len(content), sent[len(sent)-1])
+}
+
+func TestJob_Results(t *testing.T) {
+	sim := jobtest.New()
+	sim.ProcessingTime = 10 * time.Millisecond
+	sim.Rules = []jobtest.Rule{jobtest.FailWhen("LastName", "", "REQUIRED_FIELD_MISSING", "Required fields are missing: [LastName]")}
+
+	server := sim.Start()
+	defer server.Close()
+
+	job := New(JobConfig{"Contact", "insert", "CSV", "COMMA", ""}, sim.Session(server.URL))
+
+	assert.NoError(t, job.Create())
+	assert.NoError(t, job.Upload([]byte("FirstName,LastName\nPerson,One\nPerson,\n")))
+
+	info, err := job.Wait(5 * time.Millisecond)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "JobComplete", info.State)
+
+	success, err := job.GetSuccess()
+
+	assert.NoError(t, err)
+	assert.Equal(t, "sf__Id,sf__Created,FirstName,LastName\n"+sim.Records("Contact")[0]["Id"]+",true,Person,One\n", string(success))
+
+	failure, err := job.GetFailure()
+
+	assert.NoError(t, err)
+	assert.Equal(t, "sf__Id,sf__Error,FirstName,LastName\n,REQUIRED_FIELD_MISSING:Required fields are missing: [LastName],Person,\n", string(failure))
+
+	unprocessed, err := job.GetUnprocessed()
+
+	assert.NoError(t, err)
+	assert.Equal(t, "FirstName,LastName\n", string(unprocessed))
 }
 
 func TestJob_Delete(t *testing.T) {
+	testCases := []struct {
+		status   int
+		expected error
+	}{
+		{204, nil},
+		{404, ErrInvalidJob},
+	}
+
+	for _, tc := range testCases {
+		var actualMethod string
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			actualMethod = r.Method
+
+			if tc.status != 204 {
+				w.Header().Set("X-Request-Id", "REQ123")
+				w.WriteHeader(tc.status)
+				resp, _ := json.Marshal([]JobError{{ErrorCode: "INVALIDJOB", Message: "Job not found"}})
+				w.Write(resp)
+				return
+			}
+
+			w.WriteHeader(tc.status)
+		}))
+
+		job := New(JobConfig{"Contact", "insert", "CSV", "COMMA", ""}, makeSession(server.URL))
+		job.info.ID = "123ID321"
 
+		err := job.Delete()
+
+		assert.Equal(t, "DELETE", actualMethod)
+
+		if tc.expected == nil {
+			assert.NoError(t, err)
+		} else {
+			var reqErr *RequestError
+
+			assert.True(t, errors.Is(err, tc.expected))
+			assert.True(t, errors.As(err, &reqErr))
+			assert.Equal(t, tc.status, reqErr.StatusCode)
+			assert.Equal(t, "REQ123", reqErr.RequestID)
+		}
+
+		server.Close()
+	}
 }
 
 func makeSession(instanceURL string) auth.Session {
@@ -0,0 +1,256 @@
+package jobtest
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"unicode"
+)
+
+// field and object mirror the parts of the describe resource the simulator reports.
+type field struct {
+	Name               string          `json:"name"`
+	Label              string          `json:"label"`
+	Type               string          `json:"type"`
+	Length             int             `json:"length,omitempty"`
+	Nillable           bool            `json:"nillable"`
+	DefaultedOnCreate  bool            `json:"defaultedOnCreate"`
+	Createable         bool            `json:"createable"`
+	Updateable         bool            `json:"updateable"`
+	ExternalID         bool            `json:"externalId"`
+	IDLookup           bool            `json:"idLookup"`
+	RestrictedPicklist bool            `json:"restrictedPicklist"`
+	PicklistValues     []picklistValue `json:"picklistValues"`
+	ReferenceTo        []string        `json:"referenceTo"`
+	RelationshipName   string          `json:"relationshipName,omitempty"`
+}
+
+type picklistValue struct {
+	Value  string `json:"value"`
+	Label  string `json:"label"`
+	Active bool   `json:"active"`
+}
+
+type object struct {
+	Name       string  `json:"name"`
+	Label      string  `json:"label"`
+	KeyPrefix  string  `json:"keyPrefix"`
+	Createable bool    `json:"createable"`
+	Updateable bool    `json:"updateable"`
+	Deletable  bool    `json:"deletable"`
+	Fields     []field `json:"fields"`
+}
+
+func idField() field {
+	return field{Name: "Id", Type: "id", Length: 18, IDLookup: true}
+}
+
+func text(name string, length int, required bool) field {
+	return field{Name: name, Type: "string", Length: length, Nillable: !required, Createable: true, Updateable: true}
+}
+
+func typed(name, fieldType string, required bool) field {
+	return field{Name: name, Type: fieldType, Nillable: !required, Createable: true, Updateable: true}
+}
+
+func externalID() field {
+	f := text("External_Id__c", 255, false)
+	f.ExternalID = true
+	f.IDLookup = true
+
+	return f
+}
+
+func reference(name, relationship string, to ...string) field {
+	f := typed(name, "reference", false)
+	f.Length = 18
+	f.ReferenceTo = to
+	f.RelationshipName = relationship
+
+	return f
+}
+
+func owner() field {
+	f := reference("OwnerId", "Owner", "User")
+	f.Nillable = false
+	f.DefaultedOnCreate = true
+
+	return f
+}
+
+func picklist(name string, required, defaulted bool, values ...string) field {
+	f := typed(name, "picklist", required)
+	f.Length = 255
+	f.DefaultedOnCreate = defaulted
+
+	for _, v := range values {
+		f.PicklistValues = append(f.PicklistValues, picklistValue{Value: v, Label: v, Active: true})
+	}
+
+	return f
+}
+
+// defaultObjects are the objects every simulator knows how to describe.
+func defaultObjects() map[string]object {
+	objects := []object{
+		{Name: "Account", Fields: []field{
+			idField(),
+			text("Name", 255, true),
+			text("AccountNumber", 40, false),
+			picklist("Type", false, false, "Prospect", "Customer", "Partner", "Other"),
+			picklist("Industry", false, false, "Agriculture", "Banking", "Technology", "Other"),
+			typed("Phone", "phone", false),
+			typed("Website", "url", false),
+			typed("AnnualRevenue", "currency", false),
+			typed("NumberOfEmployees", "int", false),
+			reference("ParentId", "Parent", "Account"),
+			reference("RecordTypeId", "RecordType", "RecordType"),
+			owner(),
+			externalID(),
+		}},
+		{Name: "Contact", Fields: []field{
+			idField(),
+			text("FirstName", 40, false),
+			text("LastName", 80, true),
+			typed("Email", "email", false),
+			typed("Phone", "phone", false),
+			typed("Birthdate", "date", false),
+			typed("DoNotCall", "boolean", false),
+			reference("AccountId", "Account", "Account"),
+			reference("ReportsToId", "ReportsTo", "Contact"),
+			owner(),
+			externalID(),
+		}},
+		{Name: "Opportunity", Fields: []field{
+			idField(),
+			text("Name", 120, true),
+			picklist("StageName", true, false, "Prospecting", "Qualification", "Closed Won", "Closed Lost"),
+			typed("CloseDate", "date", true),
+			typed("Amount", "currency", false),
+			reference("AccountId", "Account", "Account"),
+			owner(),
+			externalID(),
+		}},
+		{Name: "Case", Fields: []field{
+			idField(),
+			text("Subject", 255, false),
+			picklist("Status", false, true, "New", "Working", "Escalated", "Closed"),
+			reference("AccountId", "Account", "Account"),
+			reference("ContactId", "Contact", "Contact"),
+			owner(),
+			externalID(),
+		}},
+		{Name: "User", Fields: []field{
+			idField(),
+			text("Username", 80, true),
+			text("LastName", 80, true),
+			typed("Email", "email", true),
+		}},
+		{Name: "RecordType", Fields: []field{
+			idField(),
+			text("Name", 80, true),
+			text("DeveloperName", 80, true),
+			text("SobjectType", 40, true),
+		}},
+	}
+
+	result := map[string]object{}
+
+	for _, o := range objects {
+		for i := range o.Fields {
+			o.Fields[i].Label = label(o.Fields[i].Name)
+		}
+
+		o.Label = o.Name
+		o.KeyPrefix = keyPrefixes[o.Name]
+		o.Createable, o.Updateable, o.Deletable = true, true, true
+		result[o.Name] = o
+	}
+
+	return result
+}
+
+// SetDescribe makes the simulator report describe, which is marshaled to JSON, as the describe of object. It replaces
+// any describe the simulator knows for the object.
+func (s *Simulator) SetDescribe(object string, describe interface{}) error {
+	b, err := json.Marshal(describe)
+
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.describes[object] = b
+
+	return nil
+}
+
+func (s *Simulator) serveDescribe(w http.ResponseWriter, r *http.Request, name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if b, ok := s.describes[name]; ok {
+		w.Header().Set("Content-Type", "application/json;charset=UTF-8")
+		w.WriteHeader(http.StatusOK)
+		w.Write(b)
+		return
+	}
+
+	if o, ok := s.objects[name]; ok {
+		writeJSON(w, http.StatusOK, o)
+		return
+	}
+
+	writeError(w, http.StatusNotFound, "NOT_FOUND", "The requested resource does not exist")
+}
+
+// describe returns the fields of object the simulator knows, if it knows any. The caller must hold s.mu.
+func (s *Simulator) describe(name string) (object, bool) {
+	if b, ok := s.describes[name]; ok {
+		var o object
+		return o, json.Unmarshal(b, &o) == nil
+	}
+
+	o, ok := s.objects[name]
+
+	return o, ok
+}
+
+// has reports whether a column of uploaded data names a field of the object, or a field of a record it refers to
+// through a relationship, such as Account.External_Id__c.
+func (o object) has(column string) bool {
+	if i := strings.Index(column, "."); i >= 0 {
+		for _, f := range o.Fields {
+			if f.RelationshipName != "" && strings.EqualFold(f.RelationshipName, column[:i]) {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	for _, f := range o.Fields {
+		if strings.EqualFold(f.Name, column) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// label turns a field name like "External_Id__c" or "FirstName" into "External Id" or "First Name".
+func label(name string) string {
+	var b strings.Builder
+
+	for i, r := range strings.Replace(strings.TrimSuffix(name, "__c"), "_", " ", -1) {
+		if i > 0 && unicode.IsUpper(r) && !strings.HasSuffix(b.String(), " ") {
+			b.WriteRune(' ')
+		}
+
+		b.WriteRune(r)
+	}
+
+	return b.String()
+}
@@ -0,0 +1,475 @@
+package jobtest
+
+import (
+	"bytes"
+	"encoding/csv"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+)
+
+var delimiters = map[string]rune{
+	"BACKQUOTE": '`',
+	"CARET":     '^',
+	"COMMA":     ',',
+	"PIPE":      '|',
+	"SEMICOLON": ';',
+	"TAB":       '\t',
+}
+
+// jobInfo mirrors the job info resource of Bulk API 2.0.
+type jobInfo struct {
+	APIVersion          float64 `json:"apiVersion"`
+	ColumnDelimiter     string  `json:"columnDelimiter"`
+	ConcurrencyMode     string  `json:"concurrencyMode"`
+	ContentType         string  `json:"contentType"`
+	ContentURL          string  `json:"contentUrl,omitempty"`
+	CreatedByID         string  `json:"createdById"`
+	CreatedDate         string  `json:"createdDate"`
+	ErrorMessage        string  `json:"errorMessage,omitempty"`
+	ExternalIDFieldName string  `json:"externalIdFieldName,omitempty"`
+	ID                  string  `json:"id"`
+	JobType             string  `json:"jobType"`
+	LineEnding          string  `json:"lineEnding"`
+	Object              string  `json:"object,omitempty"`
+	Operation           string  `json:"operation"`
+	Query               string  `json:"query,omitempty"`
+	RecordsFailed       int     `json:"numberRecordsFailed"`
+	RecordsProcessed    int     `json:"numberRecordsProcessed"`
+	Retries             int     `json:"retries"`
+	State               string  `json:"state"`
+	SystemModstamp      string  `json:"systemModstamp"`
+	TotalProcessingTime int     `json:"totalProcessingTime"`
+}
+
+type result struct {
+	row     []string
+	id      string
+	created bool
+	err     *RowError
+}
+
+type ingestJob struct {
+	info    jobInfo
+	data    bytes.Buffer
+	header  []string
+	rows    [][]string
+	results []result
+	doneAt  time.Time
+}
+
+func (s *Simulator) serveIngest(w http.ResponseWriter, r *http.Request, version string, parts []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(parts) == 0 {
+		switch r.Method {
+		case "POST":
+			s.createIngestJob(w, r, version)
+		case "GET":
+			s.listIngestJobs(w)
+		default:
+			writeError(w, http.StatusMethodNotAllowed, "METHOD_NOT_ALLOWED", "HTTP Method '"+r.Method+"' not allowed")
+		}
+
+		return
+	}
+
+	j, ok := s.ingest[parts[0]]
+
+	if !ok {
+		writeError(w, http.StatusNotFound, "INVALIDJOB", "Job not found: "+parts[0])
+		return
+	}
+
+	s.advance(j)
+
+	if len(parts) == 1 {
+		switch r.Method {
+		case "GET":
+			writeJSON(w, http.StatusOK, j.info)
+		case "PATCH":
+			s.setIngestState(w, r, j)
+		case "DELETE":
+			s.deleteIngestJob(w, j)
+		default:
+			writeError(w, http.StatusMethodNotAllowed, "METHOD_NOT_ALLOWED", "HTTP Method '"+r.Method+"' not allowed")
+		}
+
+		return
+	}
+
+	switch {
+	case parts[1] == "batches" && r.Method == "PUT":
+		s.uploadBatch(w, r, j)
+	case parts[1] == "successfulResults" && r.Method == "GET":
+		writeResults(w, j, func(res result) bool { return res.err == nil }, []string{"sf__Id", "sf__Created"}, func(res result) []string {
+			return []string{res.id, boolString(res.created)}
+		})
+	case parts[1] == "failedResults" && r.Method == "GET":
+		writeResults(w, j, func(res result) bool { return res.err != nil }, []string{"sf__Id", "sf__Error"}, func(res result) []string {
+			return []string{res.id, res.err.String()}
+		})
+	case parts[1] == "unprocessedrecords" && r.Method == "GET":
+		s.writeUnprocessed(w, j)
+	default:
+		writeError(w, http.StatusNotFound, "NOT_FOUND", "The requested resource does not exist")
+	}
+}
+
+func (s *Simulator) createIngestJob(w http.ResponseWriter, r *http.Request, version string) {
+	var info jobInfo
+
+	if err := readJSON(r, &info); err != nil {
+		writeError(w, http.StatusBadRequest, "JSON_PARSER_ERROR", err.Error())
+		return
+	}
+
+	if info.Object == "" {
+		writeError(w, http.StatusBadRequest, "INVALIDJOB", "Object must be specified")
+		return
+	}
+
+	switch info.Operation {
+	case "insert", "update", "delete", "hardDelete":
+	case "upsert":
+		if info.ExternalIDFieldName == "" {
+			writeError(w, http.StatusBadRequest, "INVALIDJOB", "External ID field is required for upsert")
+			return
+		}
+	default:
+		writeError(w, http.StatusBadRequest, "INVALIDJOB", "Invalid operation: "+info.Operation)
+		return
+	}
+
+	if info.ColumnDelimiter == "" {
+		info.ColumnDelimiter = "COMMA"
+	}
+
+	if _, ok := delimiters[info.ColumnDelimiter]; !ok {
+		writeError(w, http.StatusBadRequest, "INVALIDJOB", "Invalid column delimiter: "+info.ColumnDelimiter)
+		return
+	}
+
+	if info.LineEnding == "" {
+		info.LineEnding = "LF"
+	}
+
+	s.nextID++
+
+	now := time.Now().UTC().Format("2006-01-02T15:04:05.000+0000")
+
+	info.ID = "750MOCK" + padID(s.nextID)
+	info.APIVersion = parseVersion(version)
+	info.ConcurrencyMode = "Parallel"
+	info.ContentType = "CSV"
+	info.ContentURL = "services/data/v" + version + "/jobs/ingest/" + info.ID + "/batches"
+	info.CreatedByID = "005MOCK00000000AAA"
+	info.CreatedDate = now
+	info.SystemModstamp = now
+	info.JobType = "V2Ingest"
+	info.State = "Open"
+
+	s.ingest[info.ID] = &ingestJob{info: info}
+	s.order = append(s.order, info.ID)
+
+	writeJSON(w, http.StatusOK, info)
+}
+
+func (s *Simulator) listIngestJobs(w http.ResponseWriter) {
+	records := []jobInfo{}
+
+	for _, id := range s.order {
+		if j, ok := s.ingest[id]; ok {
+			s.advance(j)
+			records = append(records, j.info)
+		}
+	}
+
+	writeJSON(w, http.StatusOK, struct {
+		Done           bool      `json:"done"`
+		Records        []jobInfo `json:"records"`
+		NextRecordsURL *string   `json:"nextRecordsUrl"`
+	}{true, records, nil})
+}
+
+func (s *Simulator) uploadBatch(w http.ResponseWriter, r *http.Request, j *ingestJob) {
+	if j.info.State != "Open" {
+		writeError(w, http.StatusConflict, "INVALIDJOBSTATE", "Job is not open for uploads, state: "+j.info.State)
+		return
+	}
+
+	body, err := ioutil.ReadAll(r.Body)
+
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "INVALIDBATCH", err.Error())
+		return
+	}
+
+	j.data.Write(body)
+	w.WriteHeader(http.StatusCreated)
+}
+
+func (s *Simulator) setIngestState(w http.ResponseWriter, r *http.Request, j *ingestJob) {
+	var req struct {
+		State string `json:"state"`
+	}
+
+	if err := readJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, "JSON_PARSER_ERROR", err.Error())
+		return
+	}
+
+	switch {
+	case req.State == "UploadComplete" && j.info.State == "Open":
+		j.info.State = "UploadComplete"
+		j.doneAt = time.Now().Add(s.ProcessingTime)
+		s.process(j)
+	case req.State == "Aborted" && (j.info.State == "Open" || j.info.State == "UploadComplete" || j.info.State == "InProgress"):
+		// rows of an aborted job are never applied, so all of its data is unprocessed
+		j.info.State = "Aborted"
+		j.rows = nil
+	default:
+		writeError(w, http.StatusBadRequest, "INVALIDJOBSTATE", "Cannot change state from "+j.info.State+" to "+req.State)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, j.info)
+}
+
+func (s *Simulator) deleteIngestJob(w http.ResponseWriter, j *ingestJob) {
+	switch j.info.State {
+	case "UploadComplete", "JobComplete", "Aborted", "Failed":
+		delete(s.ingest, j.info.ID)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		writeError(w, http.StatusBadRequest, "INVALIDJOBSTATE", "Cannot delete job in state "+j.info.State)
+	}
+}
+
+// process parses a job's uploaded data, failing the job if it can't be loaded. Its rows are applied to the org when
+// the job completes, unless it's aborted first.
+func (s *Simulator) process(j *ingestJob) {
+	reader := csv.NewReader(bytes.NewReader(j.data.Bytes()))
+	reader.Comma = delimiters[j.info.ColumnDelimiter]
+
+	header, err := reader.Read()
+
+	if err != nil {
+		j.fail("InvalidBatch : Failed to parse CSV header")
+		return
+	}
+
+	j.header = header
+
+	if (j.info.Operation != "insert" && j.info.Operation != "upsert") && indexOf(header, "Id") < 0 {
+		j.fail("InvalidBatch : Field name not found : Id")
+		return
+	}
+
+	if j.info.Operation == "upsert" && indexOf(header, j.info.ExternalIDFieldName) < 0 {
+		j.fail("InvalidBatch : Field name not found : " + j.info.ExternalIDFieldName)
+		return
+	}
+
+	// columns that aren't fields fail the job, as they do in an org, for objects the simulator can describe
+	if o, ok := s.describe(j.info.Object); ok {
+		for _, column := range header {
+			if !o.has(column) {
+				j.fail("InvalidBatch : Field name not found : " + column)
+				return
+			}
+		}
+	}
+
+	for {
+		row, err := reader.Read()
+
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			j.fail("InvalidBatch : " + err.Error())
+			return
+		}
+
+		j.rows = append(j.rows, row)
+	}
+
+	batches := (len(j.rows) + 9999) / 10000
+
+	if batches == 0 {
+		batches = 1
+	}
+
+	s.limits["DailyBulkApiBatches"].Remaining -= batches
+}
+
+func (s *Simulator) apply(info jobInfo, header, row []string) result {
+	record := Record{}
+
+	for i, field := range header {
+		if i < len(row) {
+			record[field] = row[i]
+		}
+	}
+
+	res := result{row: row}
+
+	for _, rule := range s.Rules {
+		if res.err = rule(info.Object, info.Operation, record); res.err != nil {
+			return res
+		}
+	}
+
+	switch info.Operation {
+	case "insert":
+		delete(record, "Id")
+		res.id = s.insert(info.Object, record)
+		res.created = true
+	case "update":
+		existing, _ := s.find(info.Object, "Id", record["Id"])
+
+		if existing == nil {
+			res.err = &RowError{"INVALID_CROSS_REFERENCE_KEY", "invalid cross reference id"}
+			return res
+		}
+
+		merge(existing, record)
+		res.id = existing["Id"]
+	case "upsert":
+		existing, _ := s.find(info.Object, info.ExternalIDFieldName, record[info.ExternalIDFieldName])
+
+		if existing == nil {
+			delete(record, "Id")
+			res.id = s.insert(info.Object, record)
+			res.created = true
+		} else {
+			delete(record, "Id")
+			merge(existing, record)
+			res.id = existing["Id"]
+		}
+	case "delete", "hardDelete":
+		existing, i := s.find(info.Object, "Id", record["Id"])
+
+		if existing == nil {
+			res.err = &RowError{"ENTITY_IS_DELETED", "entity is deleted"}
+			return res
+		}
+
+		s.records[info.Object] = append(s.records[info.Object][:i], s.records[info.Object][i+1:]...)
+		res.id = existing["Id"]
+	}
+
+	return res
+}
+
+// advance moves a processed job to its final state once its processing time has elapsed, applying its rows.
+func (s *Simulator) advance(j *ingestJob) {
+	if j.info.State != "UploadComplete" && j.info.State != "InProgress" {
+		return
+	}
+
+	if time.Now().Before(j.doneAt) {
+		j.info.State = "InProgress"
+		return
+	}
+
+	for _, row := range j.rows {
+		j.results = append(j.results, s.apply(j.info, j.header, row))
+	}
+
+	j.info.State = "JobComplete"
+	j.info.RecordsProcessed = len(j.results)
+	j.info.RecordsFailed = 0
+	j.info.TotalProcessingTime = int(s.ProcessingTime / time.Millisecond)
+
+	for _, res := range j.results {
+		if res.err != nil {
+			j.info.RecordsFailed++
+		}
+	}
+}
+
+func (j *ingestJob) fail(message string) {
+	j.info.State = "Failed"
+	j.info.ErrorMessage = message
+	j.rows = nil
+	j.results = nil
+}
+
+func (s *Simulator) writeUnprocessed(w http.ResponseWriter, j *ingestJob) {
+	var buf bytes.Buffer
+
+	if j.info.State == "JobComplete" {
+		if len(j.header) > 0 {
+			writeCSV(&buf, j.info.ColumnDelimiter, [][]string{j.header})
+		}
+	} else {
+		buf.Write(j.data.Bytes())
+	}
+
+	w.Header().Set("Content-Type", "text/csv")
+	w.WriteHeader(http.StatusOK)
+	w.Write(buf.Bytes())
+}
+
+func writeResults(w http.ResponseWriter, j *ingestJob, include func(result) bool, columns []string, values func(result) []string) {
+	if j.info.State != "JobComplete" {
+		writeError(w, http.StatusBadRequest, "INVALIDJOBSTATE", "Results are not available for a job in state "+j.info.State)
+		return
+	}
+
+	rows := [][]string{append(append([]string{}, columns...), j.header...)}
+
+	for _, res := range j.results {
+		if include(res) {
+			rows = append(rows, append(values(res), res.row...))
+		}
+	}
+
+	var buf bytes.Buffer
+
+	writeCSV(&buf, j.info.ColumnDelimiter, rows)
+
+	w.Header().Set("Content-Type", "text/csv")
+	w.WriteHeader(http.StatusOK)
+	w.Write(buf.Bytes())
+}
+
+func writeCSV(w io.Writer, delim string, rows [][]string) {
+	writer := csv.NewWriter(w)
+
+	if d, ok := delimiters[delim]; ok {
+		writer.Comma = d
+	}
+
+	writer.WriteAll(rows)
+}
+
+func merge(dst, src Record) {
+	for k, v := range src {
+		dst[k] = v
+	}
+}
+
+func indexOf(values []string, value string) int {
+	for i, v := range values {
+		if strings.EqualFold(v, value) {
+			return i
+		}
+	}
+
+	return -1
+}
+
+func boolString(b bool) string {
+	if b {
+		return "true"
+	}
+
+	return "false"
+}
@@ -0,0 +1,424 @@
+package jobtest
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type queryJob struct {
+	info    jobInfo
+	columns []string
+	rows    [][]string
+	doneAt  time.Time
+}
+
+func (s *Simulator) serveQuery(w http.ResponseWriter, r *http.Request, version string, parts []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(parts) == 0 {
+		if r.Method != "POST" {
+			writeError(w, http.StatusMethodNotAllowed, "METHOD_NOT_ALLOWED", "HTTP Method '"+r.Method+"' not allowed")
+			return
+		}
+
+		s.createQueryJob(w, r, version)
+		return
+	}
+
+	j, ok := s.queries[parts[0]]
+
+	if !ok {
+		writeError(w, http.StatusNotFound, "INVALIDJOB", "Job not found: "+parts[0])
+		return
+	}
+
+	s.advanceQuery(j)
+
+	switch {
+	case len(parts) == 1 && r.Method == "GET":
+		writeJSON(w, http.StatusOK, j.info)
+	case len(parts) == 1 && r.Method == "PATCH":
+		if j.info.State != "UploadComplete" && j.info.State != "InProgress" {
+			writeError(w, http.StatusBadRequest, "INVALIDJOBSTATE", "Cannot abort job in state "+j.info.State)
+			return
+		}
+
+		j.info.State = "Aborted"
+		writeJSON(w, http.StatusOK, j.info)
+	case len(parts) == 1 && r.Method == "DELETE":
+		delete(s.queries, j.info.ID)
+		w.WriteHeader(http.StatusNoContent)
+	case len(parts) == 2 && parts[1] == "results" && r.Method == "GET":
+		if j.info.State != "JobComplete" {
+			writeError(w, http.StatusBadRequest, "INVALIDJOBSTATE", "Results are not available for a job in state "+j.info.State)
+			return
+		}
+
+		var buf bytes.Buffer
+
+		writeCSV(&buf, j.info.ColumnDelimiter, append([][]string{j.columns}, j.rows...))
+
+		w.Header().Set("Content-Type", "text/csv")
+		w.Header().Set("Sforce-NumberOfRecords", strconv.Itoa(len(j.rows)))
+		w.Header().Set("Sforce-Locator", "null")
+		w.WriteHeader(http.StatusOK)
+		w.Write(buf.Bytes())
+	default:
+		writeError(w, http.StatusNotFound, "NOT_FOUND", "The requested resource does not exist")
+	}
+}
+
+func (s *Simulator) createQueryJob(w http.ResponseWriter, r *http.Request, version string) {
+	var info jobInfo
+
+	if err := readJSON(r, &info); err != nil {
+		writeError(w, http.StatusBadRequest, "JSON_PARSER_ERROR", err.Error())
+		return
+	}
+
+	if info.Operation != "query" && info.Operation != "queryAll" {
+		writeError(w, http.StatusBadRequest, "INVALIDJOB", "Invalid operation: "+info.Operation)
+		return
+	}
+
+	q, err := parseQuery(info.Query)
+
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "MALFORMED_QUERY", err.Error())
+		return
+	}
+
+	if info.ColumnDelimiter == "" {
+		info.ColumnDelimiter = "COMMA"
+	}
+
+	if info.LineEnding == "" {
+		info.LineEnding = "LF"
+	}
+
+	s.nextID++
+
+	now := time.Now().UTC().Format("2006-01-02T15:04:05.000+0000")
+
+	info.ID = "750MOCK" + padID(s.nextID)
+	info.APIVersion = parseVersion(version)
+	info.ConcurrencyMode = "Parallel"
+	info.ContentType = "CSV"
+	info.CreatedByID = "005MOCK00000000AAA"
+	info.CreatedDate = now
+	info.SystemModstamp = now
+	info.JobType = "V2Query"
+	info.Object = q.object
+	info.State = "UploadComplete"
+
+	j := &queryJob{info: info, columns: q.fields, doneAt: time.Now().Add(s.ProcessingTime)}
+
+	for _, record := range s.records[q.object] {
+		if q.matches(func(field string) string { return s.fieldValue(record, field) }) {
+			row := make([]string, len(q.fields))
+
+			for i, f := range q.fields {
+				row[i] = s.fieldValue(record, f)
+			}
+
+			j.rows = append(j.rows, row)
+		}
+
+		if q.limit > 0 && len(j.rows) == q.limit {
+			break
+		}
+	}
+
+	s.queries[info.ID] = j
+	s.limits["DailyBulkV2QueryJobs"].Remaining--
+
+	writeJSON(w, http.StatusOK, info)
+}
+
+// serveRESTQuery answers a query made through the REST API in a single page of results.
+func (s *Simulator) serveRESTQuery(w http.ResponseWriter, r *http.Request, version string) {
+	if r.Method != "GET" {
+		writeError(w, http.StatusMethodNotAllowed, "METHOD_NOT_ALLOWED", "HTTP Method '"+r.Method+"' not allowed. Allowed are GET")
+		return
+	}
+
+	q, err := parseQuery(r.URL.Query().Get("q"))
+
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "MALFORMED_QUERY", err.Error())
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	records := []map[string]interface{}{}
+
+	for _, record := range s.records[q.object] {
+		if !q.matches(func(field string) string { return s.fieldValue(record, field) }) {
+			continue
+		}
+
+		result := map[string]interface{}{
+			"attributes": map[string]string{
+				"type": q.object,
+				"url":  "/services/data/v" + version + "/sobjects/" + q.object + "/" + record["Id"],
+			},
+		}
+
+		for _, f := range q.fields {
+			nest(result, strings.Split(f, "."), s.fieldValue(record, f))
+		}
+
+		records = append(records, result)
+
+		if q.limit > 0 && len(records) == q.limit {
+			break
+		}
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"totalSize": len(records),
+		"done":      true,
+		"records":   records,
+	})
+}
+
+// nest sets the value of a dotted field path in result the way the REST API nests related records, with null for blank
+// values.
+func nest(result map[string]interface{}, path []string, value string) {
+	if len(path) > 1 {
+		child, ok := result[path[0]].(map[string]interface{})
+
+		if !ok {
+			child = map[string]interface{}{}
+			result[path[0]] = child
+		}
+
+		nest(child, path[1:], value)
+		return
+	}
+
+	if value == "" {
+		result[path[0]] = nil
+	} else {
+		result[path[0]] = value
+	}
+}
+
+func (s *Simulator) advanceQuery(j *queryJob) {
+	if j.info.State != "UploadComplete" && j.info.State != "InProgress" {
+		return
+	}
+
+	if time.Now().Before(j.doneAt) {
+		j.info.State = "InProgress"
+		return
+	}
+
+	j.info.State = "JobComplete"
+	j.info.RecordsProcessed = len(j.rows)
+}
+
+// fieldValue resolves a field, following one level of relationship (e.g. Account.Name) through the record's
+// reference field (AccountId). Must be called with the lock held.
+func (s *Simulator) fieldValue(record Record, field string) string {
+	dot := strings.Index(field, ".")
+
+	if dot < 0 {
+		return lookup(record, field)
+	}
+
+	relationship, rest := field[:dot], field[dot+1:]
+	parentID := lookup(record, relationship+"Id")
+
+	if parentID == "" {
+		parentID = lookup(record, strings.TrimSuffix(relationship, "__r")+"__c")
+	}
+
+	if parentID == "" {
+		return ""
+	}
+
+	for _, records := range s.records {
+		for _, parent := range records {
+			if parent["Id"] == parentID {
+				return s.fieldValue(parent, rest)
+			}
+		}
+	}
+
+	return ""
+}
+
+func lookup(record Record, field string) string {
+	if v, ok := record[field]; ok {
+		return v
+	}
+
+	for k, v := range record {
+		if strings.EqualFold(k, field) {
+			return v
+		}
+	}
+
+	return ""
+}
+
+type condition struct {
+	field  string
+	op     string
+	values []string
+}
+
+type query struct {
+	fields     []string
+	object     string
+	conditions []condition
+	limit      int
+}
+
+var (
+	queryPattern     = regexp.MustCompile(`(?is)^\s*SELECT\s+(.+?)\s+FROM\s+(\w+)(?:\s+WHERE\s+(.+?))?(?:\s+LIMIT\s+(\d+))?\s*$`)
+	conditionPattern = regexp.MustCompile(`(?is)^\s*([\w.]+)\s*(=|!=|\bIN\b)\s*(.+?)\s*$`)
+	andPattern       = regexp.MustCompile(`(?i)\s+AND\s+`)
+)
+
+// parseQuery understands a small subset of SOQL: a field list, an object, conditions using =, != or IN joined by AND,
+// and a LIMIT.
+func parseQuery(soql string) (query, error) {
+	m := queryPattern.FindStringSubmatch(soql)
+
+	if m == nil {
+		return query{}, fmt.Errorf("unsupported query: %s", soql)
+	}
+
+	q := query{object: m[2]}
+
+	for _, f := range strings.Split(m[1], ",") {
+		q.fields = append(q.fields, strings.TrimSpace(f))
+	}
+
+	if m[3] != "" {
+		for _, c := range andPattern.Split(m[3], -1) {
+			cm := conditionPattern.FindStringSubmatch(c)
+
+			if cm == nil {
+				return query{}, fmt.Errorf("unsupported condition: %s", c)
+			}
+
+			cond := condition{field: cm[1], op: strings.ToUpper(cm[2])}
+
+			if cond.op == "IN" {
+				for _, v := range splitValues(strings.Trim(strings.TrimSpace(cm[3]), "()")) {
+					cond.values = append(cond.values, unquote(v))
+				}
+			} else {
+				cond.values = []string{unquote(cm[3])}
+			}
+
+			q.conditions = append(q.conditions, cond)
+		}
+	}
+
+	if m[4] != "" {
+		q.limit, _ = strconv.Atoi(m[4])
+	}
+
+	return q, nil
+}
+
+// matches reports whether a record satisfies every condition, comparing values ignoring case as SOQL does. value
+// resolves a field of the record.
+func (q query) matches(value func(field string) string) bool {
+	for _, c := range q.conditions {
+		actual := value(c.field)
+		found := false
+
+		for _, v := range c.values {
+			if strings.EqualFold(v, actual) {
+				found = true
+				break
+			}
+		}
+
+		if found == (c.op == "!=") {
+			return false
+		}
+	}
+
+	return true
+}
+
+// splitValues splits a comma separated list of values, ignoring commas inside quoted strings.
+func splitValues(list string) []string {
+	var (
+		values  []string
+		current strings.Builder
+	)
+
+	quoted, escaped := false, false
+
+	for _, c := range list {
+		switch {
+		case escaped:
+			escaped = false
+		case c == '\\' && quoted:
+			escaped = true
+		case c == '\'':
+			quoted = !quoted
+		case c == ',' && !quoted:
+			values = append(values, current.String())
+			current.Reset()
+			continue
+		}
+
+		current.WriteRune(c)
+	}
+
+	return append(values, current.String())
+}
+
+// unquote returns the value of a SOQL literal, undoing backslash escapes in strings.
+func unquote(v string) string {
+	v = strings.TrimSpace(v)
+
+	if strings.EqualFold(v, "null") {
+		return ""
+	}
+
+	if len(v) < 2 || v[0] != '\'' || v[len(v)-1] != '\'' {
+		return v
+	}
+
+	var b strings.Builder
+
+	escaped := false
+
+	for _, c := range v[1 : len(v)-1] {
+		if c == '\\' && !escaped {
+			escaped = true
+			continue
+		}
+
+		escaped = false
+		b.WriteRune(c)
+	}
+
+	return b.String()
+}
+
+func padID(n int) string {
+	return fmt.Sprintf("%08dAAA", n)
+}
+
+func parseVersion(version string) float64 {
+	v, _ := strconv.ParseFloat(version, 64)
+	return v
+}
@@ -0,0 +1,458 @@
+// Package jobtest provides an in-memory simulation of the parts of the Salesforce REST API forcedata talks to: the
+// OAuth token endpoint, API versions, org limits, and the Bulk API 2.0 ingest and query job lifecycles. It is meant
+// for tests and for pointing the CLI at a fake org with no network access.
+package jobtest
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"github.com/rfaulhaber/forcedata/auth"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultClientSecret is used to sign sessions when Simulator.ClientSecret is empty.
+	DefaultClientSecret = "mock-client-secret"
+
+	// DefaultVersion is the newest API version the simulator reports when Simulator.Versions is empty.
+	DefaultVersion = "44.0"
+
+	defaultLimit = 15000
+)
+
+// Record is a single sObject record, keyed by field name.
+type Record map[string]string
+
+// Rule decides whether a row is rejected. It is given the job's object and operation and the row keyed by header, and
+// returns nil for rows that should succeed.
+type Rule func(object, operation string, row Record) *RowError
+
+// RowError is the failure reported for a single row, rendered in failed results as "CODE:message".
+type RowError struct {
+	Code    string
+	Message string
+}
+
+func (e *RowError) String() string {
+	return e.Code + ":" + e.Message
+}
+
+// FailWhen returns a rule that rejects rows whose field has the given value.
+func FailWhen(field, value, code, message string) Rule {
+	return func(object, operation string, row Record) *RowError {
+		if row[field] == value {
+			return &RowError{code, message}
+		}
+
+		return nil
+	}
+}
+
+// Fault makes matching requests fail or slow down.
+type Fault struct {
+	// Method and Path restrict which requests the fault applies to. Path matches any request path containing it. Empty
+	// values match everything.
+	Method string
+	Path   string
+
+	// StatusCode, ErrorCode and Message describe the error response. If StatusCode is 0, the request is only delayed.
+	StatusCode int
+	ErrorCode  string
+	Message    string
+
+	// Latency is added to matching requests.
+	Latency time.Duration
+
+	// Times is how many requests the fault applies to. 0 means every matching request.
+	Times int
+}
+
+// Simulator is an http.Handler that behaves like a Salesforce org. Its exported fields should be set before it starts
+// serving requests.
+type Simulator struct {
+	// ClientSecret signs issued sessions. Defaults to DefaultClientSecret.
+	ClientSecret string
+
+	// Users maps usernames to passwords that the token endpoint accepts. If empty, any username and password are
+	// accepted.
+	Users map[string]string
+
+	// Versions lists the API versions the org reports. Defaults to DefaultVersion and a few older versions.
+	Versions []string
+
+	// Latency is added to every request.
+	Latency time.Duration
+
+	// ProcessingTime is how long jobs stay InProgress after their upload is complete.
+	ProcessingTime time.Duration
+
+	// Rules reject individual rows of ingest jobs.
+	Rules []Rule
+
+	mu        sync.Mutex
+	tokens    map[string]bool
+	faults    []*Fault
+	ingest    map[string]*ingestJob
+	queries   map[string]*queryJob
+	order     []string
+	records   map[string][]Record
+	limits    map[string]*limit
+	objects   map[string]object
+	describes map[string][]byte
+	nextID    int
+}
+
+type limit struct {
+	Max       int `json:"Max"`
+	Remaining int `json:"Remaining"`
+}
+
+type apiError struct {
+	Message   string   `json:"message"`
+	ErrorCode string   `json:"errorCode"`
+	Fields    []string `json:"fields,omitempty"`
+}
+
+// New returns a simulator for an empty org.
+func New() *Simulator {
+	return &Simulator{
+		tokens:    map[string]bool{},
+		ingest:    map[string]*ingestJob{},
+		queries:   map[string]*queryJob{},
+		records:   map[string][]Record{},
+		objects:   defaultObjects(),
+		describes: map[string][]byte{},
+		limits: map[string]*limit{
+			"DailyApiRequests":              {defaultLimit, defaultLimit},
+			"DailyBulkApiBatches":           {defaultLimit, defaultLimit},
+			"DailyBulkV2QueryJobs":          {10000, 10000},
+			"DailyBulkV2QueryFileStorageMB": {976562, 976562},
+		},
+	}
+}
+
+// Start serves the simulator on a local test server. Callers should Close the returned server.
+func (s *Simulator) Start() *httptest.Server {
+	return httptest.NewServer(s)
+}
+
+// Session issues a valid session for an org served at instanceURL.
+func (s *Simulator) Session(instanceURL string) auth.Session {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.newSession(instanceURL, "mock@example.com")
+}
+
+// AddFault registers a fault. Faults are checked in the order they were added.
+func (s *Simulator) AddFault(f Fault) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.faults = append(s.faults, &f)
+}
+
+// SetLimit overrides the max and remaining values of an org limit.
+func (s *Simulator) SetLimit(name string, max, remaining int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.limits[name] = &limit{max, remaining}
+}
+
+// Insert adds records to the org, assigning IDs to any without one, and returns their IDs.
+func (s *Simulator) Insert(object string, records ...Record) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ids := make([]string, len(records))
+
+	for i, r := range records {
+		ids[i] = s.insert(object, r)
+	}
+
+	return ids
+}
+
+// Records returns a copy of every record of an object.
+func (s *Simulator) Records(object string) []Record {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	result := make([]Record, len(s.records[object]))
+
+	for i, r := range s.records[object] {
+		result[i] = r.copy()
+	}
+
+	return result
+}
+
+// ServeHTTP routes a request to the simulated endpoint.
+func (s *Simulator) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if s.Latency > 0 {
+		time.Sleep(s.Latency)
+	}
+
+	if fault := s.matchFault(r); fault != nil {
+		if fault.Latency > 0 {
+			time.Sleep(fault.Latency)
+		}
+
+		if fault.StatusCode != 0 {
+			writeError(w, fault.StatusCode, fault.ErrorCode, fault.Message)
+			return
+		}
+	}
+
+	path := strings.TrimSuffix(r.URL.Path, "/")
+
+	if path == "/services/oauth2/token" {
+		s.serveToken(w, r)
+		return
+	}
+
+	if path == "/services/data" {
+		s.serveVersions(w, r)
+		return
+	}
+
+	if !strings.HasPrefix(path, "/services/data/v") {
+		writeError(w, http.StatusNotFound, "NOT_FOUND", "The requested resource does not exist")
+		return
+	}
+
+	if !s.authorized(r) {
+		writeError(w, http.StatusUnauthorized, "INVALID_SESSION_ID", "Session expired or invalid")
+		return
+	}
+
+	parts := strings.Split(strings.TrimPrefix(path, "/services/data/"), "/")
+	version, parts := strings.TrimPrefix(parts[0], "v"), parts[1:]
+
+	s.mu.Lock()
+	s.limits["DailyApiRequests"].Remaining--
+	s.mu.Unlock()
+
+	switch {
+	case len(parts) == 1 && parts[0] == "limits":
+		s.serveLimits(w, r)
+	case len(parts) == 3 && parts[0] == "sobjects" && parts[2] == "describe":
+		s.serveDescribe(w, r, parts[1])
+	case len(parts) >= 2 && parts[0] == "jobs" && parts[1] == "ingest":
+		s.serveIngest(w, r, version, parts[2:])
+	case len(parts) >= 2 && parts[0] == "jobs" && parts[1] == "query":
+		s.serveQuery(w, r, version, parts[2:])
+	case len(parts) == 1 && (parts[0] == "query" || parts[0] == "queryAll"):
+		s.serveRESTQuery(w, r, version)
+	default:
+		writeError(w, http.StatusNotFound, "NOT_FOUND", "The requested resource does not exist")
+	}
+}
+
+func (s *Simulator) matchFault(r *http.Request) *Fault {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, f := range s.faults {
+		if f.Method != "" && f.Method != r.Method {
+			continue
+		}
+
+		if f.Path != "" && !strings.Contains(r.URL.Path, f.Path) {
+			continue
+		}
+
+		if f.Times > 0 {
+			f.Times--
+
+			if f.Times == 0 {
+				s.faults = append(s.faults[:i], s.faults[i+1:]...)
+			}
+		}
+
+		return f
+	}
+
+	return nil
+}
+
+func (s *Simulator) authorized(r *http.Request) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.tokens[strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")]
+}
+
+func (s *Simulator) serveToken(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		writeError(w, http.StatusMethodNotAllowed, "METHOD_NOT_ALLOWED", "HTTP Method '"+r.Method+"' not allowed. Allowed are POST")
+		return
+	}
+
+	q := r.URL.Query()
+	r.ParseForm()
+
+	get := func(key string) string {
+		if v := q.Get(key); v != "" {
+			return v
+		}
+
+		return r.PostForm.Get(key)
+	}
+
+	if get("grant_type") != "password" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "unsupported_grant_type", "error_description": "grant type not supported"})
+		return
+	}
+
+	username, password := get("username"), get("password")
+
+	if len(s.Users) > 0 && (s.Users[username] == "" || s.Users[username] != password) {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant", "error_description": "authentication failure"})
+		return
+	}
+
+	s.mu.Lock()
+	session := s.newSession("http://"+r.Host, username)
+	s.mu.Unlock()
+
+	writeJSON(w, http.StatusOK, session)
+}
+
+func (s *Simulator) serveVersions(w http.ResponseWriter, r *http.Request) {
+	versions := s.Versions
+
+	if len(versions) == 0 {
+		versions = []string{"42.0", "43.0", DefaultVersion}
+	}
+
+	result := make([]auth.Version, len(versions))
+
+	for i, v := range versions {
+		result[i] = auth.Version{Label: "Mock " + v, URL: "/services/data/v" + v, Version: v}
+	}
+
+	writeJSON(w, http.StatusOK, result)
+}
+
+func (s *Simulator) serveLimits(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	writeJSON(w, http.StatusOK, s.limits)
+}
+
+// newSession must be called with the lock held.
+func (s *Simulator) newSession(instanceURL, username string) auth.Session {
+	s.nextID++
+
+	session := auth.Session{
+		AccessToken: fmt.Sprintf("00DMOCK!token%d", s.nextID),
+		InstanceURL: instanceURL,
+		ID:          instanceURL + "/id/00DMOCK000000000AAA/" + username,
+		IssuedAt:    strconv.FormatInt(time.Now().UnixNano()/int64(time.Millisecond), 10),
+	}
+
+	secret := s.ClientSecret
+
+	if secret == "" {
+		secret = DefaultClientSecret
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(session.ID + session.IssuedAt))
+	session.Signature = base64.StdEncoding.EncodeToString(mac.Sum(nil))
+
+	s.tokens[session.AccessToken] = true
+
+	return session
+}
+
+// insert must be called with the lock held.
+func (s *Simulator) insert(object string, r Record) string {
+	record := r.copy()
+
+	if record["Id"] == "" {
+		record["Id"] = s.newID(object)
+	}
+
+	s.records[object] = append(s.records[object], record)
+
+	return record["Id"]
+}
+
+// find must be called with the lock held.
+func (s *Simulator) find(object, field, value string) (Record, int) {
+	for i, r := range s.records[object] {
+		if value != "" && r[field] == value {
+			return r, i
+		}
+	}
+
+	return nil, -1
+}
+
+var keyPrefixes = map[string]string{
+	"Account":     "001",
+	"Contact":     "003",
+	"User":        "005",
+	"Opportunity": "006",
+	"Lead":        "00Q",
+	"Case":        "500",
+	"RecordType":  "012",
+}
+
+// newID must be called with the lock held.
+func (s *Simulator) newID(object string) string {
+	s.nextID++
+
+	prefix, ok := keyPrefixes[object]
+
+	if !ok {
+		prefix = "a00"
+	}
+
+	return fmt.Sprintf("%s%012dAAA", prefix, s.nextID)
+}
+
+func (r Record) copy() Record {
+	c := Record{}
+
+	for k, v := range r {
+		c[k] = v
+	}
+
+	return c
+}
+
+func readJSON(r *http.Request, v interface{}) error {
+	body, err := ioutil.ReadAll(r.Body)
+
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(body, v)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	b, _ := json.Marshal(v)
+
+	w.Header().Set("Content-Type", "application/json;charset=UTF-8")
+	w.WriteHeader(status)
+	w.Write(b)
+}
+
+func writeError(w http.ResponseWriter, status int, code, message string) {
+	writeJSON(w, status, []apiError{{Message: message, ErrorCode: code}})
+}
@@ -0,0 +1,283 @@
+package jobtest
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/rfaulhaber/forcedata/auth"
+	"github.com/rfaulhaber/forcedata/describe"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestSimulator_Token(t *testing.T) {
+	sim := New()
+	sim.Users = map[string]string{"test@example.com": "password"}
+
+	server := sim.Start()
+	defer server.Close()
+
+	creds := auth.Credential{
+		Username:     "test@example.com",
+		Password:     "password",
+		ClientID:     "id",
+		ClientSecret: DefaultClientSecret,
+		URL:          server.URL,
+	}
+
+	session, err := auth.SendAuthRequest(creds)
+
+	assert.NoError(t, err)
+	assert.Equal(t, server.URL, session.InstanceURL)
+	assert.True(t, auth.ValidateSession(session, DefaultClientSecret))
+
+	creds.Password = "wrong"
+
+	resp, err := http.Post(creds.Encode(), "", nil)
+
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
+
+func TestSimulator_Unauthorized(t *testing.T) {
+	sim := New()
+	server := sim.Start()
+	defer server.Close()
+
+	resp, body := do(t, auth.Session{InstanceURL: server.URL, AccessToken: "bogus"}, "GET", "/limits", "")
+
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	assert.Contains(t, body, "INVALID_SESSION_ID")
+}
+
+func TestSimulator_Ingest(t *testing.T) {
+	sim := New()
+	sim.Rules = []Rule{FailWhen("LastName", "", "REQUIRED_FIELD_MISSING", "Required fields are missing: [LastName]")}
+
+	server := sim.Start()
+	defer server.Close()
+
+	session := sim.Session(server.URL)
+
+	resp, body := do(t, session, "POST", "/jobs/ingest/", `{"object":"Contact","operation":"insert","contentType":"CSV"}`)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var info jobInfo
+	json.Unmarshal([]byte(body), &info)
+
+	assert.Equal(t, "Open", info.State)
+
+	resp, _ = do(t, session, "PUT", "/jobs/ingest/"+info.ID+"/batches", "FirstName,LastName\nPerson,One\nNo,\n")
+	assert.Equal(t, http.StatusCreated, resp.StatusCode)
+
+	resp, _ = do(t, session, "PATCH", "/jobs/ingest/"+info.ID, `{"state":"UploadComplete"}`)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	_, body = do(t, session, "GET", "/jobs/ingest/"+info.ID, "")
+	json.Unmarshal([]byte(body), &info)
+
+	assert.Equal(t, "JobComplete", info.State)
+	assert.Equal(t, 2, info.RecordsProcessed)
+	assert.Equal(t, 1, info.RecordsFailed)
+
+	_, body = do(t, session, "GET", "/jobs/ingest/"+info.ID+"/successfulResults", "")
+	records := sim.Records("Contact")
+
+	assert.Len(t, records, 1)
+	assert.Equal(t, "sf__Id,sf__Created,FirstName,LastName\n"+records[0]["Id"]+",true,Person,One\n", body)
+
+	_, body = do(t, session, "GET", "/jobs/ingest/"+info.ID+"/failedResults", "")
+	assert.Equal(t, "sf__Id,sf__Error,FirstName,LastName\n,REQUIRED_FIELD_MISSING:Required fields are missing: [LastName],No,\n", body)
+
+	resp, _ = do(t, session, "DELETE", "/jobs/ingest/"+info.ID, "")
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+}
+
+func TestSimulator_IngestUpsertAndDelete(t *testing.T) {
+	sim := New()
+	server := sim.Start()
+	defer server.Close()
+
+	session := sim.Session(server.URL)
+	ids := sim.Insert("Account", Record{"Name": "Acme", "External_Id__c": "A1"})
+
+	runIngest(t, session, `{"object":"Account","operation":"upsert","externalIdFieldName":"External_Id__c"}`, "External_Id__c,Name\nA1,Acme Corp\nA2,Globex\n")
+
+	records := sim.Records("Account")
+
+	assert.Len(t, records, 2)
+	assert.Equal(t, "Acme Corp", records[0]["Name"])
+	assert.Equal(t, ids[0], records[0]["Id"])
+
+	info := runIngest(t, session, `{"object":"Account","operation":"delete"}`, "Id\n"+ids[0]+"\n001MISSING\n")
+
+	assert.Equal(t, 1, info.RecordsFailed)
+	assert.Len(t, sim.Records("Account"), 1)
+}
+
+func TestSimulator_IngestUnknownField(t *testing.T) {
+	sim := New()
+	server := sim.Start()
+	defer server.Close()
+
+	session := sim.Session(server.URL)
+
+	info := runIngest(t, session, `{"object":"Contact","operation":"insert"}`, "LastName,Legacy Id,Account.External_Id__c\nSmith,C1,A1\n")
+
+	assert.Equal(t, "Failed", info.State)
+	assert.Equal(t, "InvalidBatch : Field name not found : Legacy Id", info.ErrorMessage)
+	assert.Empty(t, sim.Records("Contact"))
+
+	// objects the simulator can't describe take any column
+	info = runIngest(t, session, `{"object":"Widget__c","operation":"insert"}`, "Legacy Id\nW1\n")
+
+	assert.Equal(t, "JobComplete", info.State)
+}
+
+func TestSimulator_ProcessingTime(t *testing.T) {
+	sim := New()
+	sim.ProcessingTime = time.Hour
+
+	server := sim.Start()
+	defer server.Close()
+
+	session := sim.Session(server.URL)
+	info := runIngest(t, session, `{"object":"Account","operation":"insert"}`, "Name\nAcme\n")
+
+	assert.Equal(t, "InProgress", info.State)
+	assert.Empty(t, sim.Records("Account"))
+
+	// aborting a job before it completes leaves every row unprocessed
+	resp, _ := do(t, session, "PATCH", "/jobs/ingest/"+info.ID, `{"state":"Aborted"}`)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	_, body := do(t, session, "GET", "/jobs/ingest/"+info.ID+"/unprocessedrecords", "")
+	assert.Equal(t, "Name\nAcme\n", body)
+	assert.Empty(t, sim.Records("Account"))
+}
+
+func TestSimulator_Query(t *testing.T) {
+	sim := New()
+	server := sim.Start()
+	defer server.Close()
+
+	session := sim.Session(server.URL)
+	ids := sim.Insert("Account", Record{"Name": "Acme"}, Record{"Name": "Globex"})
+	sim.Insert("Contact", Record{"LastName": "One", "AccountId": ids[0]}, Record{"LastName": "Two", "AccountId": ids[1]})
+
+	resp, body := do(t, session, "POST", "/jobs/query", `{"operation":"query","query":"SELECT LastName, Account.Name FROM Contact WHERE Account.Name IN ('Acme', 'Initech')"}`)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var info jobInfo
+	json.Unmarshal([]byte(body), &info)
+
+	resp, body = do(t, session, "GET", "/jobs/query/"+info.ID+"/results", "")
+
+	assert.Equal(t, "1", resp.Header.Get("Sforce-NumberOfRecords"))
+	assert.Equal(t, "LastName,Account.Name\nOne,Acme\n", body)
+
+	resp, body = do(t, session, "POST", "/jobs/query", `{"operation":"query","query":"DELETE FROM Contact"}`)
+
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	assert.Contains(t, body, "MALFORMED_QUERY")
+}
+
+func TestSimulator_Fault(t *testing.T) {
+	sim := New()
+	sim.AddFault(Fault{Method: "POST", Path: "/jobs/ingest", StatusCode: 403, ErrorCode: "REQUEST_LIMIT_EXCEEDED", Message: "TotalRequests Limit exceeded.", Times: 1})
+
+	server := sim.Start()
+	defer server.Close()
+
+	session := sim.Session(server.URL)
+
+	resp, body := do(t, session, "POST", "/jobs/ingest/", `{"object":"Account","operation":"insert"}`)
+
+	assert.Equal(t, 403, resp.StatusCode)
+	assert.Contains(t, body, "REQUEST_LIMIT_EXCEEDED")
+
+	resp, _ = do(t, session, "POST", "/jobs/ingest/", `{"object":"Account","operation":"insert"}`)
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+}
+
+func TestSimulator_Versions(t *testing.T) {
+	sim := New()
+	server := sim.Start()
+	defer server.Close()
+
+	version, err := auth.LatestVersion(auth.Session{InstanceURL: server.URL})
+
+	assert.NoError(t, err)
+	assert.Equal(t, DefaultVersion, version)
+}
+
+func TestSimulator_Describe(t *testing.T) {
+	sim := New()
+	sim.SetDescribe("Widget__c", map[string]interface{}{"name": "Widget__c", "fields": []map[string]string{{"name": "Id", "type": "id"}}})
+
+	server := sim.Start()
+	defer server.Close()
+
+	session := sim.Session(server.URL)
+
+	contact, err := describe.Get(session, "Contact")
+
+	assert.NoError(t, err)
+
+	lastName, ok := contact.Field("LastName")
+
+	assert.True(t, ok)
+	assert.True(t, lastName.Required())
+
+	widget, err := describe.Get(session, "Widget__c")
+
+	assert.NoError(t, err)
+	assert.Len(t, widget.Fields, 1)
+
+	_, err = describe.Get(session, "Nope__c")
+
+	assert.Error(t, err)
+}
+
+func runIngest(t *testing.T, session auth.Session, config string, data string) jobInfo {
+	var info jobInfo
+
+	_, body := do(t, session, "POST", "/jobs/ingest/", config)
+	json.Unmarshal([]byte(body), &info)
+
+	do(t, session, "PUT", "/jobs/ingest/"+info.ID+"/batches", data)
+	do(t, session, "PATCH", "/jobs/ingest/"+info.ID, `{"state":"UploadComplete"}`)
+
+	_, body = do(t, session, "GET", "/jobs/ingest/"+info.ID, "")
+	json.Unmarshal([]byte(body), &info)
+
+	return info
+}
+
+func do(t *testing.T, session auth.Session, method, path, body string) (*http.Response, string) {
+	u, _ := url.Parse(session.DataURL() + path)
+
+	req, err := http.NewRequest(method, u.String(), bytes.NewReader([]byte(body)))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req.Header.Set("Authorization", "Bearer "+session.AccessToken)
+
+	resp, err := http.DefaultClient.Do(req)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer resp.Body.Close()
+
+	b, _ := ioutil.ReadAll(resp.Body)
+
+	return resp, string(b)
+}
@@ -0,0 +1,189 @@
+// Package journal keeps a record on disk of what a load has sent to the org, so that a load that was interrupted can be
+// resumed without sending records twice, and a file that was already loaded isn't loaded again by mistake.
+package journal
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"github.com/pkg/errors"
+	"github.com/rfaulhaber/forcedata/job"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// StatePending is the state of a chunk no job has been created for. Chunks with a job take the job's state.
+const StatePending = "Pending"
+
+// Journal records a load of one file into one object, split into chunks of records that are each loaded by a job.
+type Journal struct {
+	// File is the path of the file loaded.
+	File string `json:"file"`
+
+	// Hash identifies the file's contents as read.
+	Hash string `json:"hash"`
+
+	// ContentHash identifies the content uploaded, once the file was prepared for loading. A resumed load must prepare
+	// the same content for its chunks to line up.
+	ContentHash string `json:"contentHash"`
+
+	// Org identifies the org the file is loaded into, whose jobs the chunks' job IDs are.
+	Org string `json:"org"`
+
+	Config  job.JobConfig `json:"config"`
+	Created time.Time     `json:"created"`
+	Updated time.Time     `json:"updated"`
+	Chunks  []*Chunk      `json:"chunks"`
+
+	// Stopped is why the load was stopped by its Runner's Stop, if it was. Loading the rest of it would go against
+	// what stopped it.
+	Stopped string `json:"stopped,omitempty"`
+
+	path string
+}
+
+// Chunk is a range of records loaded by a single job.
+type Chunk struct {
+	// Start and End are the first record of the chunk and the one after its last, counting from zero after the header.
+	Start int `json:"start"`
+	End   int `json:"end"`
+
+	JobID string `json:"jobId,omitempty"`
+	State string `json:"state"`
+
+	Processed uint `json:"processed,omitempty"`
+	Failed    uint `json:"failed,omitempty"`
+
+	// FailedResults is the file the job's failed records were saved to, once they've been retrieved.
+	FailedResults string `json:"failedResults,omitempty"`
+
+	// Replaced lists the jobs that loaded the chunk before, which didn't finish.
+	Replaced []string `json:"replaced,omitempty"`
+}
+
+// Hash returns the hash of content that identifies it in journals.
+func Hash(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+// Path returns the path of the journal of loading content with config into org, in dir. The same file loaded into
+// different orgs has a journal for each.
+func Path(dir, org string, content []byte, config job.JobConfig) string {
+	name := strings.Join([]string{config.Object, config.Operation, Hash(append([]byte(org+"\n"), content...))[:16]}, "-")
+	return filepath.Join(dir, name+".json")
+}
+
+// New returns a journal for loading file, whose contents as read are content, that will be saved at path.
+func New(path, file string, content []byte, config job.JobConfig) *Journal {
+	now := time.Now().UTC()
+
+	return &Journal{
+		File:    file,
+		Hash:    Hash(content),
+		Config:  config,
+		Created: now,
+		Updated: now,
+		path:    path,
+	}
+}
+
+// Load reads the journal at path. It returns nil and no error if there isn't one.
+func Load(path string) (*Journal, error) {
+	b, err := ioutil.ReadFile(path)
+
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, errors.Wrap(err, "could not read journal")
+	}
+
+	var j Journal
+
+	if err := json.Unmarshal(b, &j); err != nil {
+		return nil, errors.Wrap(err, "could not parse journal "+path)
+	}
+
+	j.path = path
+
+	return &j, nil
+}
+
+// Split divides records into chunks of at most size records, each pending. A size of zero or less makes one chunk.
+func (j *Journal) Split(records, size int) {
+	if size <= 0 || size > records {
+		size = records
+	}
+
+	j.Chunks = nil
+
+	for start := 0; ; {
+		end := start + size
+
+		if end > records {
+			end = records
+		}
+
+		j.Chunks = append(j.Chunks, &Chunk{Start: start, End: end, State: StatePending})
+
+		if end >= records {
+			return
+		}
+
+		start = end
+	}
+}
+
+// Save writes the journal to its path, replacing the previous version only once the new one is written.
+func (j *Journal) Save() error {
+	j.Updated = time.Now().UTC()
+
+	b, err := json.MarshalIndent(j, "", "  ")
+
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(j.path), 0700); err != nil {
+		return errors.Wrap(err, "could not create journal directory")
+	}
+
+	tmp := j.path + ".tmp"
+
+	if err := ioutil.WriteFile(tmp, b, 0600); err != nil {
+		return errors.Wrap(err, "could not write journal")
+	}
+
+	return errors.Wrap(os.Rename(tmp, j.path), "could not write journal")
+}
+
+// Path returns where the journal is saved.
+func (j *Journal) Path() string {
+	return j.path
+}
+
+// Submitted reports whether every chunk has been uploaded to a job that hasn't failed or been aborted.
+func (j *Journal) Submitted() bool {
+	for _, c := range j.Chunks {
+		if !c.Submitted() {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Submitted reports whether the chunk has been uploaded to a job that hasn't failed or been aborted.
+func (c *Chunk) Submitted() bool {
+	switch c.State {
+	case "UploadComplete", "InProgress", "JobComplete":
+		return true
+	default:
+		return false
+	}
+}
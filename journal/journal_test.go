@@ -0,0 +1,234 @@
+package journal
+
+import (
+	"github.com/pkg/errors"
+	"github.com/rfaulhaber/forcedata/job"
+	"github.com/rfaulhaber/forcedata/jobtest"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+var testConfig = job.JobConfig{Object: "Account", Operation: "insert", ContentType: "CSV", Delim: "COMMA"}
+
+const testContent = "Name,NumberOfEmployees\nAcme,1\nGlobex,2\nInitech,3\nHooli,4\nUmbrella,5\n"
+
+func TestSplit(t *testing.T) {
+	j := &Journal{}
+
+	j.Split(5, 2)
+	assert.Equal(t, []*Chunk{
+		{Start: 0, End: 2, State: StatePending},
+		{Start: 2, End: 4, State: StatePending},
+		{Start: 4, End: 5, State: StatePending},
+	}, j.Chunks)
+
+	j.Split(5, 0)
+	assert.Equal(t, []*Chunk{{Start: 0, End: 5, State: StatePending}}, j.Chunks)
+
+	j.Split(0, 10)
+	assert.Equal(t, []*Chunk{{Start: 0, End: 0, State: StatePending}}, j.Chunks)
+}
+
+func TestSaveLoad(t *testing.T) {
+	dir, err := ioutil.TempDir("", "journal")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := Path(filepath.Join(dir, "journal"), "00DA", []byte(testContent), testConfig)
+	assert.Regexp(t, `/Account-insert-[0-9a-f]{16}\.json$`, path)
+	assert.NotEqual(t, path, Path(filepath.Join(dir, "journal"), "00DB", []byte(testContent), testConfig))
+
+	missing, err := Load(path)
+	assert.NoError(t, err)
+	assert.Nil(t, missing)
+
+	j := New(path, "accounts.csv", []byte(testContent), testConfig)
+	j.Split(5, 3)
+	j.Chunks[0].JobID, j.Chunks[0].State = "750A", "JobComplete"
+
+	assert.NoError(t, j.Save())
+
+	loaded, err := Load(path)
+	assert.NoError(t, err)
+	assert.Equal(t, j.Hash, loaded.Hash)
+	assert.Equal(t, testConfig, loaded.Config)
+	assert.Equal(t, j.Chunks, loaded.Chunks)
+	assert.Equal(t, path, loaded.Path())
+	assert.False(t, loaded.Submitted())
+}
+
+func TestRunner_Resume(t *testing.T) {
+	sim := jobtest.New()
+	server := sim.Start()
+	defer server.Close()
+
+	sim.Rules = append(sim.Rules, func(object, operation string, record jobtest.Record) *jobtest.RowError {
+		if record["Name"] == "Hooli" {
+			return &jobtest.RowError{Code: "FIELD_CUSTOM_VALIDATION_EXCEPTION", Message: "no"}
+		}
+
+		return nil
+	})
+
+	dir, err := ioutil.TempDir("", "journal")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	session := sim.Session(server.URL)
+
+	j := New(Path(dir, "00DA", []byte(testContent), testConfig), "accounts.csv", []byte(testContent), testConfig)
+	j.Split(5, 2)
+
+	// the first chunk was loaded, and the second chunk's job was created before the load was interrupted
+	done := job.New(testConfig, session)
+	assert.NoError(t, done.Create())
+	assert.NoError(t, done.Upload([]byte("Name,NumberOfEmployees\nAcme,1\nGlobex,2\n")))
+
+	open := job.New(testConfig, session)
+	assert.NoError(t, open.Create())
+
+	j.Chunks[0].JobID, j.Chunks[0].State = done.ID(), "UploadComplete"
+	j.Chunks[1].JobID, j.Chunks[1].State = open.ID(), "Open"
+
+	var (
+		logs     []string
+		states   []string
+		uploaded []int
+	)
+
+	r := Runner{
+		Session: session,
+		Journal: j,
+		Content: []byte(testContent),
+		Comma:   ',',
+		Wait:    time.Millisecond,
+		Log:     func(format string, args ...interface{}) { logs = append(logs, format) },
+		Progress: func(n int, c *Chunk) {
+			if n == 3 {
+				states = append(states, c.State)
+			}
+		},
+		Uploading: func(n int, sent, total int) {
+			if sent == total {
+				uploaded = append(uploaded, n)
+			}
+		},
+	}
+
+	assert.NoError(t, r.Run())
+
+	var names []string
+
+	for _, record := range sim.Records("Account") {
+		names = append(names, record["Name"])
+	}
+
+	assert.Equal(t, []string{"Acme", "Globex", "Initech", "Umbrella"}, names)
+
+	assert.Equal(t, done.ID(), j.Chunks[0].JobID)
+	assert.Equal(t, []string{open.ID()}, j.Chunks[1].Replaced)
+	assert.NotEqual(t, open.ID(), j.Chunks[1].JobID)
+	assert.True(t, j.Submitted())
+	assert.Equal(t, []int{2, 3}, uploaded)
+
+	info, err := open.GetInfo()
+	assert.NoError(t, err)
+	assert.Equal(t, "Aborted", info.State)
+
+	assert.Equal(t, uint(1), j.Chunks[1].Failed)
+	failed, err := ioutil.ReadFile(j.Chunks[1].FailedResults)
+	assert.NoError(t, err)
+	assert.Contains(t, string(failed), "Hooli")
+
+	loaded, err := Load(j.Path())
+	assert.NoError(t, err)
+	assert.Equal(t, j.Chunks, loaded.Chunks)
+
+	assert.Equal(t, []string{"Open", "UploadComplete"}, states[:2])
+	assert.Equal(t, "JobComplete", states[len(states)-1])
+	assert.Contains(t, logs, "chunk %d: reattached to job %s")
+	assert.Contains(t, logs, "chunk %d: job %s was never closed, loading the chunk again")
+}
+
+func TestRunner_Stop(t *testing.T) {
+	sim := jobtest.New()
+	sim.ProcessingTime = time.Hour
+	server := sim.Start()
+	defer server.Close()
+
+	dir, err := ioutil.TempDir("", "journal")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	j := New(Path(dir, "00DA", []byte(testContent), testConfig), "accounts.csv", []byte(testContent), testConfig)
+	j.Split(5, 3)
+
+	r := Runner{
+		Session: sim.Session(server.URL),
+		Journal: j,
+		Content: []byte(testContent),
+		Comma:   ',',
+		Wait:    time.Millisecond,
+		Stop:    func() error { return errors.New("too many records failed") },
+	}
+
+	assert.EqualError(t, r.Run(), "chunk 1: too many records failed")
+	assert.Equal(t, "Aborted", j.Chunks[0].State)
+	assert.Equal(t, "", j.Chunks[1].JobID)
+
+	loaded, err := Load(j.Path())
+	assert.NoError(t, err)
+	assert.Equal(t, "Aborted", loaded.Chunks[0].State)
+	assert.Equal(t, "too many records failed", loaded.Stopped)
+}
+
+func TestRunner_Payloads(t *testing.T) {
+	j := New("journal.json", "accounts.csv", []byte(testContent), testConfig)
+	j.Split(5, 3)
+
+	r := Runner{Journal: j, Content: []byte(testContent), Comma: ','}
+
+	payloads, err := r.Payloads()
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		"Name,NumberOfEmployees\nAcme,1\nGlobex,2\nInitech,3\n",
+		"Name,NumberOfEmployees\nHooli,4\nUmbrella,5\n",
+	}, []string{string(payloads[0]), string(payloads[1])})
+}
+
+func TestRunner_ContentChanged(t *testing.T) {
+	j := New("journal.json", "accounts.csv", []byte(testContent), testConfig)
+	j.Split(4, 2)
+
+	r := Runner{Journal: j, Content: []byte(testContent), Comma: ','}
+
+	assert.EqualError(t, r.Run(), "journal has 4 records but the content has 5")
+}
+
+func TestRead(t *testing.T) {
+	header, records, err := Read([]byte("Name,Description\nAcme,5\" pipe\nGlobex\n"), ',')
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Name", "Description"}, header)
+	assert.Equal(t, [][]string{{"Acme", "5\" pipe"}, {"Globex"}}, records)
+
+	_, _, err = Read(nil, ',')
+	assert.EqualError(t, err, "content is empty")
+}
+
+func TestRunner_Records(t *testing.T) {
+	j := New("journal.json", "accounts.csv", []byte(testContent), testConfig)
+	j.Split(1, 0)
+
+	r := Runner{Journal: j, Content: []byte(testContent), Comma: ',', Header: []string{"Name"}, Records: [][]string{{"Acme"}}}
+
+	payloads, err := r.Payloads()
+
+	assert.NoError(t, err)
+	assert.Equal(t, "Name\nAcme\n", string(payloads[0]))
+}
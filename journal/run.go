@@ -0,0 +1,380 @@
+package journal
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"github.com/pkg/errors"
+	"github.com/rfaulhaber/forcedata/auth"
+	"github.com/rfaulhaber/forcedata/job"
+	"io/ioutil"
+	"strings"
+	"time"
+)
+
+// Runner loads the chunks of a journal, saving the journal as each job is created, uploaded and finished.
+type Runner struct {
+	Session auth.Session
+	Journal *Journal
+
+	// Content is the prepared content of the file, whose records are split into the journal's chunks.
+	Content []byte
+	Comma   rune
+
+	// Header and Records, if Records is set, are Content as returned by Read, so that it isn't read again.
+	Header  []string
+	Records [][]string
+
+	// Wait, if more than zero, is how often each chunk's job is checked until it finishes before the next chunk is
+	// loaded. Otherwise chunks are only uploaded.
+	Wait time.Duration
+
+	// Log, if set, is called as chunks are loaded.
+	Log func(format string, args ...interface{})
+
+	// Progress, if set, is called with a chunk and its 1-based number whenever its job is created, uploaded or
+	// checked.
+	Progress func(n int, c *Chunk)
+
+	// Uploading, if set, is called with a chunk's 1-based number, the bytes of its content sent so far and their total
+	// as the content is uploaded to its job.
+	Uploading func(n int, sent, total int)
+
+	// Stop, if set, is called each time a running job is checked. If it returns an error the job is aborted, unless
+	// it has already finished, and Run stops with the error without loading the chunks after it, recording it as the
+	// journal's Stopped.
+	Stop func() error
+}
+
+// Run loads every chunk that hasn't been submitted. A chunk whose job was created but never closed is loaded again by
+// a new job, after aborting the old one; a chunk whose job failed or was aborted is loaded again with the records
+// that job didn't process. Chunks whose jobs are running or complete are left alone, apart from waiting for them.
+func (r *Runner) Run() error {
+	header, records, err := r.read()
+
+	if err != nil {
+		return err
+	}
+
+	for i, c := range r.Journal.Chunks {
+		if err := r.runChunk(i+1, c, header, records); err != nil {
+			return errors.Wrapf(err, "chunk %d", i+1)
+		}
+	}
+
+	failed := 0
+
+	for _, c := range r.Journal.Chunks {
+		if !c.Submitted() {
+			failed++
+		}
+	}
+
+	if failed > 0 {
+		return errors.Errorf("%d of %d chunks did not load", failed, len(r.Journal.Chunks))
+	}
+
+	return nil
+}
+
+// Payloads returns the content each chunk's job would be uploaded, without loading anything.
+func (r *Runner) Payloads() ([][]byte, error) {
+	header, records, err := r.read()
+
+	if err != nil {
+		return nil, err
+	}
+
+	payloads := make([][]byte, len(r.Journal.Chunks))
+
+	for i, c := range r.Journal.Chunks {
+		if payloads[i], err = r.write(header, records[c.Start:c.End]); err != nil {
+			return nil, err
+		}
+	}
+
+	return payloads, nil
+}
+
+func (r *Runner) runChunk(n int, c *Chunk, header []string, records [][]string) error {
+	var content []byte
+
+	if c.JobID != "" {
+		j := r.job(c.JobID)
+		info, err := j.GetInfo()
+
+		if err != nil {
+			return errors.Wrap(err, "could not check job "+c.JobID)
+		}
+
+		if err := r.record(n, c, j, info); err != nil {
+			return err
+		}
+
+		switch info.State {
+		case "Open":
+			r.log("chunk %d: job %s was never closed, loading the chunk again", n, c.JobID)
+
+			if err := j.Abort(); err != nil {
+				return errors.Wrap(err, "could not abort job "+c.JobID)
+			}
+		case "Failed", "Aborted":
+			unprocessed, err := j.GetUnprocessed()
+
+			if err != nil {
+				return err
+			}
+
+			if r.count(unprocessed) == 0 {
+				r.log("chunk %d: job %s %s with no records left to load", n, c.JobID, strings.ToLower(info.State))
+				return nil
+			}
+
+			r.log("chunk %d: job %s %s, loading its unprocessed records again", n, c.JobID, strings.ToLower(info.State))
+			content = unprocessed
+		default:
+			r.log("chunk %d: reattached to job %s", n, c.JobID)
+			return r.wait(n, c, j)
+		}
+
+		c.Replaced = append(c.Replaced, c.JobID)
+		c.JobID, c.State = "", StatePending
+
+		if err := r.Journal.Save(); err != nil {
+			return err
+		}
+	}
+
+	if content == nil {
+		var err error
+
+		if content, err = r.write(header, records[c.Start:c.End]); err != nil {
+			return err
+		}
+	}
+
+	j := job.New(r.Journal.Config, r.Session)
+
+	if err := j.Create(); err != nil {
+		return errors.Wrap(err, "could not create job")
+	}
+
+	c.JobID, c.State = j.ID(), "Open"
+
+	if err := r.Journal.Save(); err != nil {
+		return err
+	}
+
+	r.progress(n, c)
+
+	var uploading func(sent, total int)
+
+	if r.Uploading != nil {
+		uploading = func(sent, total int) { r.Uploading(n, sent, total) }
+	}
+
+	if err := j.UploadWithProgress(content, uploading); err != nil {
+		return errors.Wrap(err, "could not upload content to job")
+	}
+
+	c.State = "UploadComplete"
+
+	if err := r.Journal.Save(); err != nil {
+		return err
+	}
+
+	r.progress(n, c)
+
+	r.log("chunk %d: records %d to %d uploaded to job %s", n, c.Start+1, c.End, c.JobID)
+
+	return r.wait(n, c, j)
+}
+
+func (r *Runner) wait(n int, c *Chunk, j *job.Job) error {
+	if r.Wait <= 0 || c.State == "JobComplete" {
+		return nil
+	}
+
+	for {
+		info, err := j.GetInfo()
+
+		if err != nil {
+			return errors.Wrap(err, "could not check job "+c.JobID)
+		}
+
+		if err := r.record(n, c, j, info); err != nil {
+			return err
+		}
+
+		finished := false
+
+		switch info.State {
+		case "JobComplete", "Failed", "Aborted":
+			r.log("chunk %d: job %s %s, %d processed, %d failed", n, c.JobID, info.State, info.RecordsProcessed, info.RecordsFailed)
+			finished = true
+		}
+
+		if err := r.stop(n, c, j, finished); err != nil {
+			return err
+		}
+
+		if finished {
+			return nil
+		}
+
+		time.Sleep(r.Wait)
+	}
+}
+
+// stop calls Stop, aborting the chunk's job if it returns an error and the job is still running.
+func (r *Runner) stop(n int, c *Chunk, j *job.Job, finished bool) error {
+	if r.Stop == nil {
+		return nil
+	}
+
+	err := r.Stop()
+
+	if err == nil {
+		return nil
+	}
+
+	r.Journal.Stopped = err.Error()
+
+	if saveErr := r.Journal.Save(); saveErr != nil {
+		return saveErr
+	}
+
+	if finished {
+		return err
+	}
+
+	r.log("chunk %d: aborting job %s: %s", n, c.JobID, err)
+
+	if abortErr := j.Abort(); abortErr != nil {
+		return errors.Wrapf(abortErr, "could not abort job %s after %s", c.JobID, err)
+	}
+
+	info, infoErr := j.GetInfo()
+
+	if infoErr != nil {
+		return errors.Wrapf(infoErr, "could not check job %s after aborting it", c.JobID)
+	}
+
+	if recordErr := r.record(n, c, j, info); recordErr != nil {
+		return recordErr
+	}
+
+	return err
+}
+
+// record saves the state of a chunk's job, and its failed records once it's complete.
+func (r *Runner) record(n int, c *Chunk, j *job.Job, info job.JobInfo) error {
+	c.State = info.State
+	c.Processed, c.Failed = info.RecordsProcessed, info.RecordsFailed
+
+	if c.State == "JobComplete" && c.Failed > 0 && c.FailedResults == "" {
+		failed, err := j.GetFailure()
+
+		if err != nil {
+			return err
+		}
+
+		path := strings.TrimSuffix(r.Journal.Path(), ".json") + fmt.Sprintf("-%d.failed.csv", n)
+
+		if err := ioutil.WriteFile(path, failed, 0600); err != nil {
+			return errors.Wrap(err, "could not save failed results")
+		}
+
+		c.FailedResults = path
+	}
+
+	if err := r.Journal.Save(); err != nil {
+		return err
+	}
+
+	r.progress(n, c)
+
+	return nil
+}
+
+func (r *Runner) job(id string) *job.Job {
+	j := job.New(r.Journal.Config, r.Session)
+	j.SetInfo(job.JobInfo{ID: id})
+
+	return j
+}
+
+func (r *Runner) read() ([]string, [][]string, error) {
+	header, records := r.Header, r.Records
+
+	if records == nil {
+		var err error
+
+		if header, records, err = Read(r.Content, r.Comma); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	last := r.Journal.Chunks[len(r.Journal.Chunks)-1]
+
+	if last.End != len(records) {
+		return nil, nil, errors.Errorf("journal has %d records but the content has %d", last.End, len(records))
+	}
+
+	return header, records, nil
+}
+
+// count returns the number of records in CSV content, not counting the header.
+func (r *Runner) count(content []byte) int {
+	_, records, _ := Read(content, r.Comma)
+
+	return len(records)
+}
+
+// Read returns the header and records of CSV content delimited by comma, as a load splits them into chunks. Quotes
+// are read leniently and records may have any number of fields, as the content is loaded however the org takes it.
+func Read(content []byte, comma rune) ([]string, [][]string, error) {
+	reader := csv.NewReader(bytes.NewReader(content))
+	reader.Comma = comma
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+
+	records, err := reader.ReadAll()
+
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "could not read content")
+	}
+
+	if len(records) == 0 {
+		return nil, nil, errors.New("content is empty")
+	}
+
+	return records[0], records[1:], nil
+}
+
+func (r *Runner) write(header []string, records [][]string) ([]byte, error) {
+	var buf bytes.Buffer
+
+	w := csv.NewWriter(&buf)
+	w.Comma = r.Comma
+	w.Write(header)
+	w.WriteAll(records)
+
+	if err := w.Error(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func (r *Runner) progress(n int, c *Chunk) {
+	if r.Progress != nil {
+		r.Progress(n, c)
+	}
+}
+
+func (r *Runner) log(format string, args ...interface{}) {
+	if r.Log != nil {
+		r.Log(format, args...)
+	}
+}
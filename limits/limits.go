@@ -0,0 +1,165 @@
+package limits
+
+import (
+	"encoding/json"
+	"github.com/pkg/errors"
+	"github.com/rfaulhaber/forcedata/auth"
+	"github.com/rfaulhaber/forcedata/job"
+	"io/ioutil"
+	"net/http"
+	"sort"
+)
+
+// Names of the org limits forcedata cares about.
+const (
+	DailyAPIRequests              = "DailyApiRequests"
+	DailyBulkAPIBatches           = "DailyBulkApiBatches"
+	DailyBulkV2QueryJobs          = "DailyBulkV2QueryJobs"
+	DailyBulkV2QueryFileStorageMB = "DailyBulkV2QueryFileStorageMB"
+)
+
+// Relevant lists the limits reported by `data limits`, in display order.
+var Relevant = []string{
+	DailyBulkAPIBatches,
+	DailyBulkV2QueryJobs,
+	DailyBulkV2QueryFileStorageMB,
+	DailyAPIRequests,
+}
+
+const (
+	// RecordsPerBatch is how many records Salesforce puts in each batch of a Bulk API 2.0 ingest job. Every batch
+	// counts against DailyBulkApiBatches.
+	RecordsPerBatch = 10000
+
+	// requestsPerJob is how many API requests it takes to create, upload to, and close an ingest job.
+	requestsPerJob = 3
+)
+
+// Limit is a single org limit.
+type Limit struct {
+	Max       int `json:"Max"`
+	Remaining int `json:"Remaining"`
+}
+
+// Used returns how much of the limit has been consumed.
+func (l Limit) Used() int {
+	return l.Max - l.Remaining
+}
+
+// Limits maps limit names to their values, as returned by the limits resource.
+type Limits map[string]Limit
+
+// Usage maps limit names to an amount consumed or expected to be consumed.
+type Usage map[string]int
+
+// Problem describes a limit a load would exceed or come close to exceeding.
+type Problem struct {
+	Name      string
+	Needed    int
+	Remaining int
+	Max       int
+
+	// Exceeded is true if the load needs more than what remains, as opposed to only leaving little room.
+	Exceeded bool
+}
+
+// Get retrieves the org's current limits.
+func Get(session auth.Session) (Limits, error) {
+	req, err := http.NewRequest("GET", session.DataURL()+"/limits", nil)
+
+	if err != nil {
+		return nil, errors.Wrap(err, "could not generate limits request")
+	}
+
+	req.Header.Add("Accept", "application/json")
+	req.Header.Add("Authorization", "Bearer "+session.AccessToken)
+
+	resp, err := http.DefaultClient.Do(req)
+
+	if err != nil {
+		return nil, errors.Wrap(err, "limits request failed")
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.Wrap(job.NewRequestError(resp), "limits")
+	}
+
+	body, err := ioutil.ReadAll(resp.Body)
+
+	if err != nil {
+		return nil, errors.Wrap(err, "could not read limits response")
+	}
+
+	var limits Limits
+
+	if err := json.Unmarshal(body, &limits); err != nil {
+		return nil, errors.Wrap(err, "could not parse limits response")
+	}
+
+	return limits, nil
+}
+
+// IngestUsage estimates what loading the given number of records across the given number of jobs will consume.
+func IngestUsage(records int, jobs int) Usage {
+	batches := 0
+
+	if records > 0 {
+		batches = (records + RecordsPerBatch - 1) / RecordsPerBatch
+	}
+
+	if batches < jobs {
+		batches = jobs
+	}
+
+	return Usage{
+		DailyBulkAPIBatches: batches,
+		DailyAPIRequests:    jobs * requestsPerJob,
+	}
+}
+
+// Check compares usage against what remains of each limit. A limit is reported if the usage exceeds what remains, or
+// if it would leave less than the warn fraction (e.g. 0.1 for 10%) of the limit's max.
+func (l Limits) Check(usage Usage, warn float64) []Problem {
+	var problems []Problem
+
+	for name, needed := range usage {
+		limit, ok := l[name]
+
+		if !ok || needed == 0 {
+			continue
+		}
+
+		left := limit.Remaining - needed
+
+		if left < 0 || float64(left) < warn*float64(limit.Max) {
+			problems = append(problems, Problem{
+				Name:      name,
+				Needed:    needed,
+				Remaining: limit.Remaining,
+				Max:       limit.Max,
+				Exceeded:  left < 0,
+			})
+		}
+	}
+
+	sort.Slice(problems, func(i, j int) bool {
+		return problems[i].Name < problems[j].Name
+	})
+
+	return problems
+}
+
+// Consumed returns how much of each limit was used between two snapshots.
+func Consumed(before, after Limits) Usage {
+	usage := Usage{}
+
+	for name, b := range before {
+		if a, ok := after[name]; ok && b.Remaining != a.Remaining {
+			usage[name] = b.Remaining - a.Remaining
+		}
+	}
+
+	return usage
+}
@@ -0,0 +1,90 @@
+package limits
+
+import (
+	"errors"
+	"github.com/rfaulhaber/forcedata/auth"
+	"github.com/rfaulhaber/forcedata/job"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestGet(t *testing.T) {
+	var actualPath string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		actualPath = r.URL.Path
+
+		w.Write([]byte(`{
+			"DailyApiRequests": {"Max": 15000, "Remaining": 14998},
+			"DailyBulkApiBatches": {"Max": 15000, "Remaining": 14000}
+		}`))
+	}))
+	defer server.Close()
+
+	result, err := Get(auth.Session{InstanceURL: server.URL, APIVersion: "44.0"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "/services/data/v44.0/limits", actualPath)
+	assert.Equal(t, Limit{15000, 14000}, result[DailyBulkAPIBatches])
+	assert.Equal(t, 2, result[DailyAPIRequests].Used())
+}
+
+func TestGetError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(401)
+		w.Write([]byte(`[{"errorCode":"INVALID_SESSION_ID","message":"Session expired or invalid"}]`))
+	}))
+	defer server.Close()
+
+	_, err := Get(auth.Session{InstanceURL: server.URL})
+
+	assert.True(t, errors.Is(err, job.ErrInvalidSession))
+}
+
+func TestIngestUsage(t *testing.T) {
+	testCases := []struct {
+		records  int
+		jobs     int
+		expected Usage
+	}{
+		{0, 1, Usage{DailyBulkAPIBatches: 1, DailyAPIRequests: 3}},
+		{10000, 1, Usage{DailyBulkAPIBatches: 1, DailyAPIRequests: 3}},
+		{10001, 1, Usage{DailyBulkAPIBatches: 2, DailyAPIRequests: 3}},
+		{25000, 3, Usage{DailyBulkAPIBatches: 3, DailyAPIRequests: 9}},
+	}
+
+	for _, tc := range testCases {
+		assert.Equal(t, tc.expected, IngestUsage(tc.records, tc.jobs))
+	}
+}
+
+func TestLimits_Check(t *testing.T) {
+	l := Limits{
+		DailyBulkAPIBatches: {Max: 100, Remaining: 12},
+		DailyAPIRequests:    {Max: 1000, Remaining: 900},
+	}
+
+	assert.Empty(t, l.Check(Usage{DailyBulkAPIBatches: 1, DailyAPIRequests: 3}, 0.1))
+
+	problems := l.Check(Usage{DailyBulkAPIBatches: 5, DailyAPIRequests: 3}, 0.1)
+	assert.Equal(t, []Problem{{DailyBulkAPIBatches, 5, 12, 100, false}}, problems)
+
+	problems = l.Check(Usage{DailyBulkAPIBatches: 13, DailyAPIRequests: 3}, 0.1)
+	assert.Equal(t, []Problem{{DailyBulkAPIBatches, 13, 12, 100, true}}, problems)
+}
+
+func TestConsumed(t *testing.T) {
+	before := Limits{
+		DailyBulkAPIBatches: {Max: 100, Remaining: 12},
+		DailyAPIRequests:    {Max: 1000, Remaining: 900},
+	}
+
+	after := Limits{
+		DailyBulkAPIBatches: {Max: 100, Remaining: 10},
+		DailyAPIRequests:    {Max: 1000, Remaining: 895},
+	}
+
+	assert.Equal(t, Usage{DailyBulkAPIBatches: 2, DailyAPIRequests: 5}, Consumed(before, after))
+}
@@ -0,0 +1,170 @@
+// Package mapping renames, drops, duplicates and adds CSV columns so that source files line up with Salesforce field
+// API names.
+package mapping
+
+import (
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+	"io/ioutil"
+	"strings"
+)
+
+// Mapping describes how the columns of a source file become the columns of a load. Source columns that aren't mapped
+// are dropped.
+type Mapping struct {
+	// Columns maps source columns to one or more target fields, in the order they should appear.
+	Columns []Column
+
+	// Constants are columns added to every row with a fixed value.
+	Constants []Constant
+
+	plan []int
+}
+
+// Column maps a single source column to the target fields it should fill.
+type Column struct {
+	Source  string
+	Targets []string
+}
+
+// Constant is a target field that gets the same value on every row.
+type Constant struct {
+	Field string
+	Value string
+}
+
+// Load reads a mapping file.
+func Load(path string) (*Mapping, error) {
+	b, err := ioutil.ReadFile(path)
+
+	if err != nil {
+		return nil, errors.Wrap(err, "could not read mapping file")
+	}
+
+	return Parse(b)
+}
+
+// Parse reads a mapping from YAML in the following form:
+//
+//	columns:
+//	  Customer Name: Name
+//	  Cust No:
+//	    - AccountNumber
+//	    - External_Id__c
+//	constants:
+//	  Type: Customer
+func Parse(b []byte) (*Mapping, error) {
+	var file struct {
+		Columns   yaml.MapSlice `yaml:"columns"`
+		Constants yaml.MapSlice `yaml:"constants"`
+	}
+
+	if err := yaml.Unmarshal(b, &file); err != nil {
+		return nil, errors.Wrap(err, "could not parse mapping")
+	}
+
+	m := &Mapping{}
+
+	for _, item := range file.Columns {
+		col := Column{Source: toString(item.Key)}
+
+		switch v := item.Value.(type) {
+		case []interface{}:
+			for _, target := range v {
+				col.Targets = append(col.Targets, toString(target))
+			}
+		case nil:
+			return nil, errors.Errorf("mapping: column %q has no target field", col.Source)
+		default:
+			col.Targets = []string{toString(v)}
+		}
+
+		m.Columns = append(m.Columns, col)
+	}
+
+	for _, item := range file.Constants {
+		m.Constants = append(m.Constants, Constant{toString(item.Key), toString(item.Value)})
+	}
+
+	if len(m.Columns) == 0 && len(m.Constants) == 0 {
+		return nil, errors.New("mapping: no columns or constants defined")
+	}
+
+	return m, nil
+}
+
+// Header finds the mapped source columns in header and returns the target header.
+func (m *Mapping) Header(header []string) ([]string, error) {
+	var target []string
+
+	m.plan = nil
+
+	for _, col := range m.Columns {
+		i := indexOf(header, col.Source)
+
+		if i < 0 {
+			return nil, errors.Errorf("mapping: column %q not found in file", col.Source)
+		}
+
+		for _, t := range col.Targets {
+			m.plan = append(m.plan, i)
+			target = append(target, t)
+		}
+	}
+
+	for _, c := range m.Constants {
+		target = append(target, c.Field)
+	}
+
+	return target, nil
+}
+
+// Row rewrites a source row into a target row.
+func (m *Mapping) Row(row []string) ([]string, error) {
+	target := make([]string, 0, len(m.plan)+len(m.Constants))
+
+	for _, i := range m.plan {
+		if i < len(row) {
+			target = append(target, row[i])
+		} else {
+			target = append(target, "")
+		}
+	}
+
+	for _, c := range m.Constants {
+		target = append(target, c.Value)
+	}
+
+	return target, nil
+}
+
+// indexOf finds a column by exact name, falling back to a case-insensitive match that ignores surrounding whitespace.
+func indexOf(header []string, name string) int {
+	for i, h := range header {
+		if h == name {
+			return i
+		}
+	}
+
+	for i, h := range header {
+		if strings.EqualFold(strings.TrimSpace(h), strings.TrimSpace(name)) {
+			return i
+		}
+	}
+
+	return -1
+}
+
+func toString(v interface{}) string {
+	if s, ok := v.(string); ok {
+		return s
+	}
+
+	if v == nil {
+		return ""
+	}
+
+	b, _ := yaml.Marshal(v)
+
+	return strings.TrimSpace(string(b))
+}
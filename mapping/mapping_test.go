@@ -0,0 +1,95 @@
+package mapping
+
+import (
+	"bytes"
+	"github.com/rfaulhaber/forcedata/describe"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+const testMapping = `
+columns:
+  Customer Name: Name
+  Cust No:
+    - AccountNumber
+    - External_Id__c
+constants:
+  Type: Customer
+  NumberOfEmployees: 10
+`
+
+func TestParse(t *testing.T) {
+	m, err := Parse([]byte(testMapping))
+
+	assert.NoError(t, err)
+	assert.Equal(t, []Column{
+		{"Customer Name", []string{"Name"}},
+		{"Cust No", []string{"AccountNumber", "External_Id__c"}},
+	}, m.Columns)
+	assert.Equal(t, []Constant{{"Type", "Customer"}, {"NumberOfEmployees", "10"}}, m.Constants)
+}
+
+func TestParseError(t *testing.T) {
+	testCases := []string{
+		"columns:\n  Customer Name:\n",
+		"{}",
+		"columns: [",
+	}
+
+	for _, tc := range testCases {
+		_, err := Parse([]byte(tc))
+		assert.Error(t, err, tc)
+	}
+}
+
+func TestMapping_Apply(t *testing.T) {
+	m, _ := Parse([]byte(testMapping))
+
+	header, err := m.Header([]string{"Cust No", "Region", "customer name "})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Name", "AccountNumber", "External_Id__c", "Type", "NumberOfEmployees"}, header)
+
+	row, err := m.Row([]string{"A-1", "West", "Acme"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Acme", "A-1", "A-1", "Customer", "10"}, row)
+
+	_, err = m.Header([]string{"Region"})
+
+	assert.EqualError(t, err, `mapping: column "Customer Name" not found in file`)
+}
+
+func TestSuggest(t *testing.T) {
+	account := describe.SObject{
+		Name: "Account",
+		Fields: []describe.Field{
+			{Name: "Id", Type: "id"},
+			{Name: "Name", Label: "Account Name", Createable: true},
+			{Name: "AccountNumber", Label: "Account Number", Createable: true},
+			{Name: "BillingCity", Label: "Billing City", Createable: true},
+			{Name: "Customer_Priority__c", Label: "Customer Priority", Createable: true},
+			{Name: "CreatedDate", Label: "Created Date"},
+		},
+	}
+
+	m, unmatched := Suggest([]string{"account name", "Acount Number", "billing_city", "Priority", "Created Date", "Fax Extension"}, account)
+
+	assert.Equal(t, []Column{
+		{"account name", []string{"Name"}},
+		{"Acount Number", []string{"AccountNumber"}},
+		{"billing_city", []string{"BillingCity"}},
+		{"Priority", []string{"Customer_Priority__c"}},
+	}, m.Columns)
+	assert.Equal(t, []string{"Created Date", "Fax Extension"}, unmatched)
+
+	var buf bytes.Buffer
+
+	assert.NoError(t, m.WriteYAML(&buf, unmatched))
+
+	parsed, err := Parse(buf.Bytes())
+
+	assert.NoError(t, err)
+	assert.Equal(t, m.Columns, parsed.Columns)
+	assert.Contains(t, buf.String(), "  # Fax Extension: \n")
+}
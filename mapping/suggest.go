@@ -0,0 +1,194 @@
+package mapping
+
+import (
+	"fmt"
+	"github.com/rfaulhaber/forcedata/describe"
+	"io"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+const (
+	// minSimilarity is how similar a header and a field name or label must be for Suggest to pair them.
+	minSimilarity = 0.7
+
+	// containedSimilarity is the score given when one name contains the other, e.g. "First" and "FirstName", as long
+	// as both are at least minContained characters long.
+	containedSimilarity = 0.75
+	minContained        = 4
+)
+
+// Suggest guesses a mapping for header by fuzzy-matching each column against the names and labels of the object's
+// writable fields. It returns the suggested mapping and the columns it couldn't match.
+func Suggest(header []string, object describe.SObject) (*Mapping, []string) {
+	m := &Mapping{}
+	used := map[string]bool{}
+
+	var unmatched []string
+
+	for _, col := range header {
+		best, score := "", 0.0
+
+		for _, f := range object.Fields {
+			if !f.Createable && !f.Updateable && f.Type != "id" {
+				continue
+			}
+
+			if used[f.Name] {
+				continue
+			}
+
+			s := similarity(col, f.Name)
+
+			if l := similarity(col, f.Label); l > s {
+				s = l
+			}
+
+			if s > score {
+				best, score = f.Name, s
+			}
+		}
+
+		if score < minSimilarity {
+			unmatched = append(unmatched, col)
+			continue
+		}
+
+		used[best] = true
+		m.Columns = append(m.Columns, Column{Source: col, Targets: []string{best}})
+	}
+
+	return m, unmatched
+}
+
+// WriteYAML writes the mapping in the format read by Parse. Unmatched columns are written as comments so they can be
+// filled in by hand.
+func (m *Mapping) WriteYAML(w io.Writer, unmatched []string) error {
+	if _, err := fmt.Fprintln(w, "columns:"); err != nil {
+		return err
+	}
+
+	for _, col := range m.Columns {
+		if len(col.Targets) == 1 {
+			fmt.Fprintf(w, "  %s: %s\n", quote(col.Source), quote(col.Targets[0]))
+			continue
+		}
+
+		fmt.Fprintf(w, "  %s:\n", quote(col.Source))
+
+		for _, t := range col.Targets {
+			fmt.Fprintf(w, "    - %s\n", quote(t))
+		}
+	}
+
+	for _, col := range unmatched {
+		fmt.Fprintf(w, "  # %s: \n", quote(col))
+	}
+
+	if len(m.Constants) > 0 {
+		fmt.Fprintln(w, "constants:")
+
+		for _, c := range m.Constants {
+			fmt.Fprintf(w, "  %s: %s\n", quote(c.Field), quote(c.Value))
+		}
+	}
+
+	return nil
+}
+
+// quote quotes YAML scalars that wouldn't otherwise be read back as the same string.
+func quote(s string) string {
+	if s == "" || strings.ContainsAny(s, ":#{}[],&*?|<>=!%@`'\"\\") || strings.TrimSpace(s) != s {
+		return strconv.Quote(s)
+	}
+
+	switch strings.ToLower(s) {
+	case "true", "false", "yes", "no", "on", "off", "null", "~":
+		return strconv.Quote(s)
+	}
+
+	if _, err := strconv.ParseFloat(s, 64); err == nil {
+		return strconv.Quote(s)
+	}
+
+	return s
+}
+
+// similarity scores how alike two names are from 0 to 1, ignoring case, punctuation, whitespace and the custom field
+// suffix.
+func similarity(a, b string) float64 {
+	a, b = normalize(a), normalize(b)
+
+	if a == "" || b == "" {
+		return 0
+	}
+
+	if a == b {
+		return 1
+	}
+
+	if len(a) >= minContained && len(b) >= minContained && (strings.Contains(a, b) || strings.Contains(b, a)) {
+		return containedSimilarity
+	}
+
+	longest := len([]rune(a))
+
+	if l := len([]rune(b)); l > longest {
+		longest = l
+	}
+
+	return 1 - float64(levenshtein(a, b))/float64(longest)
+}
+
+func normalize(s string) string {
+	s = strings.TrimSuffix(strings.ToLower(s), "__c")
+
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return r
+		}
+
+		return -1
+	}, s)
+}
+
+func levenshtein(a, b string) int {
+	x, y := []rune(a), []rune(b)
+	prev := make([]int, len(y)+1)
+	cur := make([]int, len(y)+1)
+
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(x); i++ {
+		cur[0] = i
+
+		for j := 1; j <= len(y); j++ {
+			cost := 1
+
+			if x[i-1] == y[j-1] {
+				cost = 0
+			}
+
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+
+		prev, cur = cur, prev
+	}
+
+	return prev[len(y)]
+}
+
+func min(values ...int) int {
+	m := values[0]
+
+	for _, v := range values[1:] {
+		if v < m {
+			m = v
+		}
+	}
+
+	return m
+}
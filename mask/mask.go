@@ -0,0 +1,179 @@
+// Package mask replaces personal data in CSV records with realistic fake values, for copying production data into
+// sandboxes. Masking is deterministic: with the same seed, the same source value always becomes the same masked value,
+// in any file and any field masked the same way, so records still match each other after masking.
+package mask
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"github.com/pkg/errors"
+	"github.com/rfaulhaber/forcedata/pipeline"
+	"gopkg.in/yaml.v2"
+	"io/ioutil"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Rules are the masking strategies of each object's fields.
+type Rules struct {
+	objects map[string][]rule
+}
+
+type rule struct {
+	field    string
+	strategy strategy
+}
+
+// Load reads a rules file.
+func Load(path string) (*Rules, error) {
+	b, err := ioutil.ReadFile(path)
+
+	if err != nil {
+		return nil, errors.Wrap(err, "could not read mask rules")
+	}
+
+	return Parse(b)
+}
+
+// Parse reads rules from YAML, giving the strategy of each field by object:
+//
+//	objects:
+//	  Contact:
+//	    FirstName: first-name
+//	    LastName: last-name
+//	    Email: email
+//	    Phone: phone
+//	    MailingStreet: street
+//	    MailingCity: city
+//	    SSN__c: hash
+//	    Description: null
+//	    AccountNumber__c: scramble      # keeps length, punctuation and case
+//	    Birthdate:
+//	      shift-date: 90                # moves dates up to 90 days either way
+//
+// The strategies are first-name, last-name, name, email, phone, street, city, hash, null, scramble and shift-date.
+func Parse(b []byte) (*Rules, error) {
+	var file struct {
+		Objects map[string]map[string]interface{} `yaml:"objects"`
+	}
+
+	if err := yaml.UnmarshalStrict(b, &file); err != nil {
+		return nil, errors.Wrap(err, "could not parse mask rules")
+	}
+
+	r := &Rules{objects: map[string][]rule{}}
+
+	for object, fields := range file.Objects {
+		var rules []rule
+
+		for field, spec := range fields {
+			s, err := parseStrategy(spec)
+
+			if err != nil {
+				return nil, errors.Wrapf(err, "mask: %s.%s", object, field)
+			}
+
+			rules = append(rules, rule{field: field, strategy: s})
+		}
+
+		sort.Slice(rules, func(i, j int) bool { return rules[i].field < rules[j].field })
+
+		r.objects[strings.ToLower(object)] = rules
+	}
+
+	if len(r.objects) == 0 {
+		return nil, errors.New("mask: no objects defined")
+	}
+
+	return r, nil
+}
+
+// Has reports whether the rules mask any fields of object.
+func (r *Rules) Has(object string) bool {
+	return len(r.objects[strings.ToLower(object)]) > 0
+}
+
+// Masker returns a stage that masks records of object with the given seed. The seed keys every masked value, so it
+// should be kept secret; without it, hashed values can't be reversed by hashing guesses.
+func (r *Rules) Masker(object, seed string) (*Masker, error) {
+	if seed == "" {
+		return nil, errors.New("mask: a seed is required")
+	}
+
+	if !r.Has(object) {
+		return nil, errors.Errorf("mask: no rules for %s", object)
+	}
+
+	return &Masker{rules: r.objects[strings.ToLower(object)], seed: []byte(seed)}, nil
+}
+
+// Masker is a pipeline stage that masks the fields of one object. Fields of the rules that aren't in the header are
+// ignored, and blank values are left blank.
+type Masker struct {
+	rules []rule
+	seed  []byte
+
+	columns map[int]strategy
+	names   map[int]string
+}
+
+// Header locates the masked columns.
+func (m *Masker) Header(header []string) ([]string, error) {
+	m.columns = map[int]strategy{}
+	m.names = map[int]string{}
+
+	for _, r := range m.rules {
+		for i, name := range header {
+			if strings.EqualFold(strings.TrimSpace(name), r.field) {
+				m.columns[i] = r.strategy
+				m.names[i] = name
+			}
+		}
+	}
+
+	return header, nil
+}
+
+// Row masks the row's values, rejecting it if a value can't be masked, such as a date that can't be read.
+func (m *Masker) Row(row []string) ([]string, error) {
+	for i, s := range m.columns {
+		if i >= len(row) {
+			continue
+		}
+
+		if _, null := s.(nullStrategy); !null && strings.TrimSpace(row[i]) == "" {
+			continue
+		}
+
+		masked, err := s.mask(m, row[i])
+
+		if err != nil {
+			return nil, pipeline.Reject("%s: %s", m.names[i], err)
+		}
+
+		row[i] = masked
+	}
+
+	return row, nil
+}
+
+// digest returns the nth block of bytes derived from value for the given kind of mask. Values masked the same way
+// derive the same bytes whatever field they're in.
+func (m *Masker) digest(kind, value string, n int) []byte {
+	mac := hmac.New(sha256.New, m.seed)
+	mac.Write([]byte(kind + "\x00" + strconv.Itoa(n) + "\x00" + value))
+
+	return mac.Sum(nil)
+}
+
+// number returns a number below max derived from value.
+func (m *Masker) number(kind, value string, max int) int {
+	return int(binary.BigEndian.Uint64(m.digest(kind, value, 0)) % uint64(max))
+}
+
+// pick returns one of choices derived from value.
+func (m *Masker) pick(kind, value string, choices []string) string {
+	return choices[m.number(kind, value, len(choices))]
+}
@@ -0,0 +1,140 @@
+package mask
+
+import (
+	"bytes"
+	"encoding/csv"
+	"github.com/rfaulhaber/forcedata/pipeline"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testRules = `
+objects:
+  Contact:
+    FirstName: first-name
+    LastName: last-name
+    Email: email
+    Phone: phone
+    MailingStreet: street
+    SSN__c: hash
+    Description: null
+    Code__c: scramble
+    Birthdate:
+      shift-date: 10
+  Lead:
+    Email: email
+`
+
+func run(t *testing.T, m *Masker, input string) ([][]string, string) {
+	var out, rejects bytes.Buffer
+
+	p := pipeline.Pipeline{Stages: []pipeline.Stage{m}, Rejects: csv.NewWriter(&rejects)}
+
+	assert.NoError(t, p.Run(csv.NewReader(strings.NewReader(input)), csv.NewWriter(&out)))
+
+	records, err := csv.NewReader(&out).ReadAll()
+	assert.NoError(t, err)
+
+	return records, rejects.String()
+}
+
+func TestMasker(t *testing.T) {
+	rules, err := Parse([]byte(testRules))
+	assert.NoError(t, err)
+
+	m, err := rules.Masker("contact", "s3cret")
+	assert.NoError(t, err)
+
+	input := "Id,FirstName,LastName,Email,Phone,MailingStreet,SSN__c,Description,Code__c,Birthdate\n" +
+		"003A,Ada,Lovelace,ada@example.org,(555) 123-4567 x12,1 Main St,123-45-6789,VIP,AB-12cd,1990-01-31\n" +
+		"003B,,Babbage,ADA@example.org ,,,,notes,,\n" +
+		"003C,Ada,,,,,,,,31/01/1990\n"
+
+	records, rejects := run(t, m, input)
+
+	assert.Len(t, records, 3)
+	assert.Equal(t, strings.Split("Id,FirstName,LastName,Email,Phone,MailingStreet,SSN__c,Description,Code__c,Birthdate", ","), records[0])
+
+	first, second := records[1], records[2]
+
+	assert.Equal(t, "003A", first[0])
+	assert.NotEqual(t, "Ada", first[1])
+	assert.Contains(t, firstNames, first[1])
+	assert.Contains(t, lastNames, first[2])
+	assert.Regexp(t, `^[a-z]+\.[a-z]+\.[0-9a-f]{12}@example\.com$`, first[3])
+	assert.Regexp(t, `^\(\d{3}\) \d{3}-\d{4} x\d{2}$`, first[4])
+	assert.NotEqual(t, "(555) 123-4567 x12", first[4])
+	assert.Regexp(t, `^\d+ \w+ \w+$`, first[5])
+	assert.Regexp(t, `^[0-9a-f]{64}$`, first[6])
+	assert.Equal(t, "", first[7])
+	assert.Regexp(t, `^[A-Z]{2}-\d{2}[a-z]{2}$`, first[8])
+
+	birthdate, _ := time.Parse("2006-01-02", first[9])
+	shift := birthdate.Sub(time.Date(1990, 1, 31, 0, 0, 0, 0, time.UTC)).Hours() / 24
+
+	assert.True(t, shift != 0 && shift >= -10 && shift <= 10, "shifted %v days", shift)
+
+	// blanks stay blank, and equal values mask the same ignoring case and space
+	assert.Equal(t, []string{"003B", "", second[2], first[3], "", "", "", "", "", ""}, second)
+
+	assert.Equal(t, "Id,FirstName,LastName,Email,Phone,MailingStreet,SSN__c,Description,Code__c,Birthdate,forcedata__Error\n"+
+		"003C,Ada,,,,,,,,31/01/1990,\"Birthdate: \"\"31/01/1990\"\" is not a date\"\n", rejects)
+}
+
+func TestMasker_Deterministic(t *testing.T) {
+	rules, _ := Parse([]byte(testRules))
+
+	contacts, _ := rules.Masker("Contact", "s3cret")
+	leads, _ := rules.Masker("Lead", "s3cret")
+	other, _ := rules.Masker("Lead", "other")
+
+	contact, _ := run(t, contacts, "Email,FirstName\nada@example.org,Ada\n")
+	again, _ := run(t, contacts, "FirstName,Email\nAda,ada@example.org\n")
+	lead, _ := run(t, leads, "Email\nada@example.org\n")
+	reseeded, _ := run(t, other, "Email\nada@example.org\n")
+
+	assert.Equal(t, contact[1][0], again[1][1])
+	assert.Equal(t, contact[1][1], again[1][0])
+	assert.Equal(t, contact[1][0], lead[1][0])
+	assert.NotEqual(t, contact[1][0], reseeded[1][0])
+}
+
+func TestShiftDatetime(t *testing.T) {
+	m := &Masker{seed: []byte("s3cret")}
+
+	for _, value := range []string{"2018-07-04T13:05:00Z", "2018-07-04T13:05:00.000+0000"} {
+		masked, err := shiftStrategy(5).mask(m, value)
+
+		assert.NoError(t, err)
+		assert.Regexp(t, "^2018-0[67]-\\d\\dT13:05:00", masked)
+		assert.Len(t, masked, len(value))
+	}
+}
+
+func TestMasker_Errors(t *testing.T) {
+	rules, _ := Parse([]byte(testRules))
+
+	_, err := rules.Masker("Contact", "")
+	assert.EqualError(t, err, "mask: a seed is required")
+
+	_, err = rules.Masker("Account", "s3cret")
+	assert.EqualError(t, err, "mask: no rules for Account")
+}
+
+func TestParseError(t *testing.T) {
+	testCases := []string{
+		"objects:\n  Contact:\n    Email: shout\n",
+		"objects:\n  Contact:\n    Birthdate: {shift-date: soon}\n",
+		"objects:\n  Contact:\n    Birthdate: {shift: 10}\n",
+		"objects:\n  Contact:\n    Email: [email]\n",
+		"objects: {}\n",
+		"fields: []\n",
+	}
+
+	for _, tc := range testCases {
+		_, err := Parse([]byte(tc))
+		assert.Error(t, err, tc)
+	}
+}
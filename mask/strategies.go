@@ -0,0 +1,231 @@
+package mask
+
+import (
+	"encoding/hex"
+	"fmt"
+	"github.com/pkg/errors"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// DefaultShift is the most days shift-date moves a date when it isn't given.
+const DefaultShift = 30
+
+// dateLayouts are the forms dates and datetimes are read and written in: those of Salesforce's CSV and query results.
+var dateLayouts = []string{
+	"2006-01-02",
+	"2006-01-02T15:04:05Z07:00",
+	"2006-01-02T15:04:05.000Z07:00",
+	"2006-01-02T15:04:05.000-0700",
+}
+
+// strategy masks a single non-blank value.
+type strategy interface {
+	mask(m *Masker, value string) (string, error)
+}
+
+// parseStrategy reads a strategy from a bare name ("email"), null, or a single-key map ({shift-date: 90}).
+func parseStrategy(spec interface{}) (strategy, error) {
+	switch s := spec.(type) {
+	case nil:
+		return nullStrategy{}, nil
+	case string:
+		switch s {
+		case "first-name":
+			return pickStrategy{"first-name", firstNames}, nil
+		case "last-name":
+			return pickStrategy{"last-name", lastNames}, nil
+		case "name":
+			return nameStrategy{}, nil
+		case "email":
+			return emailStrategy{}, nil
+		case "phone":
+			return scrambleStrategy{"phone", true}, nil
+		case "street":
+			return streetStrategy{}, nil
+		case "city":
+			return pickStrategy{"city", cities}, nil
+		case "hash":
+			return hashStrategy{}, nil
+		case "null":
+			return nullStrategy{}, nil
+		case "scramble":
+			return scrambleStrategy{"scramble", false}, nil
+		case "shift-date":
+			return shiftStrategy(DefaultShift), nil
+		default:
+			return nil, errors.Errorf("unknown strategy %q", s)
+		}
+	case map[interface{}]interface{}:
+		if len(s) != 1 {
+			break
+		}
+
+		if days, ok := s["shift-date"]; ok {
+			n, ok := days.(int)
+
+			if !ok || n <= 0 {
+				return nil, errors.Errorf("shift-date needs a number of days, not %v", days)
+			}
+
+			return shiftStrategy(n), nil
+		}
+
+		for k := range s {
+			return nil, errors.Errorf("unknown strategy %q", fmt.Sprint(k))
+		}
+	}
+
+	return nil, errors.Errorf("invalid strategy %v", spec)
+}
+
+// key is the form of a value that's masked, so that values differing only in case or surrounding space are masked
+// the same.
+func key(value string) string {
+	return strings.ToLower(strings.TrimSpace(value))
+}
+
+// nullStrategy blanks every value.
+type nullStrategy struct{}
+
+func (nullStrategy) mask(m *Masker, value string) (string, error) {
+	return "", nil
+}
+
+// hashStrategy replaces values with a hex digest, which is the same for equal values but can't be read.
+type hashStrategy struct{}
+
+func (hashStrategy) mask(m *Masker, value string) (string, error) {
+	return hex.EncodeToString(m.digest("hash", value, 0)), nil
+}
+
+// pickStrategy replaces values with one of a list of fake ones.
+type pickStrategy struct {
+	kind    string
+	choices []string
+}
+
+func (p pickStrategy) mask(m *Masker, value string) (string, error) {
+	return m.pick(p.kind, key(value), p.choices), nil
+}
+
+// nameStrategy replaces full names with a fake first and last name. There are only so many of those, so different
+// names often get the same fake one; it shouldn't be used for a field that has to stay unique.
+type nameStrategy struct{}
+
+func (nameStrategy) mask(m *Masker, value string) (string, error) {
+	k := key(value)
+	return m.pick("name-first", k, firstNames) + " " + m.pick("name-last", k, lastNames), nil
+}
+
+// emailStrategy replaces email addresses with fake ones at example.com, which never receives mail. Addresses are
+// often unique fields, such as a user's username, so each ends in 48 bits of the address's digest, making different
+// addresses unlikely to get the same fake one even among millions.
+type emailStrategy struct{}
+
+func (emailStrategy) mask(m *Masker, value string) (string, error) {
+	k := key(value)
+	first := strings.ToLower(m.pick("email-first", k, firstNames))
+	last := strings.ToLower(m.pick("email-last", k, lastNames))
+
+	return fmt.Sprintf("%s.%s.%s@example.com", first, last, hex.EncodeToString(m.digest("email-id", k, 0)[:6])), nil
+}
+
+// streetStrategy replaces street addresses with a fake number and street. Different addresses can get the same fake
+// one, so it shouldn't be used for a field that has to stay unique.
+type streetStrategy struct{}
+
+func (streetStrategy) mask(m *Masker, value string) (string, error) {
+	k := key(value)
+	return fmt.Sprintf("%d %s %s", 1+m.number("street-number", k, 9999), m.pick("street-name", k, streets), m.pick("street-suffix", k, suffixes)), nil
+}
+
+// scrambleStrategy replaces each digit with another digit and, unless digitsOnly is set, each letter with another
+// letter of the same case, keeping the length and punctuation of the value.
+type scrambleStrategy struct {
+	kind       string
+	digitsOnly bool
+}
+
+func (s scrambleStrategy) mask(m *Masker, value string) (string, error) {
+	var (
+		b     strings.Builder
+		block []byte
+	)
+
+	for i, r := range []rune(value) {
+		if i%32 == 0 {
+			block = m.digest(s.kind, value, i/32)
+		}
+
+		n := int(block[i%32])
+
+		switch {
+		case unicode.IsDigit(r):
+			b.WriteByte(byte('0' + n%10))
+		case s.digitsOnly:
+			b.WriteRune(r)
+		case unicode.IsUpper(r):
+			b.WriteByte(byte('A' + n%26))
+		case unicode.IsLower(r):
+			b.WriteByte(byte('a' + n%26))
+		default:
+			b.WriteRune(r)
+		}
+	}
+
+	return b.String(), nil
+}
+
+// shiftStrategy moves dates and datetimes by between 1 and the given number of days, earlier or later.
+type shiftStrategy int
+
+func (s shiftStrategy) mask(m *Masker, value string) (string, error) {
+	value = strings.TrimSpace(value)
+
+	days := m.number("shift-date", value, 2*int(s)) - int(s)
+
+	if days >= 0 {
+		days++
+	}
+
+	for _, layout := range dateLayouts {
+		t, err := time.Parse(layout, value)
+
+		if err == nil {
+			return t.AddDate(0, 0, days).Format(layout), nil
+		}
+	}
+
+	return "", errors.Errorf("%q is not a date", value)
+}
+
+var firstNames = []string{
+	"Alex", "Avery", "Bailey", "Blake", "Cameron", "Carmen", "Casey", "Charlie", "Dakota", "Dana",
+	"Devon", "Drew", "Eden", "Elliot", "Emerson", "Finley", "Frankie", "Gray", "Harper", "Hayden",
+	"Jamie", "Jesse", "Jordan", "Kai", "Kendall", "Lee", "Logan", "Marley", "Morgan", "Noel",
+	"Parker", "Peyton", "Quinn", "Reese", "Riley", "Robin", "Rowan", "Sage", "Sam", "Skyler",
+	"Taylor", "Terry", "Toni", "Val", "Wren",
+}
+
+var lastNames = []string{
+	"Abbott", "Barker", "Bishop", "Carter", "Chavez", "Daniels", "Ellis", "Fleming", "Foster", "Garcia",
+	"Gibson", "Hale", "Hughes", "Ingram", "Jensen", "Keller", "Kim", "Lambert", "Lopez", "Marsh",
+	"Meyer", "Nguyen", "Novak", "Okafor", "Owens", "Patel", "Porter", "Quinlan", "Ramos", "Reid",
+	"Sato", "Schmidt", "Shaw", "Silva", "Stone", "Tanaka", "Thornton", "Underwood", "Vance", "Walsh",
+	"Webb", "Weiss", "Young", "Zimmer",
+}
+
+var streets = []string{
+	"Maple", "Oak", "Cedar", "Pine", "Elm", "Willow", "Birch", "Aspen", "Hickory", "Chestnut",
+	"Lake", "Hill", "River", "Meadow", "Park", "Forest", "Spring", "Sunset", "Highland", "Orchard",
+}
+
+var suffixes = []string{"St", "Ave", "Rd", "Ln", "Dr", "Ct", "Way", "Blvd", "Pl"}
+
+var cities = []string{
+	"Ashford", "Bayview", "Brookfield", "Cedar Falls", "Clearwater", "Dover", "Fairview", "Glenwood", "Greenville",
+	"Harborton", "Kingsport", "Lakeside", "Maplewood", "Millbrook", "Newport", "Oakridge", "Pinecrest", "Riverside",
+	"Springdale", "Westfield",
+}
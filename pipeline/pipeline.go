@@ -0,0 +1,162 @@
+// Package pipeline streams CSV records through a series of stages on their way to the Bulk API.
+package pipeline
+
+import (
+	"encoding/csv"
+	"fmt"
+	"github.com/pkg/errors"
+	"io"
+)
+
+// RejectColumn is added to the header of reject files. It holds the reason each row was rejected.
+const RejectColumn = "forcedata__Error"
+
+// Stage transforms records. Header is called once with the incoming header before any rows, and returns the header
+// of the records the stage produces. Row is then called for each row in order.
+type Stage interface {
+	Header(header []string) ([]string, error)
+	Row(row []string) ([]string, error)
+}
+
+// RejectError is returned by a stage's Row to drop a single row instead of stopping the whole run.
+type RejectError struct {
+	Reason string
+}
+
+func (e *RejectError) Error() string {
+	return e.Reason
+}
+
+// Reject returns a RejectError with a formatted reason.
+func Reject(format string, args ...interface{}) error {
+	return &RejectError{fmt.Sprintf(format, args...)}
+}
+
+// Pipeline passes records through stages, writing rejected rows to Rejects.
+type Pipeline struct {
+	Stages []Stage
+
+	// Rejects receives the original source row of every rejected row, with the reason in an extra RejectColumn. If
+	// it's nil, a rejected row stops the run.
+	Rejects *csv.Writer
+
+	// Rejecting, if set, is called with every rejected row as it was given to the stage that rejected it, along with
+	// the header that stage was given.
+	Rejecting func(header, row []string)
+
+	// Rows and Rejected count the rows written and rejected by the last run.
+	Rows     int
+	Rejected int
+}
+
+// Run reads every record from r, passes it through each stage in order, and writes the result to w.
+func Run(r *csv.Reader, w *csv.Writer, stages ...Stage) error {
+	p := Pipeline{Stages: stages}
+	return p.Run(r, w)
+}
+
+// Run reads every record from r, passes it through each stage in order, and writes the result to w.
+func (p *Pipeline) Run(r *csv.Reader, w *csv.Writer) error {
+	p.Rows, p.Rejected = 0, 0
+
+	source, err := r.Read()
+
+	if err != nil {
+		return errors.Wrap(err, "could not read header")
+	}
+
+	header := append([]string{}, source...)
+
+	// headers are the headers each stage is given
+	headers := make([][]string, len(p.Stages))
+
+	for i, s := range p.Stages {
+		headers[i] = append([]string{}, header...)
+
+		if header, err = s.Header(header); err != nil {
+			return err
+		}
+	}
+
+	if err := w.Write(header); err != nil {
+		return err
+	}
+
+	if p.Rejects != nil {
+		if err := p.Rejects.Write(append(source, RejectColumn)); err != nil {
+			return err
+		}
+	}
+
+	line := 0
+
+	for {
+		original, err := r.Read()
+
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			return errors.Wrap(err, "could not parse CSV")
+		}
+
+		line++
+
+		row, stage, err := p.row(original)
+
+		if reject, ok := err.(*RejectError); ok && p.Rejects != nil {
+			p.Rejected++
+
+			if p.Rejecting != nil {
+				p.Rejecting(headers[stage], row)
+			}
+
+			if err := p.Rejects.Write(append(original, reject.Reason)); err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		if err != nil {
+			return errors.Wrapf(err, "row %d", line)
+		}
+
+		if err := w.Write(row); err != nil {
+			return err
+		}
+
+		p.Rows++
+	}
+
+	w.Flush()
+
+	if p.Rejects != nil {
+		p.Rejects.Flush()
+
+		if err := p.Rejects.Error(); err != nil {
+			return err
+		}
+	}
+
+	return w.Error()
+}
+
+// row passes a row through the stages. If one fails, it returns the row as that stage was given it, and the stage's
+// index.
+func (p *Pipeline) row(original []string) ([]string, int, error) {
+	row := append([]string{}, original...)
+
+	for i, s := range p.Stages {
+		next, err := s.Row(row)
+
+		if err != nil {
+			return row, i, err
+		}
+
+		row = next
+	}
+
+	return row, len(p.Stages), nil
+}
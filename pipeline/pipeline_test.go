@@ -0,0 +1,64 @@
+package pipeline
+
+import (
+	"bytes"
+	"encoding/csv"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+type upper struct{}
+
+func (upper) Header(header []string) ([]string, error) {
+	return append(header, "Upper"), nil
+}
+
+func (upper) Row(row []string) ([]string, error) {
+	if row[0] == "" {
+		return nil, Reject("blank")
+	}
+
+	return append(row, strings.ToUpper(row[0])), nil
+}
+
+func TestRun(t *testing.T) {
+	var buf bytes.Buffer
+
+	err := Run(csv.NewReader(strings.NewReader("Name\nacme\nglobex\n")), csv.NewWriter(&buf), upper{}, upper{})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "Name,Upper,Upper\nacme,ACME,ACME\nglobex,GLOBEX,GLOBEX\n", buf.String())
+}
+
+func TestRunError(t *testing.T) {
+	var buf bytes.Buffer
+
+	err := Run(csv.NewReader(strings.NewReader("Name\nacme\n\"\"\n")), csv.NewWriter(&buf), upper{})
+
+	assert.EqualError(t, err, "row 2: blank")
+}
+
+func TestPipeline_Rejects(t *testing.T) {
+	var (
+		buf, rejects bytes.Buffer
+		rejected     [][]string
+	)
+
+	p := Pipeline{
+		Stages:  []Stage{upper{}},
+		Rejects: csv.NewWriter(&rejects),
+		Rejecting: func(header, row []string) {
+			rejected = append(rejected, header, row)
+		},
+	}
+
+	err := p.Run(csv.NewReader(strings.NewReader("Name\nacme\n\"\"\nglobex\n")), csv.NewWriter(&buf))
+
+	assert.NoError(t, err)
+	assert.Equal(t, "Name,Upper\nacme,ACME\nglobex,GLOBEX\n", buf.String())
+	assert.Equal(t, "Name,forcedata__Error\n,blank\n", rejects.String())
+	assert.Equal(t, 2, p.Rows)
+	assert.Equal(t, 1, p.Rejected)
+	assert.Equal(t, [][]string{{"Name"}, {""}}, rejected)
+}
@@ -0,0 +1,257 @@
+// Package plan loads several related files in one go, creating parent records before the children that refer to them
+// and rewriting the children's reference columns with the new parent IDs.
+package plan
+
+import (
+	"github.com/pkg/errors"
+	"github.com/rfaulhaber/forcedata/job"
+	"gopkg.in/yaml.v2"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+var operations = map[string]bool{
+	"insert":     true,
+	"update":     true,
+	"upsert":     true,
+	"delete":     true,
+	"hardDelete": true,
+}
+
+// Plan is a set of steps, each loading one file into one object.
+type Plan struct {
+	// Ordered runs the steps one at a time in the order they're listed, rather than in parallel as their dependencies
+	// allow.
+	Ordered bool `yaml:"ordered,omitempty"`
+
+	Steps []*Step `yaml:"steps"`
+}
+
+// Step is a single job of a plan.
+type Step struct {
+	Name string `yaml:"name"`
+
+	job.JobConfig `yaml:",inline"`
+
+	// File is the CSV file loaded by the step, relative to the plan.
+	File string `yaml:"file"`
+
+	// Mapping is an optional column mapping file applied to File, relative to the plan.
+	Mapping string `yaml:"mapping,omitempty"`
+
+	// Key is a column of File whose values identify its rows to later steps. It's only uploaded if it's mapped or, without
+	// a mapping, if it's a field of Object.
+	Key string `yaml:"key,omitempty"`
+
+	// References maps reference columns, after mapping, to the step whose keys they hold. Their values are replaced
+	// with the IDs of the records that step created or updated.
+	References map[string]string `yaml:"references,omitempty"`
+
+	// DependsOn lists steps that must finish before this one starts, in addition to those it references.
+	DependsOn []string `yaml:"dependsOn,omitempty"`
+
+	// Exclude lists columns, after mapping, that aren't uploaded. They can still be used as the key.
+	Exclude []string `yaml:"exclude,omitempty"`
+}
+
+// Load reads a plan file. Files named by its steps are relative to the plan file.
+func Load(path string) (*Plan, error) {
+	b, err := ioutil.ReadFile(path)
+
+	if err != nil {
+		return nil, errors.Wrap(err, "could not read plan file")
+	}
+
+	return Parse(b, filepath.Dir(path))
+}
+
+// Parse reads a plan from YAML in the following form, resolving file names relative to dir:
+//
+//	steps:
+//	  - name: accounts
+//	    object: Account
+//	    operation: insert
+//	    file: accounts.csv
+//	    key: Legacy Id
+//	  - name: contacts
+//	    object: Contact
+//	    operation: insert
+//	    file: contacts.csv
+//	    mapping: contacts-mapping.yaml
+//	    references:
+//	      AccountId: accounts
+//
+// Steps take the same settings as a job, with contentType defaulting to CSV and columnDelimiter to COMMA.
+func Parse(b []byte, dir string) (*Plan, error) {
+	var p Plan
+
+	if err := yaml.UnmarshalStrict(b, &p); err != nil {
+		return nil, errors.Wrap(err, "could not parse plan")
+	}
+
+	if len(p.Steps) == 0 {
+		return nil, errors.New("plan has no steps")
+	}
+
+	for _, s := range p.Steps {
+		if s.ContentType == "" {
+			s.ContentType = "CSV"
+		}
+
+		if s.Delim == "" {
+			s.Delim = "COMMA"
+		}
+
+		if s.File != "" && !filepath.IsAbs(s.File) {
+			s.File = filepath.Join(dir, s.File)
+		}
+
+		if s.Mapping != "" && !filepath.IsAbs(s.Mapping) {
+			s.Mapping = filepath.Join(dir, s.Mapping)
+		}
+	}
+
+	if err := p.check(); err != nil {
+		return nil, err
+	}
+
+	if _, err := p.Order(); err != nil {
+		return nil, err
+	}
+
+	return &p, nil
+}
+
+func (p *Plan) check() error {
+	names := map[string]*Step{}
+
+	for i, s := range p.Steps {
+		if s.Name == "" {
+			return errors.Errorf("step %d has no name", i+1)
+		}
+
+		if names[s.Name] != nil {
+			return errors.Errorf("step %s: name is used by another step", s.Name)
+		}
+
+		names[s.Name] = s
+	}
+
+	for _, s := range p.Steps {
+		var problem string
+
+		switch {
+		case s.Object == "":
+			problem = "object is required"
+		case !operations[s.Operation]:
+			problem = "operation must be one of insert, update, upsert, delete or hardDelete"
+		case s.Operation == "upsert" && s.ExternalIDField == "":
+			problem = "externalIdFieldName is required for an upsert"
+		case s.File == "":
+			problem = "file is required"
+		}
+
+		if _, ok := job.GetDelim(s.Delim); !ok && problem == "" {
+			problem = "invalid columnDelimiter " + s.Delim
+		}
+
+		if problem != "" {
+			return errors.Errorf("step %s: %s", s.Name, problem)
+		}
+
+		for _, dep := range s.DependsOn {
+			if names[dep] == nil {
+				return errors.Errorf("step %s: depends on unknown step %s", s.Name, dep)
+			}
+		}
+
+		for col, ref := range s.References {
+			if names[ref] == nil {
+				return errors.Errorf("step %s: column %s refers to unknown step %s", s.Name, col, ref)
+			}
+
+			if names[ref].Key == "" {
+				return errors.Errorf("step %s: column %s refers to step %s, which has no key", s.Name, col, ref)
+			}
+		}
+	}
+
+	return nil
+}
+
+// Dependencies returns the names of the steps that must finish before step starts.
+func (p *Plan) Dependencies(step *Step) []string {
+	deps := map[string]bool{}
+
+	for _, d := range step.DependsOn {
+		deps[d] = true
+	}
+
+	for _, ref := range step.References {
+		deps[ref] = true
+	}
+
+	if p.Ordered {
+		for i, s := range p.Steps {
+			if s == step && i > 0 {
+				deps[p.Steps[i-1].Name] = true
+			}
+		}
+	}
+
+	var names []string
+
+	for d := range deps {
+		names = append(names, d)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+// Order returns the steps in an order that runs every step after its dependencies, keeping the order they're listed
+// in where it can. It returns an error if steps depend on each other.
+func (p *Plan) Order() ([]*Step, error) {
+	done := map[string]bool{}
+
+	var order []*Step
+
+	for len(order) < len(p.Steps) {
+		progress := false
+
+		for _, s := range p.Steps {
+			if done[s.Name] {
+				continue
+			}
+
+			ready := true
+
+			for _, d := range p.Dependencies(s) {
+				ready = ready && done[d]
+			}
+
+			if ready {
+				done[s.Name] = true
+				order = append(order, s)
+				progress = true
+			}
+		}
+
+		if !progress {
+			var cycle []string
+
+			for _, s := range p.Steps {
+				if !done[s.Name] {
+					cycle = append(cycle, s.Name)
+				}
+			}
+
+			return nil, errors.Errorf("steps depend on each other: %s", strings.Join(cycle, ", "))
+		}
+	}
+
+	return order, nil
+}
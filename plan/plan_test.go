@@ -0,0 +1,92 @@
+package plan
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	p, err := Parse([]byte(`
+steps:
+  - name: contacts
+    object: Contact
+    operation: insert
+    file: contacts.csv
+    mapping: /abs/contacts.yaml
+    references:
+      AccountId: accounts
+  - name: accounts
+    object: Account
+    operation: upsert
+    externalIdFieldName: External_Id__c
+    columnDelimiter: SEMICOLON
+    file: accounts.csv
+    key: Legacy Id
+  - name: cases
+    object: Case
+    operation: insert
+    file: cases.csv
+    dependsOn: [contacts]
+`), "data")
+
+	assert.NoError(t, err)
+	assert.Equal(t, "data/contacts.csv", p.Steps[0].File)
+	assert.Equal(t, "/abs/contacts.yaml", p.Steps[0].Mapping)
+	assert.Equal(t, "COMMA", p.Steps[0].Delim)
+	assert.Equal(t, "CSV", p.Steps[0].ContentType)
+	assert.Equal(t, "SEMICOLON", p.Steps[1].Delim)
+	assert.Equal(t, "External_Id__c", p.Steps[1].ExternalIDField)
+	assert.Equal(t, []string{"accounts"}, p.Dependencies(p.Steps[0]))
+
+	order, err := p.Order()
+
+	assert.NoError(t, err)
+	assert.Equal(t, []*Step{p.Steps[1], p.Steps[0], p.Steps[2]}, order)
+}
+
+func TestParse_Ordered(t *testing.T) {
+	p, err := Parse([]byte(`
+ordered: true
+steps:
+  - {name: a, object: Account, operation: insert, file: a.csv}
+  - {name: b, object: Account, operation: insert, file: b.csv}
+`), ".")
+
+	assert.NoError(t, err)
+	assert.Empty(t, p.Dependencies(p.Steps[0]))
+	assert.Equal(t, []string{"a"}, p.Dependencies(p.Steps[1]))
+}
+
+func TestParse_Errors(t *testing.T) {
+	testCases := []struct {
+		plan     string
+		expected string
+	}{
+		{`steps: []`, "plan has no steps"},
+		{`{steps: [{name: a, object: Account, operation: insert, file: a.csv, fle: b}]}`, "could not parse plan: yaml: unmarshal errors:\n  line 1: field fle not found in type plan.Step"},
+		{`{steps: [{object: Account, operation: insert, file: a.csv}]}`, "step 1 has no name"},
+		{`{steps: [{name: a, operation: insert, file: a.csv}]}`, "step a: object is required"},
+		{`{steps: [{name: a, object: Account, operation: merge, file: a.csv}]}`, "step a: operation must be one of insert, update, upsert, delete or hardDelete"},
+		{`{steps: [{name: a, object: Account, operation: upsert, file: a.csv}]}`, "step a: externalIdFieldName is required for an upsert"},
+		{`{steps: [{name: a, object: Account, operation: insert}]}`, "step a: file is required"},
+		{`{steps: [{name: a, object: Account, operation: insert, file: a.csv, columnDelimiter: COLON}]}`, "step a: invalid columnDelimiter COLON"},
+		{`{steps: [{name: a, object: Account, operation: insert, file: a.csv, dependsOn: [b]}]}`, "step a: depends on unknown step b"},
+		{
+			`{steps: [{name: a, object: Account, operation: insert, file: a.csv}, {name: a, object: Account, operation: insert, file: a.csv}]}`,
+			"step a: name is used by another step",
+		},
+		{
+			`{steps: [{name: a, object: Account, operation: insert, file: a.csv}, {name: b, object: Contact, operation: insert, file: b.csv, references: {AccountId: a}}]}`,
+			"step b: column AccountId refers to step a, which has no key",
+		},
+		{
+			`{steps: [{name: a, object: Account, operation: insert, file: a.csv, dependsOn: [b]}, {name: b, object: Account, operation: insert, file: b.csv, dependsOn: [a]}]}`,
+			"steps depend on each other: a, b",
+		},
+	}
+
+	for _, tc := range testCases {
+		_, err := Parse([]byte(tc.plan), ".")
+		assert.EqualError(t, err, tc.expected, tc.plan)
+	}
+}
@@ -0,0 +1,510 @@
+package plan
+
+import (
+	"bytes"
+	"encoding/csv"
+	"github.com/pkg/errors"
+	"github.com/rfaulhaber/forcedata/auth"
+	"github.com/rfaulhaber/forcedata/charset"
+	"github.com/rfaulhaber/forcedata/describe"
+	"github.com/rfaulhaber/forcedata/job"
+	"github.com/rfaulhaber/forcedata/mapping"
+	"github.com/rfaulhaber/forcedata/pipeline"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// States of a step once a plan has run.
+const (
+	// StateDone means the step's job completed. Some of its records may still have failed.
+	StateDone = "done"
+
+	// StateFailed means the step couldn't be loaded, or its job failed or was aborted.
+	StateFailed = "failed"
+
+	// StateSkipped means the step didn't run because a step it depends on didn't complete.
+	StateSkipped = "skipped"
+)
+
+// DefaultParallel is the number of steps run at once when a Runner doesn't say.
+const DefaultParallel = 3
+
+// Result is the outcome of a single step.
+type Result struct {
+	Step  *Step
+	State string
+
+	// Info is the final info of the step's job, if one was created.
+	Info job.JobInfo
+
+	// Rejected counts rows that weren't uploaded because a reference column named a key that wasn't loaded.
+	Rejected int
+
+	// IDs maps the step's keys to the IDs of the records its job created or updated.
+	IDs map[string]string
+
+	Err error
+}
+
+// Runner runs plans against an org.
+type Runner struct {
+	Session auth.Session
+
+	// Parallel is the most steps run at once. Zero means DefaultParallel.
+	Parallel int
+
+	// PollInterval is how often a running job is checked. Zero means job.DefaultWatchTime.
+	PollInterval time.Duration
+
+	// ResultsDir receives the failed results and rejected rows of each step, as NAME.failed.csv and
+	// NAME.rejects.csv. Zero means the current directory.
+	ResultsDir string
+
+	// Log, if set, is called as steps start and finish.
+	Log func(format string, args ...interface{})
+}
+
+// Run runs every step of the plan, each once its dependencies are done, and returns their results in the order the
+// steps are listed. A step whose dependency didn't complete is skipped.
+func (r *Runner) Run(p *Plan) []Result {
+	parallel := r.Parallel
+
+	if parallel <= 0 {
+		parallel = DefaultParallel
+	}
+
+	results := map[string]*Result{}
+	done := map[string]chan struct{}{}
+
+	for _, s := range p.Steps {
+		results[s.Name] = &Result{Step: s}
+		done[s.Name] = make(chan struct{})
+	}
+
+	order, _ := p.Order()
+	slots := make(chan struct{}, parallel)
+
+	var wg sync.WaitGroup
+
+	for _, s := range order {
+		wg.Add(1)
+
+		go func(s *Step) {
+			defer wg.Done()
+			defer close(done[s.Name])
+
+			res := results[s.Name]
+			deps := map[string]map[string]string{}
+
+			for _, d := range p.Dependencies(s) {
+				<-done[d]
+
+				if results[d].State != StateDone {
+					res.State = StateSkipped
+					res.Err = errors.Errorf("step %s did not complete", d)
+					r.log("%s: skipped, step %s did not complete", s.Name, d)
+					return
+				}
+
+				deps[d] = results[d].IDs
+			}
+
+			slots <- struct{}{}
+			defer func() { <-slots }()
+
+			r.log("%s: loading %s into %s", s.Name, filepath.Base(s.File), s.Object)
+
+			if err := r.runStep(s, deps, res); err != nil {
+				res.State = StateFailed
+				res.Err = err
+				r.log("%s: failed: %s", s.Name, err)
+				return
+			}
+
+			res.State = StateDone
+			r.log("%s: done, %d processed, %d failed, %d rejected", s.Name, res.Info.RecordsProcessed, res.Info.RecordsFailed, res.Rejected)
+		}(s)
+	}
+
+	wg.Wait()
+
+	list := make([]Result, len(p.Steps))
+
+	for i, s := range p.Steps {
+		list[i] = *results[s.Name]
+	}
+
+	return list
+}
+
+func (r *Runner) log(format string, args ...interface{}) {
+	if r.Log != nil {
+		r.Log(format, args...)
+	}
+}
+
+func (r *Runner) runStep(s *Step, deps map[string]map[string]string, res *Result) error {
+	content, err := ioutil.ReadFile(s.File)
+
+	if err != nil {
+		return errors.Wrap(err, "could not read file")
+	}
+
+	if content, _, err = charset.Normalize(content, charset.Auto); err != nil {
+		return err
+	}
+
+	delim, _ := job.GetDelim(s.Delim)
+	comma := delimRune(delim)
+
+	keys := &keyTracker{column: s.Key, ids: map[string][]string{}}
+	stages := []pipeline.Stage{keys.capture()}
+
+	if s.Mapping != "" {
+		m, err := mapping.Load(s.Mapping)
+
+		if err != nil {
+			return err
+		}
+
+		stages = append(stages, m)
+	}
+
+	if len(s.References) > 0 {
+		stages = append(stages, &references{columns: s.References, ids: deps})
+	}
+
+	excluded := s.Exclude
+
+	// without a mapping, the key would be uploaded as it is; it's left out unless the object has a field by its name
+	if s.Key != "" && s.Mapping == "" {
+		sobject, err := describe.Get(r.Session, s.Object)
+
+		if err != nil {
+			return errors.Wrap(err, "could not describe "+s.Object)
+		}
+
+		if _, ok := sobject.Field(s.Key); !ok {
+			excluded = append(append([]string{}, excluded...), s.Key)
+		}
+	}
+
+	if len(excluded) > 0 {
+		stages = append(stages, &exclude{columns: excluded})
+	}
+
+	stages = append(stages, keys.record())
+
+	var upload, rejects bytes.Buffer
+
+	in := csv.NewReader(bytes.NewReader(content))
+	in.Comma = comma
+	in.FieldsPerRecord = -1
+
+	out := csv.NewWriter(&upload)
+	out.Comma = comma
+
+	pl := pipeline.Pipeline{Stages: stages, Rejects: csv.NewWriter(&rejects)}
+	pl.Rejects.Comma = comma
+
+	if err := pl.Run(in, out); err != nil {
+		return err
+	}
+
+	res.Rejected = pl.Rejected
+
+	if pl.Rejected > 0 {
+		if err := r.write(s.Name+".rejects.csv", rejects.Bytes()); err != nil {
+			return err
+		}
+	}
+
+	if pl.Rows == 0 {
+		res.IDs = map[string]string{}
+		return nil
+	}
+
+	j := job.New(s.JobConfig, r.Session)
+
+	if err := j.Create(); err != nil {
+		return errors.Wrap(err, "could not create job")
+	}
+
+	if err := j.Upload(upload.Bytes()); err != nil {
+		return errors.Wrap(err, "could not upload content to job")
+	}
+
+	interval := r.PollInterval
+
+	if interval <= 0 {
+		interval = job.DefaultWatchTime
+	}
+
+	if res.Info, err = j.Wait(interval); err != nil {
+		return errors.Wrap(err, "could not check job")
+	}
+
+	if res.Info.State != "JobComplete" {
+		return errors.Errorf("job %s %s: %s", res.Info.ID, strings.ToLower(res.Info.State), res.Info.ErrorMessage)
+	}
+
+	if res.Info.RecordsFailed > 0 {
+		failed, err := j.GetFailure()
+
+		if err != nil {
+			return err
+		}
+
+		if err := r.write(s.Name+".failed.csv", failed); err != nil {
+			return err
+		}
+	}
+
+	if s.Key == "" {
+		return nil
+	}
+
+	success, err := j.GetSuccess()
+
+	if err != nil {
+		return err
+	}
+
+	res.IDs, err = keys.match(success, comma)
+
+	return err
+}
+
+func (r *Runner) write(name string, content []byte) error {
+	path := filepath.Join(r.ResultsDir, name)
+
+	if err := ioutil.WriteFile(path, content, 0644); err != nil {
+		return errors.Wrap(err, "could not write "+path)
+	}
+
+	return nil
+}
+
+func delimRune(delim string) rune {
+	if delim == "\\t" {
+		return '\t'
+	}
+
+	r, _ := utf8.DecodeRuneInString(delim)
+
+	return r
+}
+
+// references is a stage that replaces keys of earlier steps with the IDs of the records they loaded.
+type references struct {
+	columns map[string]string
+	ids     map[string]map[string]string
+
+	index map[int]string
+	names map[int]string
+}
+
+func (ref *references) Header(header []string) ([]string, error) {
+	ref.index = map[int]string{}
+	ref.names = map[int]string{}
+
+	for col, step := range ref.columns {
+		i := indexOf(header, col)
+
+		if i < 0 {
+			return nil, errors.Errorf("reference column %s not found", col)
+		}
+
+		ref.index[i] = step
+		ref.names[i] = header[i]
+	}
+
+	return header, nil
+}
+
+func (ref *references) Row(row []string) ([]string, error) {
+	for i, step := range ref.index {
+		if i >= len(row) || row[i] == "" {
+			continue
+		}
+
+		id, ok := ref.ids[step][row[i]]
+
+		if !ok {
+			return nil, pipeline.Reject("%s: step %s loaded no record with key %q", ref.names[i], step, row[i])
+		}
+
+		row[i] = id
+	}
+
+	return row, nil
+}
+
+// exclude is a stage that removes columns.
+type exclude struct {
+	columns []string
+
+	keep []int
+}
+
+func (e *exclude) Header(header []string) ([]string, error) {
+	e.keep = nil
+
+	for i, name := range header {
+		if indexOf(e.columns, name) < 0 {
+			e.keep = append(e.keep, i)
+		}
+	}
+
+	return e.Row(header)
+}
+
+func (e *exclude) Row(row []string) ([]string, error) {
+	result := make([]string, len(e.keep))
+
+	for i, k := range e.keep {
+		if k < len(row) {
+			result[i] = row[k]
+		}
+	}
+
+	return result, nil
+}
+
+// keyTracker remembers which key each uploaded row came from, so that the IDs in the job's successful results can be
+// matched back to keys. The Bulk API doesn't return results in the order records were uploaded, so rows are matched
+// by their values.
+type keyTracker struct {
+	column string
+
+	index   int
+	current string
+	header  []string
+
+	// ids holds the keys of the uploaded rows with the given values.
+	ids map[string][]string
+}
+
+func (k *keyTracker) capture() pipeline.Stage {
+	return keyCapture{k}
+}
+
+func (k *keyTracker) record() pipeline.Stage {
+	return keyRecord{k}
+}
+
+type keyCapture struct {
+	k *keyTracker
+}
+
+func (c keyCapture) Header(header []string) ([]string, error) {
+	c.k.index = -1
+
+	if c.k.column != "" {
+		if c.k.index = indexOf(header, c.k.column); c.k.index < 0 {
+			return nil, errors.Errorf("key column %s not found", c.k.column)
+		}
+	}
+
+	return header, nil
+}
+
+func (c keyCapture) Row(row []string) ([]string, error) {
+	c.k.current = ""
+
+	if c.k.index >= 0 && c.k.index < len(row) {
+		c.k.current = row[c.k.index]
+	}
+
+	return row, nil
+}
+
+type keyRecord struct {
+	k *keyTracker
+}
+
+func (r keyRecord) Header(header []string) ([]string, error) {
+	r.k.header = header
+	return header, nil
+}
+
+func (r keyRecord) Row(row []string) ([]string, error) {
+	if r.k.index >= 0 {
+		values := strings.Join(row, "\x00")
+		r.k.ids[values] = append(r.k.ids[values], r.k.current)
+	}
+
+	return row, nil
+}
+
+// match reads a job's successful results, returning the ID of each key.
+func (k *keyTracker) match(results []byte, comma rune) (map[string]string, error) {
+	reader := csv.NewReader(bytes.NewReader(results))
+	reader.Comma = comma
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+
+	if err == io.EOF {
+		return map[string]string{}, nil
+	}
+
+	if err != nil {
+		return nil, errors.Wrap(err, "could not read successful results")
+	}
+
+	idColumn := indexOf(header, "sf__Id")
+	columns := make([]int, len(k.header))
+
+	for i, name := range k.header {
+		if columns[i] = indexOf(header, name); columns[i] < 0 {
+			return nil, errors.Errorf("successful results have no %s column", name)
+		}
+	}
+
+	ids := map[string]string{}
+
+	for {
+		row, err := reader.Read()
+
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			return nil, errors.Wrap(err, "could not read successful results")
+		}
+
+		values := make([]string, len(columns))
+
+		for i, c := range columns {
+			if c < len(row) {
+				values[i] = row[c]
+			}
+		}
+
+		joined := strings.Join(values, "\x00")
+
+		if keys := k.ids[joined]; len(keys) > 0 && idColumn >= 0 {
+			ids[keys[0]] = row[idColumn]
+			k.ids[joined] = keys[1:]
+		}
+	}
+
+	return ids, nil
+}
+
+func indexOf(header []string, name string) int {
+	for i, h := range header {
+		if strings.EqualFold(h, name) {
+			return i
+		}
+	}
+
+	return -1
+}
@@ -0,0 +1,58 @@
+package plan
+
+import (
+	"github.com/rfaulhaber/forcedata/jobtest"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRunner_Run(t *testing.T) {
+	sim := jobtest.New()
+	server := sim.Start()
+	defer server.Close()
+
+	dir, err := ioutil.TempDir("", "plan")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	p, err := Load("testdata/plan.yaml")
+	assert.NoError(t, err)
+
+	r := Runner{Session: sim.Session(server.URL), PollInterval: time.Millisecond, ResultsDir: dir}
+	results := r.Run(p)
+
+	assert.Len(t, results, 5)
+
+	accounts := sim.Records("Account")
+	assert.Len(t, accounts, 3)
+	assert.Equal(t, StateDone, results[0].State)
+	assert.Equal(t, map[string]string{"A1": accounts[0]["Id"], "A2": accounts[1]["Id"], "A3": accounts[2]["Id"]}, results[0].IDs)
+
+	contacts := sim.Records("Contact")
+	assert.Equal(t, StateDone, results[1].State)
+	assert.Equal(t, 1, results[1].Rejected)
+	assert.Len(t, contacts, 3)
+	assert.Equal(t, accounts[0]["Id"], contacts[0]["AccountId"])
+	assert.Equal(t, accounts[2]["Id"], contacts[1]["AccountId"])
+	assert.Equal(t, "", contacts[2]["AccountId"])
+	assert.Equal(t, map[string]string{"C1": contacts[0]["Id"], "C2": contacts[1]["Id"], "C4": contacts[2]["Id"]}, results[1].IDs)
+
+	rejects, err := ioutil.ReadFile(filepath.Join(dir, "contacts.rejects.csv"))
+	assert.NoError(t, err)
+	assert.Equal(t, "Legacy Id,Last,Company,forcedata__Error\nC3,Brown,A9,\"AccountId: step accounts loaded no record with key \"\"A9\"\"\"\n", string(rejects))
+
+	assert.Equal(t, StateDone, results[2].State)
+	assert.Equal(t, accounts[1]["Id"], sim.Records("Opportunity")[0]["AccountId"])
+
+	assert.Equal(t, StateFailed, results[3].State)
+	assert.Contains(t, results[3].Err.Error(), "job 750MOCK")
+	assert.Contains(t, results[3].Err.Error(), "failed: InvalidBatch : Field name not found : Id")
+
+	assert.Equal(t, StateSkipped, results[4].State)
+	assert.EqualError(t, results[4].Err, "step broken did not complete")
+	assert.Empty(t, sim.Records("Case"))
+}
@@ -0,0 +1,133 @@
+// Package query runs SOQL queries through the REST API. It's meant for the small lookups forcedata makes on its own
+// behalf; exporting data goes through Bulk API query jobs.
+package query
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/pkg/errors"
+	"github.com/rfaulhaber/forcedata/auth"
+	"github.com/rfaulhaber/forcedata/job"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Record is a single record returned by a query. Fields of related records are nested under the relationship name.
+type Record map[string]interface{}
+
+// Result is one page of query results.
+type Result struct {
+	TotalSize      int      `json:"totalSize"`
+	Done           bool     `json:"done"`
+	NextRecordsURL string   `json:"nextRecordsUrl"`
+	Records        []Record `json:"records"`
+}
+
+// All runs soql, following nextRecordsUrl until every record has been read.
+func All(session auth.Session, soql string) ([]Record, error) {
+	result, err := get(session, session.DataURL()+"/query?q="+url.QueryEscape(soql))
+
+	if err != nil {
+		return nil, err
+	}
+
+	records := result.Records
+
+	for !result.Done && result.NextRecordsURL != "" {
+		if result, err = get(session, session.InstanceURL+result.NextRecordsURL); err != nil {
+			return nil, err
+		}
+
+		records = append(records, result.Records...)
+	}
+
+	return records, nil
+}
+
+func get(session auth.Session, endpoint string) (Result, error) {
+	req, err := http.NewRequest("GET", endpoint, nil)
+
+	if err != nil {
+		return Result{}, errors.Wrap(err, "could not generate query request")
+	}
+
+	req.Header.Add("Accept", "application/json")
+	req.Header.Add("Authorization", "Bearer "+session.AccessToken)
+
+	resp, err := http.DefaultClient.Do(req)
+
+	if err != nil {
+		return Result{}, errors.Wrap(err, "query request failed")
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return Result{}, errors.Wrap(job.NewRequestError(resp), "query")
+	}
+
+	var result Result
+
+	// numbers are kept as written so that large values and decimals aren't reformatted
+	dec := json.NewDecoder(resp.Body)
+	dec.UseNumber()
+
+	if err := dec.Decode(&result); err != nil {
+		return Result{}, errors.Wrap(err, "could not parse query response")
+	}
+
+	return result, nil
+}
+
+// String returns the value of field as a string, following dotted paths through related records. It returns an
+// empty string for null or missing values.
+func (r Record) String(field string) string {
+	var value interface{} = map[string]interface{}(r)
+
+	for _, part := range strings.Split(field, ".") {
+		m, ok := value.(map[string]interface{})
+
+		if !ok {
+			return ""
+		}
+
+		if value, ok = m[part]; !ok {
+			value = nil
+
+			for k, v := range m {
+				if strings.EqualFold(k, part) {
+					value = v
+					break
+				}
+			}
+		}
+	}
+
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case map[string]interface{}, []interface{}:
+		return ""
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// Quote returns value as a SOQL string literal.
+func Quote(value string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(value) + "'"
+}
+
+// In returns a SOQL IN list of values, e.g. ('a', 'b').
+func In(values []string) string {
+	quoted := make([]string, len(values))
+
+	for i, v := range values {
+		quoted[i] = Quote(v)
+	}
+
+	return "(" + strings.Join(quoted, ", ") + ")"
+}
@@ -0,0 +1,58 @@
+package query
+
+import (
+	"github.com/rfaulhaber/forcedata/auth"
+	"github.com/rfaulhaber/forcedata/jobtest"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAll(t *testing.T) {
+	sim := jobtest.New()
+	server := sim.Start()
+	defer server.Close()
+
+	ids := sim.Insert("Account", jobtest.Record{"Name": "O'Neil, Inc.", "NumberOfEmployees": "12"}, jobtest.Record{"Name": "Globex"})
+	sim.Insert("Contact", jobtest.Record{"LastName": "Smith", "AccountId": ids[0]})
+
+	records, err := All(sim.Session(server.URL), "SELECT Id, Name FROM Account WHERE Name IN ('o\\'neil, inc.', 'Initech')")
+
+	assert.NoError(t, err)
+	assert.Len(t, records, 1)
+	assert.Equal(t, ids[0], records[0].String("Id"))
+
+	records, err = All(sim.Session(server.URL), "SELECT LastName, Account.Name FROM Contact")
+
+	assert.NoError(t, err)
+	assert.Equal(t, "O'Neil, Inc.", records[0].String("account.name"))
+	assert.Equal(t, "", records[0].String("Account.Missing"))
+}
+
+func TestAll_Pages(t *testing.T) {
+	var paths []string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.RequestURI())
+
+		if r.URL.Path == "/services/data/v44.0/query" {
+			w.Write([]byte(`{"totalSize": 2, "done": false, "nextRecordsUrl": "/services/data/v44.0/query/01g-2000", "records": [{"Id": "1", "Amount": 1234567.5}]}`))
+		} else {
+			w.Write([]byte(`{"totalSize": 2, "done": true, "records": [{"Id": "2", "Amount": null}]}`))
+		}
+	}))
+	defer server.Close()
+
+	records, err := All(auth.Session{InstanceURL: server.URL, APIVersion: "44.0"}, "SELECT Id FROM Opportunity")
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"/services/data/v44.0/query?q=SELECT+Id+FROM+Opportunity", "/services/data/v44.0/query/01g-2000"}, paths)
+	assert.Len(t, records, 2)
+	assert.Equal(t, "1234567.5", records[0].String("Amount"))
+	assert.Equal(t, "", records[1].String("Amount"))
+}
+
+func TestIn(t *testing.T) {
+	assert.Equal(t, `('a', 'O\'Neil', 'back\\slash')`, In([]string{"a", "O'Neil", `back\slash`}))
+}
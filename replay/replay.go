@@ -0,0 +1,359 @@
+// Package replay records HTTP exchanges to a directory and plays them back, so that a session against a real org can
+// be reproduced locally. Bearer tokens, passwords and client secrets are redacted before anything is written.
+package replay
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/pkg/errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Redacted replaces every secret value in a recording.
+const Redacted = "REDACTED"
+
+// secretKeys are redacted wherever they appear as query parameters, form fields or JSON object keys.
+var secretKeys = map[string]bool{
+	"access_token":  true,
+	"refresh_token": true,
+	"password":      true,
+	"client_secret": true,
+}
+
+// Exchange is a single recorded request and its response.
+type Exchange struct {
+	Request  Request  `json:"request"`
+	Response Response `json:"response"`
+}
+
+// Request is the recorded part of an http.Request.
+type Request struct {
+	Method string      `json:"method"`
+	URL    string      `json:"url"`
+	Header http.Header `json:"header"`
+	Body   string      `json:"body"`
+}
+
+// Response is the recorded part of an http.Response.
+type Response struct {
+	StatusCode int         `json:"status_code"`
+	Header     http.Header `json:"header"`
+	Body       string      `json:"body"`
+}
+
+// Recorder is an http.RoundTripper that saves every exchange made through it to a directory, one JSON file per
+// exchange, numbered in the order they were made.
+type Recorder struct {
+	dir       string
+	transport http.RoundTripper
+
+	mu    sync.Mutex
+	count int
+}
+
+// NewRecorder returns a Recorder that writes to dir, creating it if needed, and sends requests through transport. If
+// transport is nil, http.DefaultTransport is used.
+func NewRecorder(dir string, transport http.RoundTripper) (*Recorder, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, errors.Wrap(err, "could not create recording directory")
+	}
+
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+
+	return &Recorder{dir: dir, transport: transport}, nil
+}
+
+// RoundTrip sends the request and records the exchange.
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	reqBody, err := readBody(&req.Body)
+
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := r.transport.RoundTrip(req)
+
+	if err != nil {
+		return nil, err
+	}
+
+	respBody, err := readBody(&resp.Body)
+
+	if err != nil {
+		return nil, err
+	}
+
+	exchange := Exchange{
+		Request: Request{
+			Method: req.Method,
+			URL:    redactURL(req.URL),
+			Header: redactHeader(req.Header),
+			Body:   redactBody(req.Header.Get("Content-Type"), reqBody),
+		},
+		Response: Response{
+			StatusCode: resp.StatusCode,
+			Header:     redactHeader(resp.Header),
+			Body:       redactBody(resp.Header.Get("Content-Type"), respBody),
+		},
+	}
+
+	if err := r.save(req, exchange); err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+func (r *Recorder) save(req *http.Request, exchange Exchange) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.count++
+
+	b, err := json.MarshalIndent(exchange, "", "\t")
+
+	if err != nil {
+		return errors.Wrap(err, "could not encode recording")
+	}
+
+	name := fmt.Sprintf("%04d-%s%s.json", r.count, req.Method, unsafeChars.ReplaceAllString(req.URL.Path, "-"))
+
+	return errors.Wrap(ioutil.WriteFile(filepath.Join(r.dir, name), b, 0600), "could not write recording")
+}
+
+var unsafeChars = regexp.MustCompile(`[^A-Za-z0-9.]+`)
+
+// Player is an http.RoundTripper that answers requests from a directory of recordings instead of the network. Each
+// request is answered by the first recording not yet used with the same method and path, ignoring the host and any
+// redacted values.
+type Player struct {
+	mu        sync.Mutex
+	exchanges []Exchange
+	used      []bool
+}
+
+// NewPlayer loads every recording in dir.
+func NewPlayer(dir string) (*Player, error) {
+	names, err := filepath.Glob(filepath.Join(dir, "*.json"))
+
+	if err != nil {
+		return nil, err
+	}
+
+	if len(names) == 0 {
+		return nil, errors.Errorf("no recordings found in %s", dir)
+	}
+
+	sort.Strings(names)
+
+	p := &Player{}
+
+	for _, name := range names {
+		b, err := ioutil.ReadFile(name)
+
+		if err != nil {
+			return nil, errors.Wrap(err, "could not read recording")
+		}
+
+		var exchange Exchange
+
+		if err := json.Unmarshal(b, &exchange); err != nil {
+			return nil, errors.Wrapf(err, "could not parse recording %s", name)
+		}
+
+		p.exchanges = append(p.exchanges, exchange)
+	}
+
+	p.used = make([]bool, len(p.exchanges))
+
+	return p, nil
+}
+
+// RoundTrip answers the request with its matching recording.
+func (p *Player) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body != nil {
+		req.Body.Close()
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for i, exchange := range p.exchanges {
+		if p.used[i] || !matches(exchange.Request, req) {
+			continue
+		}
+
+		p.used[i] = true
+
+		return &http.Response{
+			Status:        fmt.Sprintf("%d %s", exchange.Response.StatusCode, http.StatusText(exchange.Response.StatusCode)),
+			StatusCode:    exchange.Response.StatusCode,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        exchange.Response.Header,
+			Body:          ioutil.NopCloser(strings.NewReader(exchange.Response.Body)),
+			ContentLength: int64(len(exchange.Response.Body)),
+			Request:       req,
+		}, nil
+	}
+
+	return nil, errors.Errorf("replay: no recording left for %s %s", req.Method, req.URL.Path)
+}
+
+// Remaining returns the number of recordings that haven't been played back yet.
+func (p *Player) Remaining() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	count := 0
+
+	for _, used := range p.used {
+		if !used {
+			count++
+		}
+	}
+
+	return count
+}
+
+func matches(recorded Request, req *http.Request) bool {
+	if recorded.Method != req.Method {
+		return false
+	}
+
+	u, err := url.Parse(recorded.URL)
+
+	if err != nil || strings.TrimSuffix(u.Path, "/") != strings.TrimSuffix(req.URL.Path, "/") {
+		return false
+	}
+
+	actual := req.URL.Query()
+
+	for key, values := range u.Query() {
+		if len(values) > 0 && values[0] != Redacted && actual.Get(key) != values[0] {
+			return false
+		}
+	}
+
+	return true
+}
+
+func readBody(body *io.ReadCloser) ([]byte, error) {
+	if *body == nil {
+		return nil, nil
+	}
+
+	b, err := ioutil.ReadAll(*body)
+	(*body).Close()
+
+	if err != nil {
+		return nil, errors.Wrap(err, "could not read body for recording")
+	}
+
+	*body = ioutil.NopCloser(bytes.NewReader(b))
+
+	return b, nil
+}
+
+func redactURL(u *url.URL) string {
+	c := *u
+	q := c.Query()
+
+	for key := range q {
+		if secretKeys[strings.ToLower(key)] {
+			q.Set(key, Redacted)
+		}
+	}
+
+	c.RawQuery = q.Encode()
+
+	return c.String()
+}
+
+func redactHeader(header http.Header) http.Header {
+	c := http.Header{}
+
+	for key, values := range header {
+		c[key] = append([]string{}, values...)
+	}
+
+	if auth := c.Get("Authorization"); auth != "" {
+		if strings.HasPrefix(auth, "Bearer ") {
+			c.Set("Authorization", "Bearer "+Redacted)
+		} else {
+			c.Set("Authorization", Redacted)
+		}
+	}
+
+	// session cookies authenticate as well as tokens do
+	for _, key := range []string{"Cookie", "Set-Cookie"} {
+		for i := range c[key] {
+			c[key][i] = Redacted
+		}
+	}
+
+	return c
+}
+
+func redactBody(contentType string, body []byte) string {
+	if strings.HasPrefix(contentType, "application/x-www-form-urlencoded") {
+		if values, err := url.ParseQuery(string(body)); err == nil {
+			for key := range values {
+				if secretKeys[strings.ToLower(key)] {
+					values.Set(key, Redacted)
+				}
+			}
+
+			return values.Encode()
+		}
+	}
+
+	var v interface{}
+
+	if err := json.Unmarshal(body, &v); err == nil {
+		if redactJSON(v) {
+			b, _ := json.Marshal(v)
+			return string(b)
+		}
+	}
+
+	return string(body)
+}
+
+// redactJSON replaces secret values in a decoded JSON value in place, returning true if anything was replaced.
+func redactJSON(v interface{}) bool {
+	redacted := false
+
+	switch t := v.(type) {
+	case map[string]interface{}:
+		for key, value := range t {
+			if secretKeys[strings.ToLower(key)] {
+				t[key] = Redacted
+				redacted = true
+			} else if redactJSON(value) {
+				redacted = true
+			}
+		}
+	case []interface{}:
+		for _, value := range t {
+			if redactJSON(value) {
+				redacted = true
+			}
+		}
+	}
+
+	return redacted
+}
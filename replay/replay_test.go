@@ -0,0 +1,137 @@
+package replay
+
+import (
+	"github.com/rfaulhaber/forcedata/auth"
+	"github.com/rfaulhaber/forcedata/job"
+	"github.com/rfaulhaber/forcedata/jobtest"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRecordAndReplay(t *testing.T) {
+	dir, err := ioutil.TempDir("", "forcedata-replay")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer os.RemoveAll(dir)
+
+	sim := jobtest.New()
+	server := sim.Start()
+
+	recorder, err := NewRecorder(dir, nil)
+	assert.NoError(t, err)
+
+	creds := auth.Credential{
+		Username:     "test@example.com",
+		Password:     "MyPassword123!!!",
+		ClientID:     "SomeReallyLongClientId123456",
+		ClientSecret: "somethingVerySecret",
+		URL:          server.URL,
+	}
+
+	recorded := runLoad(t, recorder, creds)
+
+	server.Close()
+
+	files, _ := filepath.Glob(filepath.Join(dir, "*.json"))
+	assert.Len(t, files, 6)
+
+	for _, file := range files {
+		b, _ := ioutil.ReadFile(file)
+
+		assert.NotContains(t, string(b), creds.Password)
+		assert.NotContains(t, string(b), creds.ClientSecret)
+		assert.NotContains(t, string(b), recorded.AccessToken)
+	}
+
+	player, err := NewPlayer(dir)
+	assert.NoError(t, err)
+
+	replayed := runLoad(t, player, creds)
+
+	assert.Equal(t, recorded.InstanceURL, replayed.InstanceURL)
+	assert.Equal(t, 0, player.Remaining())
+}
+
+func TestPlayer_NoRecording(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "forcedata-replay")
+	defer os.RemoveAll(dir)
+
+	ioutil.WriteFile(filepath.Join(dir, "0001-GET-services-data.json"), []byte(`{
+		"request": {"method": "GET", "url": "https://na1.salesforce.com/services/data/"},
+		"response": {"status_code": 200, "body": "[]"}
+	}`), 0600)
+
+	player, err := NewPlayer(dir)
+	assert.NoError(t, err)
+
+	client := &http.Client{Transport: player}
+
+	resp, err := client.Get("https://other.salesforce.com/services/data/")
+	assert.NoError(t, err)
+	assert.Equal(t, 200, resp.StatusCode)
+
+	_, err = client.Get("https://other.salesforce.com/services/data/")
+	assert.Error(t, err)
+}
+
+func TestRedactBody(t *testing.T) {
+	assert.Equal(t, `{"access_token":"REDACTED","id":"123"}`, redactBody("application/json", []byte(`{"access_token":"abc","id":"123"}`)))
+	assert.Equal(t, "client_secret=REDACTED&username=me", redactBody("application/x-www-form-urlencoded", []byte("client_secret=abc&username=me")))
+	assert.Equal(t, "Name\nAcme\n", redactBody("text/csv", []byte("Name\nAcme\n")))
+}
+
+func TestRedactHeader(t *testing.T) {
+	header := http.Header{
+		"Authorization": {"Bearer abc"},
+		"Cookie":        {"sid=abc"},
+		"Set-Cookie":    {"sid=abc; Secure", "BrowserId=def"},
+		"Content-Type":  {"text/csv"},
+	}
+
+	assert.Equal(t, http.Header{
+		"Authorization": {"Bearer REDACTED"},
+		"Cookie":        {"REDACTED"},
+		"Set-Cookie":    {"REDACTED", "REDACTED"},
+		"Content-Type":  {"text/csv"},
+	}, redactHeader(header))
+	assert.Equal(t, "sid=abc", header.Get("Cookie"))
+}
+
+// runLoad authenticates and runs an insert job with http.DefaultClient sending requests through transport.
+func runLoad(t *testing.T, transport http.RoundTripper, creds auth.Credential) auth.Session {
+	original := http.DefaultClient.Transport
+	http.DefaultClient.Transport = transport
+
+	defer func() {
+		http.DefaultClient.Transport = original
+	}()
+
+	session, err := auth.SendAuthRequest(creds)
+	assert.NoError(t, err)
+
+	j := job.New(job.JobConfig{Object: "Account", Operation: "insert", ContentType: "CSV", Delim: "COMMA"}, session)
+
+	assert.NoError(t, j.Create())
+	assert.NoError(t, j.Upload([]byte("Name\nAcme\n")))
+
+	info, err := j.GetInfo()
+
+	assert.NoError(t, err)
+	assert.Equal(t, "JobComplete", info.State)
+	assert.Equal(t, uint(1), info.RecordsProcessed)
+
+	_, err = auth.Versions(session)
+	assert.NoError(t, err)
+
+	assert.True(t, strings.HasPrefix(j.ID(), "750"))
+
+	return session
+}
@@ -0,0 +1,292 @@
+// Package report writes summaries of what loads did, as Markdown or HTML, to attach to change tickets as evidence: the
+// job config, how long each job took, how many records succeeded and failed, the failures grouped by error code and
+// field with sample rows, and where the result files are.
+package report
+
+import (
+	"bytes"
+	"github.com/pkg/errors"
+	"github.com/rfaulhaber/forcedata/job"
+	"github.com/rfaulhaber/forcedata/retry"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Formats reports can be written in.
+const (
+	Markdown = "markdown"
+	HTML     = "html"
+)
+
+// Samples is the number of failed rows shown for each group of failures.
+const Samples = 3
+
+// Report is a summary of one or more jobs that loaded a file, or of a single job.
+type Report struct {
+	Title     string
+	Generated time.Time
+
+	// File is the file loaded, if it's known.
+	File   string
+	Config job.JobConfig
+	Jobs   []Job
+
+	Failures []Failure
+}
+
+// Job is a job of a report.
+type Job struct {
+	// Chunk is the 1-based number of the chunk of a load the job loaded, or zero for reports of a single job.
+	Chunk int
+	Info  job.JobInfo
+
+	// Results link to the job's result files.
+	Results []Link
+}
+
+// Link is a named link to a result file, saved locally or in the org.
+type Link struct {
+	Name string
+	URL  string
+}
+
+// Failure is a group of records that failed with the same error code on the same fields.
+type Failure struct {
+	Code   string
+	Fields string
+
+	// Message is the message of the first record of the group.
+	Message string
+	Count   int
+
+	// Header and Samples are the columns uploaded and the first rows of the group.
+	Header  []string
+	Samples [][]string
+}
+
+// Processed returns the number of records the report's jobs processed.
+func (r *Report) Processed() uint {
+	var n uint
+
+	for _, j := range r.Jobs {
+		n += j.Info.RecordsProcessed
+	}
+
+	return n
+}
+
+// Failed returns the number of records the report's jobs failed to load.
+func (r *Report) Failed() uint {
+	var n uint
+
+	for _, j := range r.Jobs {
+		n += j.Info.RecordsFailed
+	}
+
+	return n
+}
+
+// Succeeded returns the number of records the report's jobs loaded.
+func (r *Report) Succeeded() uint {
+	return r.Processed() - r.Failed()
+}
+
+// fieldList matches the fields listed at the end of some errors, as in "Required fields are missing: [LastName]".
+var fieldList = regexp.MustCompile(`\[([^\[\]]*)\]\s*$`)
+
+// Fields returns the fields an error from failed results is about, or an empty string if it doesn't say. The Bulk API
+// ends some errors with the fields, as in "...:LastName --", and others list them in brackets.
+func Fields(err string) string {
+	if trimmed := strings.TrimSpace(err); strings.HasSuffix(trimmed, "--") {
+		trimmed = strings.TrimSpace(strings.TrimSuffix(trimmed, "--"))
+
+		if i := strings.LastIndex(trimmed, ":"); i >= 0 {
+			if fields := strings.TrimSpace(trimmed[i+1:]); fields != "" {
+				return fields
+			}
+		}
+	}
+
+	if m := fieldList.FindStringSubmatch(err); m != nil {
+		return strings.TrimSpace(m[1])
+	}
+
+	return ""
+}
+
+// message returns an error from failed results without its code or trailing fields.
+func message(err string) string {
+	if i := strings.Index(err, ":"); i >= 0 {
+		err = err[i+1:]
+	}
+
+	if trimmed := strings.TrimSpace(err); strings.HasSuffix(trimmed, "--") {
+		if i := strings.LastIndex(trimmed, ":"); i >= 0 {
+			err = trimmed[:i]
+		}
+	}
+
+	return strings.TrimSpace(err)
+}
+
+// Group groups the rows of failed results by error code and fields, the largest groups first, keeping the first
+// Samples rows of each. Results with different headers are grouped together, each sample keeping its own columns.
+func Group(results ...*retry.Results) []Failure {
+	var failures []*Failure
+
+	groups := make(map[string]*Failure)
+
+	for _, res := range results {
+		errorColumn, header := columns(res.Header)
+
+		for _, row := range res.Rows {
+			var text string
+
+			if errorColumn < len(row) {
+				text = row[errorColumn]
+			}
+
+			code, fields := retry.Code(text), Fields(text)
+			key := code + "\x00" + fields
+
+			f, ok := groups[key]
+
+			if !ok {
+				f = &Failure{Code: code, Fields: fields, Message: message(text)}
+				groups[key] = f
+				failures = append(failures, f)
+			}
+
+			f.Count++
+
+			if len(f.Samples) < Samples && (f.Header == nil || equal(f.Header, header)) {
+				f.Header = header
+				f.Samples = append(f.Samples, uploaded(res.Header, row))
+			}
+		}
+	}
+
+	sort.SliceStable(failures, func(i, j int) bool { return failures[i].Count > failures[j].Count })
+
+	list := make([]Failure, len(failures))
+
+	for i, f := range failures {
+		list[i] = *f
+	}
+
+	return list
+}
+
+// columns returns the index of the error column of failed results, and the columns that were uploaded.
+func columns(header []string) (int, []string) {
+	errorColumn := len(header)
+	var uploadedColumns []string
+
+	for i, name := range header {
+		switch name {
+		case retry.ErrorColumn:
+			errorColumn = i
+		case retry.IDColumn:
+		default:
+			uploadedColumns = append(uploadedColumns, name)
+		}
+	}
+
+	return errorColumn, uploadedColumns
+}
+
+// uploaded returns the values of a row of failed results without the columns the Bulk API added.
+func uploaded(header, row []string) []string {
+	var values []string
+
+	for i, value := range row {
+		if i < len(header) && (header[i] == retry.ErrorColumn || header[i] == retry.IDColumn) {
+			continue
+		}
+
+		values = append(values, value)
+	}
+
+	return values
+}
+
+func equal(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Format returns the format of a report written to path, from its extension: .md or .markdown for Markdown, and .html
+// or .htm for HTML.
+func Format(path string) (string, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".md", ".markdown":
+		return Markdown, nil
+	case ".html", ".htm":
+		return HTML, nil
+	}
+
+	return "", errors.Errorf("summary must be a .md or .html file, not %s", path)
+}
+
+// Write writes the report to path, in the format its extension calls for.
+func (r *Report) Write(path string) error {
+	format, err := Format(path)
+
+	if err != nil {
+		return err
+	}
+
+	b, err := r.Render(format)
+
+	if err != nil {
+		return err
+	}
+
+	return errors.Wrap(ioutil.WriteFile(path, b, 0644), "could not write summary")
+}
+
+// Render returns the report in the given format.
+func (r *Report) Render(format string) ([]byte, error) {
+	var buf bytes.Buffer
+	var err error
+
+	switch format {
+	case Markdown:
+		err = markdownTemplate.Execute(&buf, r)
+	case HTML:
+		err = htmlTemplate.Execute(&buf, r)
+	default:
+		return nil, errors.Errorf("%q is not a report format; use markdown or html", format)
+	}
+
+	if err != nil {
+		return nil, errors.Wrap(err, "could not write summary")
+	}
+
+	return buf.Bytes(), nil
+}
+
+// milliseconds returns a processing time, which the Bulk API reports in milliseconds, as a duration.
+func milliseconds(ms interface{}) string {
+	switch v := ms.(type) {
+	case uint:
+		return (time.Duration(v) * time.Millisecond).String()
+	case int:
+		return (time.Duration(v) * time.Millisecond).String()
+	}
+
+	return ""
+}
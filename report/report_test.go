@@ -0,0 +1,94 @@
+package report
+
+import (
+	"github.com/rfaulhaber/forcedata/job"
+	"github.com/rfaulhaber/forcedata/retry"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testFailed = `"sf__Id","sf__Error",Name,LastName
+"","REQUIRED_FIELD_MISSING:Required fields are missing: [LastName]",Acme,
+"","REQUIRED_FIELD_MISSING:Required fields are missing: [LastName]",Globex,
+"","FIELD_CUSTOM_VALIDATION_EXCEPTION:Name can't contain a pipe:Name --","Hoo|li",Smith
+"","REQUIRED_FIELD_MISSING:Required fields are missing: [LastName]",Initech,
+"","REQUIRED_FIELD_MISSING:Required fields are missing: [LastName]",Umbrella,
+`
+
+func TestFields(t *testing.T) {
+	assert.Equal(t, "LastName", Fields("REQUIRED_FIELD_MISSING:Required fields are missing: [LastName]"))
+	assert.Equal(t, "Name, Phone", Fields("FIELD_CUSTOM_VALIDATION_EXCEPTION:Check these:Name, Phone --"))
+	assert.Equal(t, "", Fields("UNABLE_TO_LOCK_ROW:unable to obtain exclusive access to this record"))
+}
+
+func TestGroup(t *testing.T) {
+	res, err := retry.Parse([]byte(testFailed), ',')
+	assert.NoError(t, err)
+
+	failures := Group(res)
+
+	assert.Len(t, failures, 2)
+	assert.Equal(t, Failure{
+		Code:    "REQUIRED_FIELD_MISSING",
+		Fields:  "LastName",
+		Message: "Required fields are missing: [LastName]",
+		Count:   4,
+		Header:  []string{"Name", "LastName"},
+		Samples: [][]string{{"Acme", ""}, {"Globex", ""}, {"Initech", ""}},
+	}, failures[0])
+	assert.Equal(t, "Name can't contain a pipe", failures[1].Message)
+	assert.Equal(t, 1, failures[1].Count)
+}
+
+func TestReport_Render(t *testing.T) {
+	res, err := retry.Parse([]byte(testFailed), ',')
+	assert.NoError(t, err)
+
+	r := &Report{
+		Title:     "Load of contacts.csv into Contact",
+		Generated: time.Date(2019, 3, 1, 12, 0, 0, 0, time.UTC),
+		File:      "contacts.csv",
+		Config:    job.JobConfig{Object: "Contact", Operation: "insert", ContentType: "CSV", Delim: "COMMA"},
+		Jobs: []Job{{
+			Chunk:   1,
+			Info:    job.JobInfo{ID: "750A", State: "JobComplete", RecordsProcessed: 10, RecordsFailed: 5, TotalProcessingTime: 1500, ErrorMessage: "Failed on *row* <2>"},
+			Results: []Link{{Name: "Chunk 1 failed records, saved", URL: "journal/chunk 1.failed.csv"}},
+		}},
+		Failures: Group(res),
+	}
+
+	b, err := r.Render(Markdown)
+	assert.NoError(t, err)
+
+	md := string(b)
+
+	assert.True(t, strings.HasPrefix(md, "# Load of contacts.csv into Contact\n\nGenerated 2019-03-01 12:00:00 UTC.\n"))
+	assert.Contains(t, md, "| 10 | 5 | 5 |\n")
+	assert.Contains(t, md, "| 1 | 750A | JobComplete | 10 | 5 |  |  | 1.5s | 0s | 0s |\n")
+	assert.Contains(t, md, "### REQUIRED_FIELD_MISSING on LastName: 4 records\n")
+	assert.Contains(t, md, "### FIELD_CUSTOM_VALIDATION_EXCEPTION on Name: 1 record\n")
+	assert.Contains(t, md, "| Hoo\\|li | Smith |\n")
+	assert.Contains(t, md, "\nRequired fields are missing: \\[LastName\\]\n")
+	assert.Contains(t, md, "Job 750A failed: Failed on \\*row\\* \\<2\\>\n")
+	assert.Contains(t, md, "- [Chunk 1 failed records, saved](<journal/chunk 1.failed.csv>)\n")
+
+	b, err = r.Render(HTML)
+	assert.NoError(t, err)
+	assert.Contains(t, string(b), "<td>Hoo|li</td><td>Smith</td>")
+	assert.Contains(t, string(b), "Name can&#39;t contain a pipe")
+}
+
+func TestFormat(t *testing.T) {
+	format, err := Format("report.MD")
+	assert.NoError(t, err)
+	assert.Equal(t, Markdown, format)
+
+	format, err = Format("out/report.html")
+	assert.NoError(t, err)
+	assert.Equal(t, HTML, format)
+
+	_, err = Format("report.pdf")
+	assert.EqualError(t, err, "summary must be a .md or .html file, not report.pdf")
+}
@@ -0,0 +1,180 @@
+package report
+
+import (
+	htmltemplate "html/template"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// Chunked reports whether the report's jobs loaded the chunks of a load, so each has a chunk number.
+func (r *Report) Chunked() bool {
+	for _, j := range r.Jobs {
+		if j.Chunk > 0 {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Links returns the links to the result files of every job.
+func (r *Report) Links() []Link {
+	var links []Link
+
+	for _, j := range r.Jobs {
+		links = append(links, j.Results...)
+	}
+
+	return links
+}
+
+var funcs = map[string]interface{}{
+	"ms": milliseconds,
+	"date": func(t time.Time) string {
+		return t.UTC().Format("2006-01-02 15:04:05 MST")
+	},
+}
+
+var markdownTemplate = template.Must(template.New("markdown").Funcs(funcs).Funcs(template.FuncMap{
+	"cell": cell,
+	"text": text,
+	"row": func(cells []string) string {
+		escaped := make([]string, len(cells))
+
+		for i, c := range cells {
+			escaped[i] = cell(c)
+		}
+
+		return "| " + strings.Join(escaped, " | ") + " |"
+	},
+	"rule": func(cells []string) string {
+		return "|" + strings.Repeat(" --- |", len(cells))
+	},
+	"link": func(url string) string {
+		if strings.ContainsAny(url, " ()") {
+			return "<" + url + ">"
+		}
+
+		return url
+	},
+}).Parse(`# {{.Title}}
+
+Generated {{date .Generated}}.
+
+## Job config
+
+| Setting | Value |
+| --- | --- |
+{{if .File}}| File | {{cell .File}} |
+{{end}}| Object | {{cell .Config.Object}} |
+| Operation | {{cell .Config.Operation}} |
+{{if .Config.ExternalIDField}}| External ID field | {{cell .Config.ExternalIDField}} |
+{{end}}| Content type | {{cell .Config.ContentType}} |
+| Column delimiter | {{cell .Config.Delim}} |
+
+## Results
+
+| Processed | Succeeded | Failed |
+| ---: | ---: | ---: |
+| {{.Processed}} | {{.Succeeded}} | {{.Failed}} |
+
+## Jobs
+
+|{{if .Chunked}} Chunk |{{end}} Job | State | Processed | Failed | Created | Last modified | Total processing | Apex processing | API active processing |
+|{{if .Chunked}} ---: |{{end}} --- | --- | ---: | ---: | --- | --- | ---: | ---: | ---: |
+{{range .Jobs}}|{{if $.Chunked}} {{.Chunk}} |{{end}} {{.Info.ID}} | {{.Info.State}} | {{.Info.RecordsProcessed}} | {{.Info.RecordsFailed}} | {{.Info.CreatedDate}} | {{.Info.SystemModstamp}} | {{ms .Info.TotalProcessingTime}} | {{ms .Info.ApexProcessingTime}} | {{ms .Info.APIActiveProcessingTime}} |
+{{end}}{{range .Jobs}}{{if .Info.ErrorMessage}}
+Job {{.Info.ID}} failed: {{text .Info.ErrorMessage}}
+{{end}}{{end}}
+## Failures
+{{if not .Failures}}
+No records failed.
+{{end}}{{range .Failures}}
+### {{.Code}}{{if .Fields}} on {{.Fields}}{{end}}: {{.Count}} {{if eq .Count 1}}record{{else}}records{{end}}
+
+{{text .Message}}
+{{if .Samples}}
+{{row .Header}}
+{{rule .Header}}
+{{range .Samples}}{{row .}}
+{{end}}{{end}}{{end}}{{with .Links}}
+## Result files
+
+{{range .}}- [{{.Name}}]({{link .URL}})
+{{end}}{{end}}`))
+
+var htmlTemplate = htmltemplate.Must(htmltemplate.New("html").Funcs(funcs).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table { border-collapse: collapse; margin-bottom: 1em; }
+th, td { border: 1px solid #ccc; padding: 0.25em 0.5em; text-align: left; }
+td.n { text-align: right; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<p>Generated {{date .Generated}}.</p>
+
+<h2>Job config</h2>
+<table>
+{{if .File}}<tr><th>File</th><td>{{.File}}</td></tr>
+{{end}}<tr><th>Object</th><td>{{.Config.Object}}</td></tr>
+<tr><th>Operation</th><td>{{.Config.Operation}}</td></tr>
+{{if .Config.ExternalIDField}}<tr><th>External ID field</th><td>{{.Config.ExternalIDField}}</td></tr>
+{{end}}<tr><th>Content type</th><td>{{.Config.ContentType}}</td></tr>
+<tr><th>Column delimiter</th><td>{{.Config.Delim}}</td></tr>
+</table>
+
+<h2>Results</h2>
+<table>
+<tr><th>Processed</th><th>Succeeded</th><th>Failed</th></tr>
+<tr><td class="n">{{.Processed}}</td><td class="n">{{.Succeeded}}</td><td class="n">{{.Failed}}</td></tr>
+</table>
+
+<h2>Jobs</h2>
+<table>
+<tr>{{if .Chunked}}<th>Chunk</th>{{end}}<th>Job</th><th>State</th><th>Processed</th><th>Failed</th><th>Created</th><th>Last modified</th><th>Total processing</th><th>Apex processing</th><th>API active processing</th></tr>
+{{range .Jobs}}<tr>{{if $.Chunked}}<td class="n">{{.Chunk}}</td>{{end}}<td>{{.Info.ID}}</td><td>{{.Info.State}}</td><td class="n">{{.Info.RecordsProcessed}}</td><td class="n">{{.Info.RecordsFailed}}</td><td>{{.Info.CreatedDate}}</td><td>{{.Info.SystemModstamp}}</td><td class="n">{{ms .Info.TotalProcessingTime}}</td><td class="n">{{ms .Info.ApexProcessingTime}}</td><td class="n">{{ms .Info.APIActiveProcessingTime}}</td></tr>
+{{end}}</table>
+{{range .Jobs}}{{if .Info.ErrorMessage}}<p>Job {{.Info.ID}} failed: {{.Info.ErrorMessage}}</p>
+{{end}}{{end}}
+<h2>Failures</h2>
+{{if not .Failures}}<p>No records failed.</p>
+{{end}}{{range .Failures}}<h3>{{.Code}}{{if .Fields}} on {{.Fields}}{{end}}: {{.Count}} {{if eq .Count 1}}record{{else}}records{{end}}</h3>
+<p>{{.Message}}</p>
+{{if .Samples}}<table>
+<tr>{{range .Header}}<th>{{.}}</th>{{end}}</tr>
+{{range .Samples}}<tr>{{range .}}<td>{{.}}</td>{{end}}</tr>
+{{end}}</table>
+{{end}}{{end}}{{with .Links}}
+<h2>Result files</h2>
+<ul>
+{{range .}}<li><a href="{{.URL}}">{{.Name}}</a></li>
+{{end}}</ul>
+{{end}}</body>
+</html>
+`))
+
+// cellEscaper escapes the characters that would end a Markdown table cell or row.
+var cellEscaper = strings.NewReplacer("|", `\|`, "\r\n", " ", "\n", " ", "\r", " ")
+
+// cell escapes a value for a Markdown table cell.
+func cell(s string) string {
+	return cellEscaper.Replace(s)
+}
+
+// textEscaper escapes the characters that Markdown would read as formatting, links or HTML in a line of text.
+var textEscaper = strings.NewReplacer(
+	`\`, `\\`, "`", "\\`", "*", `\*`, "_", `\_`, "[", `\[`, "]", `\]`, "<", `\<`, ">", `\>`, "#", `\#`, "&", `\&`,
+	"|", `\|`, "\r\n", " ", "\n", " ", "\r", " ",
+)
+
+// text escapes a value, such as an error message from the org, for a line of Markdown text.
+func text(s string) string {
+	return textEscaper.Replace(s)
+}
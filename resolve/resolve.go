@@ -0,0 +1,306 @@
+// Package resolve turns relationship columns such as Owner.Username or RecordType.DeveloperName into the IDs of the
+// records they refer to, so that child records can be loaded without looking up parent IDs first.
+package resolve
+
+import (
+	"fmt"
+	"github.com/pkg/errors"
+	"github.com/rfaulhaber/forcedata/auth"
+	"github.com/rfaulhaber/forcedata/describe"
+	"github.com/rfaulhaber/forcedata/pipeline"
+	"github.com/rfaulhaber/forcedata/query"
+	"sort"
+	"strings"
+)
+
+// BatchSize is the number of values looked up in a single query.
+const BatchSize = 100
+
+// Describer returns the describe of an object.
+type Describer func(object string) (describe.SObject, error)
+
+// Querier runs a query and returns every record.
+type Querier func(soql string) ([]query.Record, error)
+
+// Resolver is a pipeline stage that replaces relationship columns with the reference field they set, looking up the
+// ID of each value. Columns that name an external ID field of a single parent object are left for the Bulk API to
+// resolve, after checking the field is one. Rows with values that don't match exactly one record are rejected.
+//
+// Values are looked up in batches: run the records through Collect first, then call Resolve, then run them through the
+// Resolver itself. Values that weren't collected are looked up one at a time.
+type Resolver struct {
+	object   describe.SObject
+	describe Describer
+	query    Querier
+
+	lookups []*lookup
+	parents map[string]describe.SObject
+	cache   map[string]map[string][]string
+}
+
+// lookup is a relationship column resolved by forcedata.
+type lookup struct {
+	column    int
+	name      string
+	reference string
+	target    string
+	field     string
+
+	// filter restricts the query, e.g. to the record types of the object being loaded.
+	filter string
+
+	// pending holds the values collected for lookup, keyed by their lower case form.
+	pending map[string]string
+}
+
+func (l *lookup) key() string {
+	return l.target + "." + l.field + l.filter
+}
+
+// New returns a Resolver for loading into object, which uses the session to describe parent objects and query them.
+func New(session auth.Session, object describe.SObject) *Resolver {
+	describer := func(name string) (describe.SObject, error) {
+		return describe.Get(session, name)
+	}
+
+	querier := func(soql string) ([]query.Record, error) {
+		return query.All(session, soql)
+	}
+
+	return NewWith(object, describer, querier)
+}
+
+// NewWith returns a Resolver that uses describer and querier to reach the org.
+func NewWith(object describe.SObject, describer Describer, querier Querier) *Resolver {
+	return &Resolver{
+		object:   object,
+		describe: describer,
+		query:    querier,
+		parents:  map[string]describe.SObject{},
+		cache:    map[string]map[string][]string{},
+	}
+}
+
+// Header finds the relationship columns of header, replacing those that forcedata resolves with their reference field.
+func (r *Resolver) Header(header []string) ([]string, error) {
+	r.lookups = nil
+
+	result := make([]string, len(header))
+	copy(result, header)
+
+	present := map[string]bool{}
+
+	for _, name := range header {
+		present[strings.ToLower(name)] = true
+	}
+
+	for i, name := range header {
+		dot := strings.Index(name, ".")
+
+		if dot <= 0 {
+			continue
+		}
+
+		l, err := r.column(name[:dot], name[dot+1:])
+
+		if err != nil {
+			return nil, errors.Wrap(err, name)
+		}
+
+		if l == nil {
+			continue
+		}
+
+		l.column, l.name = i, name
+
+		if present[strings.ToLower(l.reference)] {
+			return nil, errors.Errorf("%s: %s is also a column", name, l.reference)
+		}
+
+		result[i] = l.reference
+		r.lookups = append(r.lookups, l)
+	}
+
+	return result, nil
+}
+
+// column returns the lookup needed to resolve relationship.field, or nil if the Bulk API resolves it.
+func (r *Resolver) column(relationship, field string) (*lookup, error) {
+	ref, ok := r.object.Relationship(relationship)
+
+	if !ok {
+		return nil, errors.Errorf("%s is not a relationship of %s", relationship, r.object.Name)
+	}
+
+	if strings.Contains(field, ".") {
+		return nil, errors.New("only one level of relationship is supported")
+	}
+
+	for _, target := range ref.ReferenceTo {
+		parent, ok := r.parents[target]
+
+		if !ok {
+			var err error
+
+			if parent, err = r.describe(target); err != nil {
+				return nil, err
+			}
+
+			r.parents[target] = parent
+		}
+
+		f, ok := parent.Field(field)
+
+		if !ok {
+			continue
+		}
+
+		if f.ExternalID && len(ref.ReferenceTo) == 1 {
+			return nil, nil
+		}
+
+		l := &lookup{reference: ref.Name, target: parent.Name, field: f.Name, pending: map[string]string{}}
+
+		if parent.Name == "RecordType" {
+			l.filter = " AND SobjectType = " + query.Quote(r.object.Name)
+		}
+
+		return l, nil
+	}
+
+	return nil, errors.Errorf("%s is not a field of %s", field, strings.Join(ref.ReferenceTo, " or "))
+}
+
+// Row replaces the value of every resolved column with the ID of the record it names.
+func (r *Resolver) Row(row []string) ([]string, error) {
+	for _, l := range r.lookups {
+		if l.column >= len(row) || row[l.column] == "" {
+			continue
+		}
+
+		value := row[l.column]
+		ids, err := r.ids(l, value)
+
+		if err != nil {
+			return nil, err
+		}
+
+		switch len(ids) {
+		case 0:
+			return nil, pipeline.Reject("%s: no %s found with %s %q", l.name, l.target, l.field, value)
+		case 1:
+			row[l.column] = ids[0]
+		default:
+			return nil, pipeline.Reject("%s: %d %s records have %s %q", l.name, len(ids), l.target, l.field, value)
+		}
+	}
+
+	return row, nil
+}
+
+func (r *Resolver) ids(l *lookup, value string) ([]string, error) {
+	cached := r.cache[l.key()]
+
+	if ids, ok := cached[strings.ToLower(value)]; ok {
+		return ids, nil
+	}
+
+	if err := r.fetch(l, []string{value}); err != nil {
+		return nil, err
+	}
+
+	return r.cache[l.key()][strings.ToLower(value)], nil
+}
+
+// Collect returns a stage that passes records through unchanged, noting the values of the columns the Resolver will
+// look up so that Resolve can query them in batches.
+func (r *Resolver) Collect() pipeline.Stage {
+	return collector{r}
+}
+
+type collector struct {
+	r *Resolver
+}
+
+func (c collector) Header(header []string) ([]string, error) {
+	if _, err := c.r.Header(header); err != nil {
+		return nil, err
+	}
+
+	return header, nil
+}
+
+func (c collector) Row(row []string) ([]string, error) {
+	for _, l := range c.r.lookups {
+		if l.column >= len(row) || row[l.column] == "" {
+			continue
+		}
+
+		if key := strings.ToLower(row[l.column]); l.pending[key] == "" {
+			l.pending[key] = row[l.column]
+		}
+	}
+
+	return row, nil
+}
+
+// Resolve looks up every value collected since the last call.
+func (r *Resolver) Resolve() error {
+	for _, l := range r.lookups {
+		var values []string
+
+		for key, v := range l.pending {
+			if _, ok := r.cache[l.key()][key]; !ok {
+				values = append(values, v)
+			}
+		}
+
+		sort.Strings(values)
+
+		for i := 0; i < len(values); i += BatchSize {
+			end := i + BatchSize
+
+			if end > len(values) {
+				end = len(values)
+			}
+
+			if err := r.fetch(l, values[i:end]); err != nil {
+				return err
+			}
+		}
+
+		l.pending = map[string]string{}
+	}
+
+	return nil
+}
+
+// fetch queries the records matching values, caching their IDs. Values that match nothing are cached as well, so that
+// they aren't queried again. Values are compared ignoring case, as SOQL does.
+func (r *Resolver) fetch(l *lookup, values []string) error {
+	soql := fmt.Sprintf("SELECT Id, %s FROM %s WHERE %s IN %s%s", l.field, l.target, l.field, query.In(values), l.filter)
+
+	records, err := r.query(soql)
+
+	if err != nil {
+		return errors.Wrapf(err, "could not look up %s", l.name)
+	}
+
+	cached, ok := r.cache[l.key()]
+
+	if !ok {
+		cached = map[string][]string{}
+		r.cache[l.key()] = cached
+	}
+
+	for _, v := range values {
+		cached[strings.ToLower(v)] = nil
+	}
+
+	for _, record := range records {
+		v := strings.ToLower(record.String(l.field))
+		cached[v] = append(cached[v], record.String("Id"))
+	}
+
+	return nil
+}
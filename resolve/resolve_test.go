@@ -0,0 +1,97 @@
+package resolve
+
+import (
+	"bytes"
+	"encoding/csv"
+	"github.com/rfaulhaber/forcedata/describe"
+	"github.com/rfaulhaber/forcedata/jobtest"
+	"github.com/rfaulhaber/forcedata/pipeline"
+	"github.com/rfaulhaber/forcedata/query"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func TestResolver(t *testing.T) {
+	sim := jobtest.New()
+	server := sim.Start()
+	defer server.Close()
+
+	session := sim.Session(server.URL)
+
+	users := sim.Insert("User", jobtest.Record{"Username": "ann@example.com"}, jobtest.Record{"Username": "bob@example.com"})
+	types := sim.Insert("RecordType",
+		jobtest.Record{"DeveloperName": "Partner", "SobjectType": "Account"},
+		jobtest.Record{"DeveloperName": "Partner", "SobjectType": "Contact"},
+	)
+	sim.Insert("Account", jobtest.Record{"Name": "Acme"}, jobtest.Record{"Name": "Acme"})
+
+	contact, err := describe.Get(session, "Contact")
+	assert.NoError(t, err)
+
+	contact.Fields = append(contact.Fields, describe.Field{Name: "RecordTypeId", Type: "reference", ReferenceTo: []string{"RecordType"}, RelationshipName: "RecordType"})
+
+	var queries []string
+
+	r := New(session, contact)
+	next := r.query
+	r.query = func(soql string) ([]query.Record, error) {
+		queries = append(queries, soql)
+		return next(soql)
+	}
+
+	content := "LastName,Owner.Username,RecordType.DeveloperName,Account.External_Id__c,Account.Name\n" +
+		"One,ANN@example.com,Partner,A1,\n" +
+		"Two,bob@example.com,Partner,,\n" +
+		"Three,carl@example.com,,,\n" +
+		"Four,ann@example.com,,,Acme\n"
+
+	stages := []pipeline.Stage{r.Collect()}
+	assert.NoError(t, pipeline.Run(csv.NewReader(strings.NewReader(content)), csv.NewWriter(&bytes.Buffer{}), stages...))
+	assert.NoError(t, r.Resolve())
+
+	var buf, rejects bytes.Buffer
+
+	p := pipeline.Pipeline{Stages: []pipeline.Stage{r}, Rejects: csv.NewWriter(&rejects)}
+
+	assert.NoError(t, p.Run(csv.NewReader(strings.NewReader(content)), csv.NewWriter(&buf)))
+
+	assert.Equal(t, "LastName,OwnerId,RecordTypeId,Account.External_Id__c,AccountId\n"+
+		"One,"+users[0]+","+types[1]+",A1,\n"+
+		"Two,"+users[1]+","+types[1]+",,\n", buf.String())
+
+	assert.Equal(t, "LastName,Owner.Username,RecordType.DeveloperName,Account.External_Id__c,Account.Name,forcedata__Error\n"+
+		"Three,carl@example.com,,,,\"Owner.Username: no User found with Username \"\"carl@example.com\"\"\"\n"+
+		"Four,ann@example.com,,,Acme,\"Account.Name: 2 Account records have Name \"\"Acme\"\"\"\n", rejects.String())
+
+	assert.Equal(t, []string{
+		"SELECT Id, Username FROM User WHERE Username IN ('ANN@example.com', 'bob@example.com', 'carl@example.com')",
+		"SELECT Id, DeveloperName FROM RecordType WHERE DeveloperName IN ('Partner') AND SobjectType = 'Contact'",
+		"SELECT Id, Name FROM Account WHERE Name IN ('Acme')",
+	}, queries)
+}
+
+func TestResolver_HeaderErrors(t *testing.T) {
+	sim := jobtest.New()
+	server := sim.Start()
+	defer server.Close()
+
+	session := sim.Session(server.URL)
+	contact, err := describe.Get(session, "Contact")
+	assert.NoError(t, err)
+
+	testCases := []struct {
+		header   string
+		expected string
+	}{
+		{"Parent.Name", "Parent.Name: Parent is not a relationship of Contact"},
+		{"Account.Nmae", "Account.Nmae: Nmae is not a field of Account"},
+		{"Account.Owner.Username", "Account.Owner.Username: only one level of relationship is supported"},
+		{"AccountId,Account.Name", "Account.Name: AccountId is also a column"},
+	}
+
+	for _, tc := range testCases {
+		_, err := New(session, contact).Header(strings.Split(tc.header, ","))
+		assert.EqualError(t, err, tc.expected, tc.header)
+	}
+}
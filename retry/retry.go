@@ -0,0 +1,196 @@
+// Package retry loads the records a job failed to load again, retrying records that failed only because they were
+// locked by something else after backing off.
+package retry
+
+import (
+	"bytes"
+	"encoding/csv"
+	"github.com/pkg/errors"
+	"io"
+	"sort"
+	"strings"
+)
+
+// Columns the Bulk API adds to failed results.
+const (
+	IDColumn    = "sf__Id"
+	ErrorColumn = "sf__Error"
+)
+
+// LockErrors are the codes of records that failed because another job or user held a lock on them or a related
+// record. They usually load once the lock is released.
+var LockErrors = []string{"UNABLE_TO_LOCK_ROW"}
+
+// Results are the failed results of a job: the records it uploaded, with the ID and error of each.
+type Results struct {
+	Header []string
+	Rows   [][]string
+
+	errorColumn int
+}
+
+// Parse reads failed results.
+func Parse(content []byte, comma rune) (*Results, error) {
+	r := csv.NewReader(bytes.NewReader(content))
+	r.Comma = comma
+	r.FieldsPerRecord = -1
+
+	header, err := r.Read()
+
+	if err == io.EOF {
+		return nil, errors.New("failed results are empty")
+	}
+
+	if err != nil {
+		return nil, errors.Wrap(err, "could not read failed results")
+	}
+
+	res := &Results{Header: header, errorColumn: indexOf(header, ErrorColumn)}
+
+	if res.errorColumn < 0 {
+		return nil, errors.Errorf("failed results have no %s column", ErrorColumn)
+	}
+
+	if res.Rows, err = r.ReadAll(); err != nil {
+		return nil, errors.Wrap(err, "could not read failed results")
+	}
+
+	return res, nil
+}
+
+// Code returns the error code of the ith row, e.g. UNABLE_TO_LOCK_ROW for "UNABLE_TO_LOCK_ROW:unable to obtain
+// exclusive access to this record".
+func (res *Results) Code(i int) string {
+	row := res.Rows[i]
+
+	if res.errorColumn >= len(row) {
+		return ""
+	}
+
+	return Code(row[res.errorColumn])
+}
+
+// Code returns the code of an error from failed results.
+func Code(err string) string {
+	if i := strings.Index(err, ":"); i >= 0 {
+		err = err[:i]
+	}
+
+	return strings.TrimSpace(err)
+}
+
+// Filter splits the results into the rows that failed with one of codes, and the rest. Codes are compared ignoring
+// case.
+func (res *Results) Filter(codes []string) (matched, rest *Results) {
+	matched = &Results{Header: res.Header, errorColumn: res.errorColumn}
+	rest = &Results{Header: res.Header, errorColumn: res.errorColumn}
+
+	for i, row := range res.Rows {
+		if hasCode(codes, res.Code(i)) {
+			matched.Rows = append(matched.Rows, row)
+		} else {
+			rest.Rows = append(rest.Rows, row)
+		}
+	}
+
+	return matched, rest
+}
+
+// Append adds the rows of other, which must have the same header, to the results.
+func (res *Results) Append(other *Results) {
+	res.Rows = append(res.Rows, other.Rows...)
+}
+
+// Counts returns the number of rows that failed with each error code, in order of code.
+func (res *Results) Counts() []CodeCount {
+	counts := map[string]int{}
+
+	for i := range res.Rows {
+		counts[res.Code(i)]++
+	}
+
+	var list []CodeCount
+
+	for code, n := range counts {
+		list = append(list, CodeCount{code, n})
+	}
+
+	sort.Slice(list, func(i, j int) bool { return list[i].Code < list[j].Code })
+
+	return list
+}
+
+// CodeCount is the number of rows that failed with an error code.
+type CodeCount struct {
+	Code  string
+	Count int
+}
+
+// Records returns the rows as they were uploaded, without the columns the Bulk API added, as CSV.
+func (res *Results) Records(comma rune) ([]byte, error) {
+	var keep []int
+
+	for i, name := range res.Header {
+		if !strings.HasPrefix(name, "sf__") {
+			keep = append(keep, i)
+		}
+	}
+
+	project := func(row []string) []string {
+		values := make([]string, len(keep))
+
+		for i, k := range keep {
+			if k < len(row) {
+				values[i] = row[k]
+			}
+		}
+
+		return values
+	}
+
+	rows := [][]string{project(res.Header)}
+
+	for _, row := range res.Rows {
+		rows = append(rows, project(row))
+	}
+
+	return write(rows, comma)
+}
+
+// Bytes returns the results as CSV.
+func (res *Results) Bytes(comma rune) ([]byte, error) {
+	return write(append([][]string{res.Header}, res.Rows...), comma)
+}
+
+func write(rows [][]string, comma rune) ([]byte, error) {
+	var buf bytes.Buffer
+
+	w := csv.NewWriter(&buf)
+	w.Comma = comma
+
+	if err := w.WriteAll(rows); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func hasCode(codes []string, code string) bool {
+	for _, c := range codes {
+		if strings.EqualFold(c, code) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func indexOf(header []string, name string) int {
+	for i, h := range header {
+		if h == name {
+			return i
+		}
+	}
+
+	return -1
+}
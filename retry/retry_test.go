@@ -0,0 +1,153 @@
+package retry
+
+import (
+	"github.com/rfaulhaber/forcedata/job"
+	"github.com/rfaulhaber/forcedata/jobtest"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+const testResults = `"sf__Id","sf__Error",Name,Industry
+"","UNABLE_TO_LOCK_ROW:unable to obtain exclusive access to this record",Acme,Banking
+"","REQUIRED_FIELD_MISSING:Required fields are missing: [Name]",,Retail
+"","unable_to_lock_row:unable to obtain exclusive access to this record",Globex,Energy
+`
+
+func TestResults(t *testing.T) {
+	res, err := Parse([]byte(testResults), ',')
+	assert.NoError(t, err)
+	assert.Len(t, res.Rows, 3)
+	assert.Equal(t, "REQUIRED_FIELD_MISSING", res.Code(1))
+
+	assert.Equal(t, []CodeCount{{"REQUIRED_FIELD_MISSING", 1}, {"UNABLE_TO_LOCK_ROW", 1}, {"unable_to_lock_row", 1}}, res.Counts())
+
+	locked, rest := res.Filter(LockErrors)
+	assert.Len(t, locked.Rows, 2)
+	assert.Len(t, rest.Rows, 1)
+
+	records, err := locked.Records(';')
+	assert.NoError(t, err)
+	assert.Equal(t, "Name;Industry\nAcme;Banking\nGlobex;Energy\n", string(records))
+
+	rest.Append(locked)
+	b, err := rest.Bytes(',')
+	assert.NoError(t, err)
+	assert.Equal(t, "sf__Id,sf__Error,Name,Industry\n,REQUIRED_FIELD_MISSING:Required fields are missing: [Name],,Retail\n"+
+		",UNABLE_TO_LOCK_ROW:unable to obtain exclusive access to this record,Acme,Banking\n"+
+		",unable_to_lock_row:unable to obtain exclusive access to this record,Globex,Energy\n", string(b))
+
+	_, err = Parse([]byte("Name\nAcme\n"), ',')
+	assert.EqualError(t, err, "failed results have no sf__Error column")
+}
+
+func TestRunner(t *testing.T) {
+	sim := jobtest.New()
+	server := sim.Start()
+	defer server.Close()
+
+	locks := map[string]int{"Acme": 1, "Globex": 5}
+
+	sim.Rules = append(sim.Rules, func(object, operation string, record jobtest.Record) *jobtest.RowError {
+		if record["Name"] == "" {
+			return &jobtest.RowError{Code: "REQUIRED_FIELD_MISSING", Message: "Required fields are missing: [Name]"}
+		}
+
+		if locks[record["Name"]] > 0 {
+			locks[record["Name"]]--
+			return &jobtest.RowError{Code: "UNABLE_TO_LOCK_ROW", Message: "unable to obtain exclusive access to this record"}
+		}
+
+		return nil
+	})
+
+	session := sim.Session(server.URL)
+	config := job.JobConfig{Object: "Account", Operation: "insert", ContentType: "CSV", Delim: "SEMICOLON"}
+
+	// a first load leaves failed results to retry
+	first := job.New(config, session)
+	assert.NoError(t, first.Create())
+	assert.NoError(t, first.Upload([]byte("Name;Industry\nAcme;Banking\n;Retail\nGlobex;Energy\nInitech;Tech\n")))
+	_, err := first.Wait(time.Millisecond)
+	assert.NoError(t, err)
+
+	failedConfig, results, err := Failed(session, first.ID())
+	assert.NoError(t, err)
+	assert.Equal(t, config, failedConfig)
+	assert.Len(t, results.Rows, 3)
+
+	_, retried := results.Filter([]string{"REQUIRED_FIELD_MISSING"})
+	content, err := retried.Records(';')
+	assert.NoError(t, err)
+
+	var waits []time.Duration
+
+	r := Runner{
+		Session:      session,
+		Config:       failedConfig,
+		Policy:       Policy{Codes: LockErrors, Attempts: 2, Backoff: time.Second},
+		PollInterval: time.Millisecond,
+		sleep:        func(d time.Duration) { waits = append(waits, d) },
+	}
+
+	infos, failed, err := r.Run(content)
+
+	assert.NoError(t, err)
+	assert.Len(t, infos, 3)
+	assert.Equal(t, []time.Duration{time.Second, 2 * time.Second}, waits)
+	assert.Len(t, failed.Rows, 1)
+	assert.Equal(t, "Globex", failed.Rows[0][2])
+
+	var names []string
+
+	for _, record := range sim.Records("Account") {
+		names = append(names, record["Name"])
+	}
+
+	assert.Equal(t, []string{"Initech", "Acme"}, names)
+}
+
+func TestFailed_Incomplete(t *testing.T) {
+	sim := jobtest.New()
+	server := sim.Start()
+	defer server.Close()
+
+	j := job.New(job.JobConfig{Object: "Account", Operation: "insert", ContentType: "CSV", Delim: "COMMA"}, sim.Session(server.URL))
+	assert.NoError(t, j.Create())
+
+	_, _, err := Failed(sim.Session(server.URL), j.ID())
+	assert.EqualError(t, err, "job "+j.ID()+" is Open; only failed records of complete jobs can be retried")
+}
+
+func TestRunner_Error(t *testing.T) {
+	sim := jobtest.New()
+	server := sim.Start()
+	defer server.Close()
+
+	sim.Rules = append(sim.Rules, func(object, operation string, record jobtest.Record) *jobtest.RowError {
+		if record["Name"] == "Acme" {
+			return &jobtest.RowError{Code: "UNABLE_TO_LOCK_ROW", Message: "unable to obtain exclusive access to this record"}
+		}
+
+		return nil
+	})
+
+	r := Runner{
+		Session:      sim.Session(server.URL),
+		Config:       job.JobConfig{Object: "Account", Operation: "insert", ContentType: "CSV", Delim: "COMMA"},
+		Policy:       Policy{Codes: LockErrors, Attempts: 2, Backoff: time.Second},
+		PollInterval: time.Millisecond,
+	}
+
+	// the retry can't create its job, so the locked record is still failed
+	r.sleep = func(time.Duration) {
+		sim.AddFault(jobtest.Fault{Method: "POST", Path: "/jobs/ingest", StatusCode: 500, ErrorCode: "UNKNOWN_EXCEPTION", Message: "server error"})
+	}
+
+	infos, failed, err := r.Run([]byte("Name\nAcme\nGlobex\n"))
+
+	assert.Error(t, err)
+	assert.Len(t, infos, 1)
+	assert.Len(t, failed.Rows, 1)
+	assert.Equal(t, "Acme", failed.Rows[0][2])
+}
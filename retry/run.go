@@ -0,0 +1,211 @@
+package retry
+
+import (
+	"github.com/pkg/errors"
+	"github.com/rfaulhaber/forcedata/auth"
+	"github.com/rfaulhaber/forcedata/job"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Policy says how records that failed with particular errors are retried.
+type Policy struct {
+	// Codes are the errors retried.
+	Codes []string
+
+	// Attempts is the most times records are retried after the first load.
+	Attempts int
+
+	// Backoff is how long to wait before the first retry. It doubles before each retry after that.
+	Backoff time.Duration
+}
+
+// DefaultPolicy retries records that failed because of lock contention three times, waiting 10, 20 and then 40
+// seconds.
+var DefaultPolicy = Policy{Codes: LockErrors, Attempts: 3, Backoff: 10 * time.Second}
+
+// Failed returns the config of a finished job, to load its records again with, and its failed results.
+func Failed(session auth.Session, jobID string) (job.JobConfig, *Results, error) {
+	j := job.New(job.JobConfig{}, session)
+	j.SetInfo(job.JobInfo{ID: jobID})
+
+	info, err := j.GetInfo()
+
+	if err != nil {
+		return job.JobConfig{}, nil, errors.Wrap(err, "could not get job "+jobID)
+	}
+
+	if info.State != "JobComplete" {
+		return job.JobConfig{}, nil, errors.Errorf("job %s is %s; only failed records of complete jobs can be retried", jobID, info.State)
+	}
+
+	config := job.JobConfig{
+		Object:          info.Object,
+		Operation:       info.Operation,
+		ContentType:     info.ContentType,
+		Delim:           info.ColumnDelimiter,
+		ExternalIDField: info.ExternalIdFieldName,
+	}
+
+	b, err := j.GetFailure()
+
+	if err != nil {
+		return config, nil, err
+	}
+
+	results, err := Parse(b, delimRune(config.Delim))
+
+	return config, results, err
+}
+
+// Runner loads records with a job, retrying those that fail with the errors of its policy with new jobs.
+type Runner struct {
+	Session auth.Session
+	Config  job.JobConfig
+	Policy  Policy
+
+	// PollInterval is how often a running job is checked. Zero means job.DefaultWatchTime.
+	PollInterval time.Duration
+
+	// Log, if set, is called as jobs finish and retries are scheduled.
+	Log func(format string, args ...interface{})
+
+	// sleep waits between retries. Tests replace it.
+	sleep func(time.Duration)
+}
+
+// Run loads content, the records to load as CSV in the runner's job config, then retries records that failed with
+// the policy's errors until they load or the policy's attempts run out. It returns the info of every job run and the
+// records that still failed, or nil if none did. If a retry can't be run, the records it would have retried are
+// returned with those that failed, along with the error.
+func (r *Runner) Run(content []byte) ([]job.JobInfo, *Results, error) {
+	var (
+		infos   []job.JobInfo
+		failed  *Results
+		retried *Results
+	)
+
+	comma := delimRune(r.Config.Delim)
+	backoff := r.Policy.Backoff
+
+	for attempt := 0; ; attempt++ {
+		info, results, err := r.load(content, comma)
+
+		// the records being retried are still failed, as far as anyone knows
+		if err != nil {
+			if retried != nil {
+				failed.Append(retried)
+			}
+
+			return infos, failed, err
+		}
+
+		infos = append(infos, info)
+
+		if results == nil {
+			return infos, failed, nil
+		}
+
+		var rest *Results
+
+		retried, rest = results.Filter(r.Policy.Codes)
+
+		if failed == nil {
+			failed = rest
+		} else {
+			failed.Append(rest)
+		}
+
+		if len(retried.Rows) == 0 {
+			return infos, failed, nil
+		}
+
+		if attempt >= r.Policy.Attempts {
+			failed.Append(retried)
+			return infos, failed, nil
+		}
+
+		r.log("%d records failed with %s, retrying in %s", len(retried.Rows), strings.Join(r.Policy.Codes, " or "), backoff)
+		r.wait(backoff)
+		backoff *= 2
+
+		if content, err = retried.Records(comma); err != nil {
+			return infos, failed, err
+		}
+	}
+}
+
+// load runs a single job, returning its failed results, or nil if no records failed.
+func (r *Runner) load(content []byte, comma rune) (job.JobInfo, *Results, error) {
+	j := job.New(r.Config, r.Session)
+
+	if err := j.Create(); err != nil {
+		return job.JobInfo{}, nil, errors.Wrap(err, "could not create job")
+	}
+
+	if err := j.Upload(content); err != nil {
+		return job.JobInfo{}, nil, errors.Wrap(err, "could not upload content to job")
+	}
+
+	interval := r.PollInterval
+
+	if interval <= 0 {
+		interval = job.DefaultWatchTime
+	}
+
+	info, err := j.Wait(interval)
+
+	if err != nil {
+		return info, nil, errors.Wrap(err, "could not check job")
+	}
+
+	if info.State != "JobComplete" {
+		return info, nil, errors.Errorf("job %s %s: %s", info.ID, strings.ToLower(info.State), info.ErrorMessage)
+	}
+
+	r.log("job %s: %d processed, %d failed", info.ID, info.RecordsProcessed, info.RecordsFailed)
+
+	if info.RecordsFailed == 0 {
+		return info, nil, nil
+	}
+
+	b, err := j.GetFailure()
+
+	if err != nil {
+		return info, nil, err
+	}
+
+	results, err := Parse(b, comma)
+
+	return info, results, err
+}
+
+// delimRune returns the delimiter with the given name, e.g. ',' for COMMA.
+func delimRune(name string) rune {
+	delim, ok := job.GetDelim(name)
+
+	switch {
+	case !ok:
+		return ','
+	case delim == "\\t":
+		return '\t'
+	default:
+		r, _ := utf8.DecodeRuneInString(delim)
+		return r
+	}
+}
+
+func (r *Runner) wait(d time.Duration) {
+	if r.sleep != nil {
+		r.sleep(d)
+	} else {
+		time.Sleep(d)
+	}
+}
+
+func (r *Runner) log(format string, args ...interface{}) {
+	if r.Log != nil {
+		r.Log(format, args...)
+	}
+}
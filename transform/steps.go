@@ -0,0 +1,383 @@
+package transform
+
+import (
+	"fmt"
+	"github.com/pkg/errors"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	isoDate     = "2006-01-02"
+	isoDatetime = "2006-01-02T15:04:05Z"
+)
+
+// parseStep reads a step from either a bare name ("trim") or a single-key map ({date: MM/DD/YYYY}).
+func parseStep(spec interface{}) (step, error) {
+	if name, ok := spec.(string); ok {
+		switch name {
+		case "trim":
+			return funcStep(strings.TrimSpace), nil
+		case "upper":
+			return funcStep(strings.ToUpper), nil
+		case "lower":
+			return funcStep(strings.ToLower), nil
+		case "drop":
+			return dropStep{}, nil
+		default:
+			return nil, errors.Errorf("unknown step %q", name)
+		}
+	}
+
+	m, ok := spec.(map[interface{}]interface{})
+
+	if !ok || len(m) != 1 {
+		return nil, errors.Errorf("invalid step %v", spec)
+	}
+
+	for k, v := range m {
+		switch k {
+		case "date":
+			return newDateStep(v, isoDate)
+		case "datetime":
+			return newDateStep(v, isoDatetime)
+		case "replace":
+			return newReplaceStep(v)
+		case "lookup":
+			return newLookupStep(v)
+		case "default":
+			return defaultStep(fmt.Sprint(v)), nil
+		case "join":
+			return newJoinStep(v)
+		case "split":
+			return newSplitStep(v)
+		default:
+			return nil, errors.Errorf("unknown step %q", k)
+		}
+	}
+
+	return nil, nil
+}
+
+// funcStep applies a function to every value.
+type funcStep func(string) string
+
+func (f funcStep) prepare(t *Transforms, col int) error { return nil }
+
+func (f funcStep) apply(row []string, col int) error {
+	row[col] = f(row[col])
+	return nil
+}
+
+// dropStep removes the column from the output.
+type dropStep struct{}
+
+func (dropStep) prepare(t *Transforms, col int) error {
+	t.dropped[col] = true
+	return nil
+}
+
+func (dropStep) apply(row []string, col int) error { return nil }
+
+// defaultStep fills blank values.
+type defaultStep string
+
+func (d defaultStep) prepare(t *Transforms, col int) error { return nil }
+
+func (d defaultStep) apply(row []string, col int) error {
+	if strings.TrimSpace(row[col]) == "" {
+		row[col] = string(d)
+	}
+
+	return nil
+}
+
+// dateStep parses values in one layout and writes them in another. Blank values are left alone.
+type dateStep struct {
+	from string
+	to   string
+	desc string
+}
+
+func newDateStep(v interface{}, to string) (step, error) {
+	switch spec := v.(type) {
+	case string:
+		return dateStep{from: layout(spec), to: to, desc: spec}, nil
+	case map[interface{}]interface{}:
+		from, _ := spec["from"].(string)
+
+		if from == "" {
+			return nil, errors.New("date step needs a from format")
+		}
+
+		s := dateStep{from: layout(from), to: to, desc: from}
+
+		if out, ok := spec["to"].(string); ok {
+			s.to = layout(out)
+		}
+
+		return s, nil
+	default:
+		return nil, errors.Errorf("invalid date step %v", v)
+	}
+}
+
+func (d dateStep) prepare(t *Transforms, col int) error { return nil }
+
+func (d dateStep) apply(row []string, col int) error {
+	value := strings.TrimSpace(row[col])
+
+	if value == "" {
+		return nil
+	}
+
+	parsed, err := time.Parse(d.from, value)
+
+	if err != nil {
+		return errors.Errorf("%q does not match %s", value, d.desc)
+	}
+
+	row[col] = parsed.UTC().Format(d.to)
+
+	return nil
+}
+
+// layoutTokens are tried in the order listed at each position, so longer tokens come before the ones they start with.
+var layoutTokens = strings.NewReplacer(
+	"YYYY", "2006",
+	"YY", "06",
+	"MMMM", "January",
+	"MMM", "Jan",
+	"MM", "01",
+	"DD", "02",
+	"HH", "15",
+	"hh", "03",
+	"mm", "04",
+	"ss", "05",
+	"M", "1",
+	"D", "2",
+)
+
+// goLayout prefixes formats written as Go time layouts, such as go:02 Jan 2006, which are used as they are.
+const goLayout = "go:"
+
+// layout converts a format such as MM/DD/YYYY or DD-MMM-YYYY to a Go time layout.
+func layout(format string) string {
+	if strings.HasPrefix(format, goLayout) {
+		return strings.TrimPrefix(format, goLayout)
+	}
+
+	return layoutTokens.Replace(format)
+}
+
+// replaceStep replaces every match of a regular expression.
+type replaceStep struct {
+	pattern *regexp.Regexp
+	with    string
+}
+
+func newReplaceStep(v interface{}) (step, error) {
+	spec, ok := v.(map[interface{}]interface{})
+
+	if !ok {
+		return nil, errors.Errorf("invalid replace step %v", v)
+	}
+
+	pattern, _ := spec["pattern"].(string)
+	re, err := regexp.Compile(pattern)
+
+	if err != nil || pattern == "" {
+		return nil, errors.Errorf("invalid replace pattern %q", pattern)
+	}
+
+	with := ""
+
+	if spec["with"] != nil {
+		with = fmt.Sprint(spec["with"])
+	}
+
+	return replaceStep{re, with}, nil
+}
+
+func (r replaceStep) prepare(t *Transforms, col int) error { return nil }
+
+func (r replaceStep) apply(row []string, col int) error {
+	row[col] = r.pattern.ReplaceAllString(row[col], r.with)
+	return nil
+}
+
+// lookupStep swaps values for the ones in a table. Values missing from the table cause the row to be rejected;
+// blank values are left alone.
+type lookupStep map[string]string
+
+func newLookupStep(v interface{}) (step, error) {
+	spec, ok := v.(map[interface{}]interface{})
+
+	if !ok || len(spec) == 0 {
+		return nil, errors.Errorf("invalid lookup step %v", v)
+	}
+
+	l := lookupStep{}
+
+	for k, v := range spec {
+		l[fmt.Sprint(k)] = fmt.Sprint(v)
+	}
+
+	return l, nil
+}
+
+func (l lookupStep) prepare(t *Transforms, col int) error { return nil }
+
+func (l lookupStep) apply(row []string, col int) error {
+	if row[col] == "" {
+		return nil
+	}
+
+	v, ok := l[row[col]]
+
+	if !ok {
+		keys := make([]string, 0, len(l))
+
+		for k := range l {
+			keys = append(keys, k)
+		}
+
+		sort.Strings(keys)
+
+		return errors.Errorf("%q is not one of %s", row[col], strings.Join(keys, ", "))
+	}
+
+	row[col] = v
+
+	return nil
+}
+
+// joinStep sets the column to the non-blank values of other columns joined by a separator.
+type joinStep struct {
+	columns   []string
+	separator string
+	indexes   []int
+}
+
+func newJoinStep(v interface{}) (step, error) {
+	spec, ok := v.(map[interface{}]interface{})
+
+	if !ok {
+		return nil, errors.Errorf("invalid join step %v", v)
+	}
+
+	columns, err := stringList(spec["columns"])
+
+	if err != nil || len(columns) == 0 {
+		return nil, errors.New("join step needs a list of columns")
+	}
+
+	separator := " "
+
+	if spec["separator"] != nil {
+		separator = fmt.Sprint(spec["separator"])
+	}
+
+	return &joinStep{columns: columns, separator: separator}, nil
+}
+
+func (j *joinStep) prepare(t *Transforms, col int) error {
+	j.indexes = nil
+
+	for _, c := range j.columns {
+		i, err := t.existing(c)
+
+		if err != nil {
+			return err
+		}
+
+		j.indexes = append(j.indexes, i)
+	}
+
+	return nil
+}
+
+func (j *joinStep) apply(row []string, col int) error {
+	var parts []string
+
+	for _, i := range j.indexes {
+		if v := strings.TrimSpace(row[i]); v != "" {
+			parts = append(parts, v)
+		}
+	}
+
+	row[col] = strings.Join(parts, j.separator)
+
+	return nil
+}
+
+// splitStep splits the column on a separator into other columns. The last column gets whatever is left over.
+type splitStep struct {
+	into      []string
+	separator string
+	indexes   []int
+}
+
+func newSplitStep(v interface{}) (step, error) {
+	spec, ok := v.(map[interface{}]interface{})
+
+	if !ok {
+		return nil, errors.Errorf("invalid split step %v", v)
+	}
+
+	into, err := stringList(spec["into"])
+
+	if err != nil || len(into) == 0 {
+		return nil, errors.New("split step needs a list of columns to split into")
+	}
+
+	separator, _ := spec["separator"].(string)
+
+	if separator == "" {
+		return nil, errors.New("split step needs a separator")
+	}
+
+	return &splitStep{into: into, separator: separator}, nil
+}
+
+func (s *splitStep) prepare(t *Transforms, col int) error {
+	s.indexes = nil
+
+	for _, c := range s.into {
+		s.indexes = append(s.indexes, t.column(c))
+	}
+
+	return nil
+}
+
+func (s *splitStep) apply(row []string, col int) error {
+	parts := strings.SplitN(row[col], s.separator, len(s.indexes))
+
+	for i, idx := range s.indexes {
+		if i < len(parts) {
+			row[idx] = strings.TrimSpace(parts[i])
+		} else {
+			row[idx] = ""
+		}
+	}
+
+	return nil
+}
+
+func stringList(v interface{}) ([]string, error) {
+	items, ok := v.([]interface{})
+
+	if !ok {
+		return nil, errors.Errorf("expected a list, got %v", v)
+	}
+
+	result := make([]string, len(items))
+
+	for i, item := range items {
+		result[i] = fmt.Sprint(item)
+	}
+
+	return result, nil
+}
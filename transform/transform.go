@@ -0,0 +1,219 @@
+// Package transform reformats the values of CSV columns while records stream through a load.
+package transform
+
+import (
+	"github.com/pkg/errors"
+	"github.com/rfaulhaber/forcedata/pipeline"
+	"gopkg.in/yaml.v2"
+	"io/ioutil"
+	"strings"
+)
+
+// Transforms applies the steps configured for each column, in the order the columns appear in the file. It
+// implements pipeline.Stage; rows that fail a step are rejected.
+type Transforms struct {
+	columns []column
+
+	header  []string
+	dropped map[int]bool
+}
+
+type column struct {
+	name  string
+	steps []step
+	index int
+}
+
+// step is a single transformation of a row. prepare is called once with the header, and may add columns to it.
+type step interface {
+	prepare(t *Transforms, col int) error
+	apply(row []string, col int) error
+}
+
+// Load reads a transform file.
+func Load(path string) (*Transforms, error) {
+	b, err := ioutil.ReadFile(path)
+
+	if err != nil {
+		return nil, errors.Wrap(err, "could not read transform file")
+	}
+
+	return Parse(b)
+}
+
+// Parse reads transforms from YAML. Each column lists the steps applied to it in order:
+//
+//	columns:
+//	  Birthdate:
+//	    - date: MM/DD/YYYY          # reformats to YYYY-MM-DD
+//	  LastActivity__c:
+//	    - datetime: MM/DD/YYYY HH:mm  # reformats to YYYY-MM-DDThh:mm:ssZ
+//	  Name: [trim, upper]
+//	  Phone:
+//	    - replace: {pattern: "[^0-9+]", with: ""}
+//	  Status:
+//	    - lookup: {A: Active, I: Inactive}
+//	  Country:
+//	    - default: US
+//	  Description:
+//	    - join: {columns: [Notes, Comments], separator: " / "}
+//	  Address:
+//	    - split: {separator: ",", into: [Street, City]}
+//	    - drop
+//
+// A column that doesn't exist in the file is added, which is how join creates new columns. Date formats use the tokens
+// YYYY, YY, MMMM, MMM, MM, M, DD, D, HH, hh, mm and ss, or are Go time layouts prefixed with go:.
+func Parse(b []byte) (*Transforms, error) {
+	var file struct {
+		Columns yaml.MapSlice `yaml:"columns"`
+	}
+
+	if err := yaml.UnmarshalStrict(b, &file); err != nil {
+		return nil, errors.Wrap(err, "could not parse transforms")
+	}
+
+	t := &Transforms{}
+
+	for _, item := range file.Columns {
+		name, ok := item.Key.(string)
+
+		if !ok {
+			return nil, errors.Errorf("transform: column name %v is not a string", item.Key)
+		}
+
+		var specs []interface{}
+
+		switch v := item.Value.(type) {
+		case []interface{}:
+			specs = v
+		default:
+			specs = []interface{}{v}
+		}
+
+		col := column{name: name}
+
+		for _, spec := range specs {
+			s, err := parseStep(plain(spec))
+
+			if err != nil {
+				return nil, errors.Wrapf(err, "transform: column %q", name)
+			}
+
+			col.steps = append(col.steps, s)
+		}
+
+		t.columns = append(t.columns, col)
+	}
+
+	if len(t.columns) == 0 {
+		return nil, errors.New("transform: no columns defined")
+	}
+
+	return t, nil
+}
+
+// Header locates every transformed column, adding columns that don't exist yet, and returns the resulting header.
+func (t *Transforms) Header(header []string) ([]string, error) {
+	t.header = append([]string{}, header...)
+	t.dropped = map[int]bool{}
+
+	for i := range t.columns {
+		col := &t.columns[i]
+		col.index = t.column(col.name)
+
+		for _, s := range col.steps {
+			if err := s.prepare(t, col.index); err != nil {
+				return nil, errors.Wrapf(err, "transform: column %q", col.name)
+			}
+		}
+	}
+
+	return t.keep(t.header), nil
+}
+
+// Row applies every step to the row, rejecting it if any step fails.
+func (t *Transforms) Row(row []string) ([]string, error) {
+	for len(row) < len(t.header) {
+		row = append(row, "")
+	}
+
+	for _, col := range t.columns {
+		for _, s := range col.steps {
+			if err := s.apply(row, col.index); err != nil {
+				return nil, pipeline.Reject("%s: %s", col.name, err)
+			}
+		}
+	}
+
+	return t.keep(row), nil
+}
+
+// column returns the index of the named column, adding it to the header if it doesn't exist.
+func (t *Transforms) column(name string) int {
+	for i, h := range t.header {
+		if h == name {
+			return i
+		}
+	}
+
+	for i, h := range t.header {
+		if strings.EqualFold(strings.TrimSpace(h), name) {
+			return i
+		}
+	}
+
+	t.header = append(t.header, name)
+
+	return len(t.header) - 1
+}
+
+// existing returns the index of a column that must already exist.
+func (t *Transforms) existing(name string) (int, error) {
+	for i, h := range t.header {
+		if h == name || strings.EqualFold(strings.TrimSpace(h), name) {
+			return i, nil
+		}
+	}
+
+	return 0, errors.Errorf("column %q not found", name)
+}
+
+func (t *Transforms) keep(values []string) []string {
+	if len(t.dropped) == 0 {
+		return values
+	}
+
+	kept := make([]string, 0, len(values))
+
+	for i, v := range values {
+		if !t.dropped[i] {
+			kept = append(kept, v)
+		}
+	}
+
+	return kept
+}
+
+// plain converts the ordered maps yaml.MapSlice decodes nested mappings into to ordinary maps.
+func plain(v interface{}) interface{} {
+	switch t := v.(type) {
+	case yaml.MapSlice:
+		m := map[interface{}]interface{}{}
+
+		for _, item := range t {
+			m[item.Key] = plain(item.Value)
+		}
+
+		return m
+	case []interface{}:
+		result := make([]interface{}, len(t))
+
+		for i, item := range t {
+			result[i] = plain(item)
+		}
+
+		return result
+	default:
+		return v
+	}
+}
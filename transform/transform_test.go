@@ -0,0 +1,97 @@
+package transform
+
+import (
+	"bytes"
+	"encoding/csv"
+	"github.com/rfaulhaber/forcedata/pipeline"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+const testTransforms = `
+columns:
+  Birthdate:
+    - date: MM/DD/YYYY
+  LastActivity__c:
+    - datetime: M/D/YYYY HH:mm
+  Name: [trim, upper]
+  Phone:
+    - replace: {pattern: "[^0-9+]", with: ""}
+  Status:
+    - lookup: {A: Active, I: Inactive}
+  Country:
+    - default: US
+  Description:
+    - join: {columns: [Notes, Country], separator: " / "}
+  Address:
+    - split: {separator: ",", into: [Street, City]}
+    - drop
+`
+
+func TestTransforms(t *testing.T) {
+	tr, err := Parse([]byte(testTransforms))
+	assert.NoError(t, err)
+
+	input := "Name,Birthdate,LastActivity__c,Phone,Status,Country,Notes,Address\n" +
+		" acme ,01/31/1990,7/4/2018 13:05,(555) 123-4567,A,,VIP,\"1 Main St, Springfield\"\n" +
+		"globex,31/01/1990,,,I,CA,,\n" +
+		"initech,,,,X,,,\n"
+
+	var out, rejects bytes.Buffer
+
+	p := pipeline.Pipeline{Stages: []pipeline.Stage{tr}, Rejects: csv.NewWriter(&rejects)}
+
+	assert.NoError(t, p.Run(csv.NewReader(strings.NewReader(input)), csv.NewWriter(&out)))
+
+	assert.Equal(t, "Name,Birthdate,LastActivity__c,Phone,Status,Country,Notes,Description,Street,City\n"+
+		"ACME,1990-01-31,2018-07-04T13:05:00Z,5551234567,Active,US,VIP,VIP / US,1 Main St,Springfield\n", out.String())
+
+	assert.Equal(t, "Name,Birthdate,LastActivity__c,Phone,Status,Country,Notes,Address,forcedata__Error\n"+
+		"globex,31/01/1990,,,I,CA,,,\"Birthdate: \"\"31/01/1990\"\" does not match MM/DD/YYYY\"\n"+
+		"initech,,,,X,,,,\"Status: \"\"X\"\" is not one of A, I\"\n", rejects.String())
+
+	assert.Equal(t, 1, p.Rows)
+	assert.Equal(t, 2, p.Rejected)
+}
+
+func TestParseError(t *testing.T) {
+	testCases := []string{
+		"columns:\n  Name: [shout]\n",
+		"columns:\n  Name:\n    - replace: {pattern: \"[\"}\n",
+		"columns:\n  Name:\n    - join: {separator: \" \"}\n",
+		"columns:\n  Name:\n    - split: {into: [A, B]}\n",
+		"columns:\n  Name:\n    - date: {}\n",
+		"rows: []\n",
+	}
+
+	for _, tc := range testCases {
+		_, err := Parse([]byte(tc))
+		assert.Error(t, err, tc)
+	}
+}
+
+func TestTransforms_MissingJoinColumn(t *testing.T) {
+	tr, _ := Parse([]byte("columns:\n  Description:\n    - join: {columns: [Notes]}\n"))
+
+	_, err := tr.Header([]string{"Name"})
+
+	assert.EqualError(t, err, `transform: column "Description": column "Notes" not found`)
+}
+
+func TestLayout(t *testing.T) {
+	assert.Equal(t, "01/02/2006", layout("MM/DD/YYYY"))
+	assert.Equal(t, "02-Jan-2006", layout("DD-MMM-YYYY"))
+	assert.Equal(t, "2 January 06", layout("D MMMM YY"))
+	assert.Equal(t, "02 Jan 2006 15:04", layout("go:02 Jan 2006 15:04"))
+
+	tr, err := Parse([]byte("columns:\n  Birthdate:\n    - date: DD-MMM-YYYY\n"))
+	assert.NoError(t, err)
+
+	_, err = tr.Header([]string{"Birthdate"})
+	assert.NoError(t, err)
+
+	row, err := tr.Row([]string{"31-Jan-1990"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"1990-01-31"}, row)
+}
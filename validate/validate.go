@@ -0,0 +1,337 @@
+// Package validate checks CSV content against an object's describe metadata before it is sent to the Bulk API.
+package validate
+
+import (
+	"encoding/csv"
+	"fmt"
+	"github.com/pkg/errors"
+	"github.com/rfaulhaber/forcedata/describe"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// ErrInvalid is wrapped by errors returned when validation finds problems.
+var ErrInvalid = errors.New("validation failed")
+
+// nullValue is how the Bulk API is told to blank out a field.
+const nullValue = "#N/A"
+
+var (
+	idPattern    = regexp.MustCompile(`^[a-zA-Z0-9]{15}([a-zA-Z0-9]{3})?$`)
+	emailPattern = regexp.MustCompile(`^[^@\s]+@[^@\s]+\.[^@\s]+$`)
+
+	datetimeLayouts = []string{
+		"2006-01-02T15:04:05Z07:00",
+		"2006-01-02T15:04:05.000Z07:00",
+		"2006-01-02T15:04:05Z0700",
+		"2006-01-02T15:04:05.000Z0700",
+		"2006-01-02T15:04:05",
+		"2006-01-02T15:04:05.000",
+	}
+)
+
+// Issue is a single problem found in the content. Row is 0 for problems with the header, and counts data rows from 1
+// otherwise.
+type Issue struct {
+	Row     int    `json:"row"`
+	Column  string `json:"column"`
+	Value   string `json:"value"`
+	Message string `json:"message"`
+}
+
+func (i Issue) String() string {
+	location := "header"
+
+	if i.Row > 0 {
+		location = "row " + strconv.Itoa(i.Row)
+	}
+
+	if i.Column != "" {
+		location += ", column " + i.Column
+	}
+
+	if i.Value != "" {
+		return fmt.Sprintf("%s: %q %s", location, i.Value, i.Message)
+	}
+
+	return location + ": " + i.Message
+}
+
+// Validator checks a header and then rows one at a time against an object for a given operation.
+type Validator struct {
+	object          describe.SObject
+	operation       string
+	externalIDField string
+
+	columns []column
+	row     int
+	issues  []Issue
+}
+
+type column struct {
+	name  string
+	field describe.Field
+	known bool
+}
+
+// New returns a Validator for loading into object with operation. externalIDField is only used for upserts.
+func New(object describe.SObject, operation, externalIDField string) *Validator {
+	return &Validator{
+		object:          object,
+		operation:       operation,
+		externalIDField: externalIDField,
+	}
+}
+
+// Header checks that every column maps to a field that can be written for the operation, and that the columns the
+// operation needs are present.
+func (v *Validator) Header(header []string) {
+	present := map[string]bool{}
+
+	for _, name := range header {
+		col := column{name: name}
+		present[strings.ToLower(name)] = true
+
+		if v.isDelete() {
+			if f, ok := v.object.Field(name); ok && f.Type == "id" {
+				col.field, col.known = f, true
+			}
+
+			v.columns = append(v.columns, col)
+			continue
+		}
+
+		if dot := strings.Index(name, "."); dot > 0 {
+			if ref, ok := v.checkRelationshipColumn(name[:dot], name); ok {
+				present[strings.ToLower(ref.Name)] = true
+			}
+
+			v.columns = append(v.columns, col)
+			continue
+		}
+
+		f, ok := v.object.Field(name)
+
+		if !ok {
+			v.add(0, name, "", "is not a field of "+v.object.Name)
+			v.columns = append(v.columns, col)
+			continue
+		}
+
+		col.field, col.known = f, true
+		v.columns = append(v.columns, col)
+
+		if f.Type == "id" {
+			continue
+		}
+
+		switch v.operation {
+		case "insert":
+			if !f.Createable {
+				v.add(0, name, "", "cannot be set when creating records")
+			}
+		case "update":
+			if !f.Updateable {
+				v.add(0, name, "", "cannot be set when updating records")
+			}
+		case "upsert":
+			if !f.Createable && !f.Updateable {
+				v.add(0, name, "", "cannot be set when creating or updating records")
+			}
+		}
+	}
+
+	switch v.operation {
+	case "insert":
+		for _, f := range v.object.Fields {
+			if f.Required() && !present[strings.ToLower(f.Name)] {
+				v.add(0, f.Name, "", "is required but missing from the file")
+			}
+		}
+	case "update", "delete", "hardDelete":
+		if !present["id"] {
+			v.add(0, "Id", "", "is required for "+v.operation+" but missing from the file")
+		}
+	case "upsert":
+		f, ok := v.object.Field(v.externalIDField)
+
+		if !ok {
+			v.add(0, v.externalIDField, "", "external ID field is not a field of "+v.object.Name)
+		} else if !f.ExternalID && !f.IDLookup {
+			v.add(0, v.externalIDField, "", "is not an external ID field")
+		} else if !present[strings.ToLower(f.Name)] {
+			v.add(0, f.Name, "", "external ID field is missing from the file")
+		}
+	}
+}
+
+// Row checks every value of a row against its column's field.
+func (v *Validator) Row(row []string) {
+	v.row++
+
+	if len(row) != len(v.columns) {
+		v.add(v.row, "", "", fmt.Sprintf("has %d values but the header has %d columns", len(row), len(v.columns)))
+	}
+
+	for i, value := range row {
+		if i >= len(v.columns) || !v.columns[i].known {
+			continue
+		}
+
+		col := v.columns[i]
+
+		if value == "" || value == nullValue {
+			if v.operation == "insert" && col.field.Required() {
+				v.add(v.row, col.name, "", "is required but blank")
+			}
+
+			if v.isDelete() || v.operation == "update" {
+				if col.field.Type == "id" {
+					v.add(v.row, col.name, "", "is required but blank")
+				}
+			}
+
+			continue
+		}
+
+		if msg := checkValue(col.field, value); msg != "" {
+			v.add(v.row, col.name, value, msg)
+		}
+	}
+}
+
+// Issues returns every problem found so far.
+func (v *Validator) Issues() []Issue {
+	return v.issues
+}
+
+// Err returns an error wrapping ErrInvalid if any problems were found.
+func (v *Validator) Err() error {
+	if len(v.issues) == 0 {
+		return nil
+	}
+
+	return errors.Wrapf(ErrInvalid, "found %d problems", len(v.issues))
+}
+
+// CSV validates all of the content read by r.
+func CSV(r *csv.Reader, v *Validator) error {
+	header, err := r.Read()
+
+	if err != nil {
+		return errors.Wrap(err, "could not read header")
+	}
+
+	v.Header(header)
+
+	for {
+		row, err := r.Read()
+
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			return errors.Wrap(err, "could not parse CSV")
+		}
+
+		v.Row(row)
+	}
+
+	return nil
+}
+
+func (v *Validator) checkRelationshipColumn(relationship, name string) (describe.Field, bool) {
+	ref, ok := v.object.Relationship(relationship)
+
+	if !ok {
+		v.add(0, name, "", relationship+" is not a relationship of "+v.object.Name)
+	}
+
+	return ref, ok
+}
+
+func (v *Validator) isDelete() bool {
+	return v.operation == "delete" || v.operation == "hardDelete"
+}
+
+func (v *Validator) add(row int, column, value, message string) {
+	v.issues = append(v.issues, Issue{row, column, value, message})
+}
+
+// checkValue returns a description of what's wrong with value, or an empty string if it's valid for the field.
+func checkValue(f describe.Field, value string) string {
+	switch f.Type {
+	case "int":
+		if _, err := strconv.ParseInt(value, 10, 64); err != nil {
+			return "is not a whole number"
+		}
+	case "double", "currency", "percent":
+		if _, err := strconv.ParseFloat(value, 64); err != nil {
+			return "is not a number"
+		}
+	case "boolean":
+		switch strings.ToLower(value) {
+		case "true", "false", "1", "0", "yes", "no":
+		default:
+			return "is not a boolean (true or false)"
+		}
+	case "date":
+		if _, err := time.Parse("2006-01-02", value); err != nil {
+			return "is not a date (YYYY-MM-DD)"
+		}
+	case "datetime":
+		if !isDatetime(value) {
+			return "is not a datetime (YYYY-MM-DDThh:mm:ssZ)"
+		}
+	case "id", "reference":
+		if !idPattern.MatchString(value) {
+			return "is not a record ID"
+		}
+	case "email":
+		if !emailPattern.MatchString(value) {
+			return "is not an email address"
+		}
+	case "picklist":
+		if f.RestrictedPicklist && !f.IsPicklistValue(value) {
+			return "is not a value of the restricted picklist"
+		}
+	case "multipicklist":
+		if f.RestrictedPicklist {
+			for _, item := range strings.Split(value, ";") {
+				if !f.IsPicklistValue(item) {
+					return "contains " + strconv.Quote(item) + ", which is not a value of the restricted picklist"
+				}
+			}
+		}
+	}
+
+	if f.Length > 0 && isText(f.Type) && utf8.RuneCountInString(value) > f.Length {
+		return fmt.Sprintf("is longer than the maximum length of %d", f.Length)
+	}
+
+	return ""
+}
+
+func isText(fieldType string) bool {
+	switch fieldType {
+	case "string", "textarea", "picklist", "multipicklist", "email", "phone", "url", "encryptedstring", "combobox":
+		return true
+	default:
+		return false
+	}
+}
+
+func isDatetime(value string) bool {
+	for _, layout := range datetimeLayouts {
+		if _, err := time.Parse(layout, value); err == nil {
+			return true
+		}
+	}
+
+	return false
+}
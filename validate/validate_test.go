@@ -0,0 +1,108 @@
+package validate
+
+import (
+	"encoding/csv"
+	"errors"
+	"github.com/rfaulhaber/forcedata/describe"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+var contact = describe.SObject{
+	Name: "Contact",
+	Fields: []describe.Field{
+		{Name: "Id", Type: "id"},
+		{Name: "LastName", Type: "string", Length: 10, Createable: true, Updateable: true},
+		{Name: "FirstName", Type: "string", Length: 40, Nillable: true, Createable: true, Updateable: true},
+		{Name: "Birthdate", Type: "date", Nillable: true, Createable: true, Updateable: true},
+		{Name: "LastActivity__c", Type: "datetime", Nillable: true, Createable: true, Updateable: true},
+		{Name: "DoNotCall", Type: "boolean", Createable: true, Updateable: true},
+		{Name: "Score__c", Type: "double", Nillable: true, Createable: true, Updateable: true},
+		{Name: "AccountId", Type: "reference", Nillable: true, Createable: true, Updateable: true, RelationshipName: "Account"},
+		{Name: "CreatedDate", Type: "datetime"},
+		{Name: "External_Id__c", Type: "string", Length: 20, Nillable: true, Createable: true, Updateable: true, ExternalID: true},
+		{Name: "Level__c", Type: "picklist", Nillable: true, Createable: true, Updateable: true, RestrictedPicklist: true, PicklistValues: []describe.PicklistValue{
+			{Value: "Primary", Active: true},
+			{Value: "Secondary", Active: true},
+		}},
+	},
+}
+
+func TestValidator_Valid(t *testing.T) {
+	content := "LastName,FirstName,Birthdate,LastActivity__c,DoNotCall,Score__c,AccountId,Level__c,Account.External_Id__c\n" +
+		"One,Person,1990-01-31,2018-07-01T12:00:00Z,true,1.5,001000000000001AAA,Primary,A1\n" +
+		"Two,,#N/A,,false,,,,\n"
+
+	v := New(contact, "insert", "")
+
+	assert.NoError(t, CSV(csv.NewReader(strings.NewReader(content)), v))
+	assert.Empty(t, v.Issues())
+	assert.NoError(t, v.Err())
+}
+
+func TestValidator_Header(t *testing.T) {
+	testCases := []struct {
+		operation string
+		header    string
+		expected  []Issue
+	}{
+		{"insert", "FirstName,Nmae,CreatedDate,Parent.Name", []Issue{
+			{0, "Nmae", "", "is not a field of Contact"},
+			{0, "CreatedDate", "", "cannot be set when creating records"},
+			{0, "Parent.Name", "", "Parent is not a relationship of Contact"},
+			{0, "LastName", "", "is required but missing from the file"},
+		}},
+		{"update", "LastName", []Issue{
+			{0, "Id", "", "is required for update but missing from the file"},
+		}},
+		{"upsert", "LastName", []Issue{
+			{0, "External_Id__c", "", "external ID field is missing from the file"},
+		}},
+		{"delete", "Id,Anything", nil},
+	}
+
+	for _, tc := range testCases {
+		v := New(contact, tc.operation, "External_Id__c")
+		v.Header(strings.Split(tc.header, ","))
+
+		assert.Equal(t, tc.expected, v.Issues(), tc.operation)
+	}
+}
+
+func TestValidator_Row(t *testing.T) {
+	content := "LastName,Birthdate,LastActivity__c,DoNotCall,Score__c,AccountId,Level__c\n" +
+		"WayTooLongName,01/31/1990,yesterday,maybe,1.2.3,123,Tertiary\n" +
+		",,,,,,\n" +
+		"Short\n"
+
+	v := New(contact, "insert", "")
+
+	assert.NoError(t, CSV(newLazyReader(content), v))
+
+	assert.Equal(t, []Issue{
+		{1, "LastName", "WayTooLongName", "is longer than the maximum length of 10"},
+		{1, "Birthdate", "01/31/1990", "is not a date (YYYY-MM-DD)"},
+		{1, "LastActivity__c", "yesterday", "is not a datetime (YYYY-MM-DDThh:mm:ssZ)"},
+		{1, "DoNotCall", "maybe", "is not a boolean (true or false)"},
+		{1, "Score__c", "1.2.3", "is not a number"},
+		{1, "AccountId", "123", "is not a record ID"},
+		{1, "Level__c", "Tertiary", "is not a value of the restricted picklist"},
+		{2, "LastName", "", "is required but blank"},
+		{3, "", "", "has 1 values but the header has 7 columns"},
+	}, v.Issues())
+
+	assert.True(t, errors.Is(v.Err(), ErrInvalid))
+}
+
+func TestIssue_String(t *testing.T) {
+	assert.Equal(t, `row 2, column Birthdate: "01/31/1990" is not a date (YYYY-MM-DD)`, Issue{2, "Birthdate", "01/31/1990", "is not a date (YYYY-MM-DD)"}.String())
+	assert.Equal(t, "header, column Nmae: is not a field of Contact", Issue{0, "Nmae", "", "is not a field of Contact"}.String())
+}
+
+func newLazyReader(content string) *csv.Reader {
+	r := csv.NewReader(strings.NewReader(content))
+	r.FieldsPerRecord = -1
+
+	return r
+}
@@ -0,0 +1,161 @@
+package xlsx
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Layouts dates and times are written in, as the Bulk API accepts them.
+const (
+	DateLayout     = "2006-01-02"
+	DateTimeLayout = "2006-01-02T15:04:05.000Z"
+	TimeLayout     = "15:04:05.000Z"
+)
+
+// precision is the number of significant digits Excel keeps of numbers. Workbooks store some with more, such as
+// 0.30000000000000004 for 0.1+0.2, which Excel shows as 0.3.
+const precision = 15
+
+var (
+	epoch1900 = time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC)
+	epoch1904 = time.Date(1904, 1, 1, 0, 0, 0, 0, time.UTC)
+)
+
+// builtin are the formats of the built in number format IDs that matter here: dates, times and plain integers. Every
+// other built in format is written as a plain number.
+var builtin = map[int]string{
+	1:  "0",
+	14: "mm-dd-yy",
+	15: "d-mmm-yy",
+	16: "d-mmm",
+	17: "mmm-yy",
+	18: "h:mm AM/PM",
+	19: "h:mm:ss AM/PM",
+	20: "h:mm",
+	21: "h:mm:ss",
+	22: "m/d/yy h:mm",
+	45: "mm:ss",
+	47: "mmss.0",
+}
+
+// numFormat is what a number format says about how its numbers are written.
+type numFormat struct {
+	date bool
+	time bool
+
+	// digits is the width integers are padded to with leading zeros, for formats like 00000 used for zip codes and
+	// account numbers.
+	digits int
+}
+
+// parseFormat reads a number format code, looking only at its first section, which positive numbers are shown with.
+func parseFormat(code string) numFormat {
+	var (
+		f       numFormat
+		tokens  strings.Builder
+		quoted  bool
+		bracket bool
+		escaped bool
+		elapsed bool
+		label   strings.Builder
+	)
+
+	for _, r := range code {
+		switch {
+		case escaped:
+			escaped = false
+		case quoted:
+			quoted = r != '"'
+		case bracket && r != ']':
+			label.WriteRune(r)
+		case bracket:
+			// [h], [mm] and [ss] are elapsed times, which are kept as numbers, and the rest are colors and locales
+			bracket = false
+			elapsed = elapsed || isElapsed(label.String())
+			label.Reset()
+		case r == '\\':
+			escaped = true
+		case r == '"':
+			quoted = true
+		case r == '[':
+			bracket = true
+		case r == ';':
+			return finish(f, tokens.String(), elapsed)
+		default:
+			tokens.WriteRune(r)
+		}
+	}
+
+	return finish(f, tokens.String(), elapsed)
+}
+
+// isElapsed reports whether the label of a bracketed part of a format, such as h or mm, is an elapsed time.
+func isElapsed(label string) bool {
+	label = strings.ToLower(label)
+
+	return label != "" && (strings.Trim(label, "h") == "" || strings.Trim(label, "m") == "" || strings.Trim(label, "s") == "")
+}
+
+func finish(f numFormat, tokens string, elapsed bool) numFormat {
+	if elapsed {
+		return f
+	}
+
+	tokens = strings.ToLower(tokens)
+
+	if strings.Trim(tokens, "0") == "" {
+		f.digits = len(tokens)
+		return f
+	}
+
+	tokens = strings.Replace(tokens, "general", "", -1)
+
+	f.time = strings.ContainsAny(tokens, "hs")
+	f.date = strings.ContainsAny(tokens, "dy") || strings.Contains(tokens, "m") && !f.time
+
+	return f
+}
+
+// format writes a number as its format says. date1904 is set for workbooks whose dates count from 1904, as older Mac
+// workbooks do.
+func (f numFormat) format(n float64, date1904 bool) string {
+	switch {
+	case f.date || f.time:
+		t := serialTime(n, date1904)
+
+		switch {
+		case f.date && f.time:
+			return t.Format(DateTimeLayout)
+		case f.date:
+			return t.Format(DateLayout)
+		default:
+			return t.Format(TimeLayout)
+		}
+	case f.digits > 0 && n >= 0 && n == math.Trunc(n) && n < 1e15:
+		return fmt.Sprintf("%0*d", f.digits, int64(n))
+	}
+
+	rounded, _ := strconv.ParseFloat(strconv.FormatFloat(n, 'g', precision, 64), 64)
+
+	return strconv.FormatFloat(rounded, 'f', -1, 64)
+}
+
+// serialTime converts an Excel serial date, the days since the workbook's epoch, to a time, rounded to the
+// millisecond. Times have no zone in a workbook, so they're taken as UTC.
+func serialTime(n float64, date1904 bool) time.Time {
+	epoch := epoch1900
+
+	if date1904 {
+		epoch = epoch1904
+	} else if n < 61 {
+		// Excel counts 29 February 1900, which didn't exist, so its dates before it are a day later than they seem
+		n++
+	}
+
+	ms := math.Round(n * 24 * 60 * 60 * 1000)
+
+	return epoch.Add(time.Duration(ms) * time.Millisecond)
+}
@@ -0,0 +1,480 @@
+// Package xlsx reads the sheets of Excel workbooks as CSV. Cells are written as the text Salesforce expects rather than
+// as Excel displays them: dates as ISO 8601, numbers without grouping or scientific notation, and booleans as true or
+// false. Text cells are written as they are, so leading zeros survive.
+package xlsx
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"encoding/xml"
+	"github.com/pkg/errors"
+	"io"
+	"io/ioutil"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// zipMagic starts every zip file, and so every workbook.
+var zipMagic = []byte("PK\x03\x04")
+
+// Workbook is an opened Excel workbook.
+type Workbook struct {
+	// Sheets are the names of the workbook's sheets, in order.
+	Sheets []string
+
+	files    map[string]*zip.File
+	targets  []string
+	strings  []string
+	styles   []numFormat
+	date1904 bool
+}
+
+// IsWorkbook reports whether content looks like an Excel workbook rather than text.
+func IsWorkbook(content []byte) bool {
+	return bytes.HasPrefix(content, zipMagic)
+}
+
+// Open reads the sheets, shared strings and styles of a workbook.
+func Open(content []byte) (*Workbook, error) {
+	z, err := zip.NewReader(bytes.NewReader(content), int64(len(content)))
+
+	if err != nil {
+		return nil, errors.Wrap(err, "could not open workbook")
+	}
+
+	w := &Workbook{files: make(map[string]*zip.File)}
+
+	for _, f := range z.File {
+		w.files[strings.TrimPrefix(f.Name, "/")] = f
+	}
+
+	if err := w.readSheets(); err != nil {
+		return nil, err
+	}
+
+	if err := w.readStrings(); err != nil {
+		return nil, err
+	}
+
+	if err := w.readStyles(); err != nil {
+		return nil, err
+	}
+
+	return w, nil
+}
+
+// Sheet returns the index of the sheet with the given name, compared case-insensitively, or 1-based number. An empty
+// name is the first sheet.
+func (w *Workbook) Sheet(name string) (int, error) {
+	if name == "" {
+		return 0, nil
+	}
+
+	for i, sheet := range w.Sheets {
+		if strings.EqualFold(sheet, name) {
+			return i, nil
+		}
+	}
+
+	if n, err := strconv.Atoi(name); err == nil && n >= 1 && n <= len(w.Sheets) {
+		return n - 1, nil
+	}
+
+	return 0, errors.Errorf("workbook has no sheet %q; its sheets are %s", name, strings.Join(w.Sheets, ", "))
+}
+
+// CSV returns a sheet as comma delimited CSV. Rows before headerRow, which is 1-based, are skipped, and the header's
+// last non-blank cell sets how many columns every row has. Blank rows are dropped.
+func (w *Workbook) CSV(sheet int, headerRow int) ([]byte, error) {
+	if headerRow < 1 {
+		headerRow = 1
+	}
+
+	f, err := w.open(w.targets[sheet])
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer f.Close()
+
+	var (
+		buf    bytes.Buffer
+		width  int
+		header = true
+	)
+
+	out := csv.NewWriter(&buf)
+
+	err = w.rows(f, func(n int, cells []string) error {
+		if n < headerRow {
+			return nil
+		}
+
+		if header {
+			header = false
+
+			for i, cell := range cells {
+				if strings.TrimSpace(cell) != "" {
+					width = i + 1
+				}
+			}
+
+			if width == 0 {
+				return errors.Errorf("row %d of sheet %s is blank; use the row the column names are in as the header", n, w.Sheets[sheet])
+			}
+		}
+
+		if blank(cells) {
+			return nil
+		}
+
+		row := make([]string, width)
+		copy(row, cells)
+
+		return out.Write(row)
+	})
+
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not read sheet %s", w.Sheets[sheet])
+	}
+
+	if header {
+		return nil, errors.Errorf("sheet %s has no row %d", w.Sheets[sheet], headerRow)
+	}
+
+	out.Flush()
+
+	return buf.Bytes(), out.Error()
+}
+
+type xmlSheets struct {
+	Pr struct {
+		Date1904 string `xml:"date1904,attr"`
+	} `xml:"workbookPr"`
+	Sheets []struct {
+		Name string `xml:"name,attr"`
+		ID   string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+	} `xml:"sheets>sheet"`
+}
+
+type xmlRels struct {
+	Rels []struct {
+		ID     string `xml:"Id,attr"`
+		Target string `xml:"Target,attr"`
+	} `xml:"Relationship"`
+}
+
+// readSheets reads the names of the sheets and the parts they're stored in.
+func (w *Workbook) readSheets() error {
+	var (
+		wb   xmlSheets
+		rels xmlRels
+	)
+
+	if err := w.decode("xl/workbook.xml", &wb); err != nil {
+		return err
+	}
+
+	if err := w.decode("xl/_rels/workbook.xml.rels", &rels); err != nil {
+		return err
+	}
+
+	targets := make(map[string]string)
+
+	for _, rel := range rels.Rels {
+		if strings.HasPrefix(rel.Target, "/") {
+			targets[rel.ID] = strings.TrimPrefix(rel.Target, "/")
+		} else {
+			targets[rel.ID] = path.Join("xl", rel.Target)
+		}
+	}
+
+	for _, sheet := range wb.Sheets {
+		target, ok := targets[sheet.ID]
+
+		if !ok {
+			return errors.Errorf("workbook has no part for sheet %s", sheet.Name)
+		}
+
+		w.Sheets = append(w.Sheets, sheet.Name)
+		w.targets = append(w.targets, target)
+	}
+
+	if len(w.Sheets) == 0 {
+		return errors.New("workbook has no sheets")
+	}
+
+	w.date1904 = wb.Pr.Date1904 == "1" || wb.Pr.Date1904 == "true"
+
+	return nil
+}
+
+type xmlText struct {
+	T    string `xml:"t"`
+	Runs []struct {
+		T string `xml:"t"`
+	} `xml:"r"`
+}
+
+func (t xmlText) String() string {
+	if len(t.Runs) == 0 {
+		return t.T
+	}
+
+	var s strings.Builder
+
+	for _, run := range t.Runs {
+		s.WriteString(run.T)
+	}
+
+	return s.String()
+}
+
+// readStrings reads the shared strings text cells refer to. Workbooks without text have none.
+func (w *Workbook) readStrings() error {
+	var sst struct {
+		Items []xmlText `xml:"si"`
+	}
+
+	if _, ok := w.files["xl/sharedStrings.xml"]; !ok {
+		return nil
+	}
+
+	if err := w.decode("xl/sharedStrings.xml", &sst); err != nil {
+		return err
+	}
+
+	for _, item := range sst.Items {
+		w.strings = append(w.strings, item.String())
+	}
+
+	return nil
+}
+
+// readStyles reads the number formats of the cell styles.
+func (w *Workbook) readStyles() error {
+	var styles struct {
+		NumFmts []struct {
+			ID     int    `xml:"numFmtId,attr"`
+			Format string `xml:"formatCode,attr"`
+		} `xml:"numFmts>numFmt"`
+		Xfs []struct {
+			NumFmtID int `xml:"numFmtId,attr"`
+		} `xml:"cellXfs>xf"`
+	}
+
+	if _, ok := w.files["xl/styles.xml"]; !ok {
+		return nil
+	}
+
+	if err := w.decode("xl/styles.xml", &styles); err != nil {
+		return err
+	}
+
+	custom := make(map[int]string)
+
+	for _, f := range styles.NumFmts {
+		custom[f.ID] = f.Format
+	}
+
+	for _, xf := range styles.Xfs {
+		code, ok := custom[xf.NumFmtID]
+
+		if !ok {
+			code = builtin[xf.NumFmtID]
+		}
+
+		w.styles = append(w.styles, parseFormat(code))
+	}
+
+	return nil
+}
+
+type xmlCell struct {
+	Ref    string  `xml:"r,attr"`
+	Type   string  `xml:"t,attr"`
+	Style  int     `xml:"s,attr"`
+	Value  string  `xml:"v"`
+	Inline xmlText `xml:"is"`
+}
+
+type xmlRow struct {
+	N     int       `xml:"r,attr"`
+	Cells []xmlCell `xml:"c"`
+}
+
+// rows calls fn with the 1-based number and cells of each row of a sheet, reading the sheet a row at a time.
+func (w *Workbook) rows(r io.Reader, fn func(n int, cells []string) error) error {
+	d := xml.NewDecoder(r)
+	n := 0
+
+	for {
+		tok, err := d.Token()
+
+		if err == io.EOF {
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+
+		start, ok := tok.(xml.StartElement)
+
+		if !ok || start.Name.Local != "row" {
+			continue
+		}
+
+		var row xmlRow
+
+		if err := d.DecodeElement(&row, &start); err != nil {
+			return err
+		}
+
+		if row.N > 0 {
+			n = row.N
+		} else {
+			n++
+		}
+
+		cells, err := w.cells(row)
+
+		if err != nil {
+			return errors.Wrapf(err, "row %d", n)
+		}
+
+		if err := fn(n, cells); err != nil {
+			return err
+		}
+	}
+}
+
+// cells returns the text of a row's cells, with blanks for the cells a sparse row leaves out.
+func (w *Workbook) cells(row xmlRow) ([]string, error) {
+	var cells []string
+
+	for _, c := range row.Cells {
+		col := len(cells)
+
+		if c.Ref != "" {
+			var err error
+
+			if col, err = column(c.Ref); err != nil {
+				return nil, err
+			}
+		}
+
+		for len(cells) < col {
+			cells = append(cells, "")
+		}
+
+		text, err := w.text(c)
+
+		if err != nil {
+			return nil, errors.Wrapf(err, "cell %s", c.Ref)
+		}
+
+		cells = append(cells, text)
+	}
+
+	return cells, nil
+}
+
+// text returns a cell's value as Salesforce expects it.
+func (w *Workbook) text(c xmlCell) (string, error) {
+	switch c.Type {
+	case "s":
+		i, err := strconv.Atoi(c.Value)
+
+		if err != nil || i < 0 || i >= len(w.strings) {
+			return "", errors.Errorf("no shared string %s", c.Value)
+		}
+
+		return w.strings[i], nil
+	case "inlineStr":
+		return c.Inline.String(), nil
+	case "str":
+		return c.Value, nil
+	case "b":
+		return strconv.FormatBool(c.Value == "1"), nil
+	case "e":
+		return "", errors.Errorf("formula error %s", c.Value)
+	case "d":
+		return c.Value, nil
+	}
+
+	if c.Value == "" {
+		return "", nil
+	}
+
+	f, err := strconv.ParseFloat(c.Value, 64)
+
+	if err != nil {
+		return "", errors.Errorf("%q is not a number", c.Value)
+	}
+
+	if c.Style >= 0 && c.Style < len(w.styles) {
+		return w.styles[c.Style].format(f, w.date1904), nil
+	}
+
+	return numFormat{}.format(f, w.date1904), nil
+}
+
+// column returns the 0-based column of a cell reference such as AB12.
+func column(ref string) (int, error) {
+	col := 0
+	i := 0
+
+	for ; i < len(ref) && ref[i] >= 'A' && ref[i] <= 'Z'; i++ {
+		col = col*26 + int(ref[i]-'A') + 1
+	}
+
+	if i == 0 {
+		return 0, errors.Errorf("%q is not a cell reference", ref)
+	}
+
+	return col - 1, nil
+}
+
+func blank(cells []string) bool {
+	for _, cell := range cells {
+		if strings.TrimSpace(cell) != "" {
+			return false
+		}
+	}
+
+	return true
+}
+
+// open opens a part of the workbook.
+func (w *Workbook) open(name string) (io.ReadCloser, error) {
+	f, ok := w.files[name]
+
+	if !ok {
+		return nil, errors.Errorf("workbook has no %s", name)
+	}
+
+	return f.Open()
+}
+
+// decode unmarshals a part of the workbook.
+func (w *Workbook) decode(name string, v interface{}) error {
+	f, err := w.open(name)
+
+	if err != nil {
+		return err
+	}
+
+	defer f.Close()
+
+	b, err := ioutil.ReadAll(f)
+
+	if err != nil {
+		return errors.Wrap(err, "could not read "+name)
+	}
+
+	return errors.Wrap(xml.Unmarshal(b, v), "could not read "+name)
+}
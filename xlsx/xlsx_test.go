@@ -0,0 +1,117 @@
+package xlsx
+
+import (
+	"archive/zip"
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+const testWorkbook = `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"
+ xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">
+<sheets><sheet name="Notes" sheetId="1" r:id="rId1"/><sheet name="Accounts" sheetId="2" r:id="rId2"/></sheets>
+</workbook>`
+
+const testRels = `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId1" Target="worksheets/sheet1.xml"/>
+<Relationship Id="rId2" Target="/xl/worksheets/sheet2.xml"/>
+</Relationships>`
+
+const testStrings = `<sst><si><t>Name</t></si><si><t>AccountNumber</t></si><si><t>Founded</t></si><si><t>Active</t></si>
+<si><t>Revenue</t></si><si><r><t>Ac</t></r><r><t>me</t></r></si><si><t>00042</t></si><si><t>Updated</t></si></sst>`
+
+const testStyles = `<styleSheet><numFmts><numFmt numFmtId="164" formatCode="yyyy\-mm\-dd;@"/>
+<numFmt numFmtId="165" formatCode="00000"/><numFmt numFmtId="166" formatCode="[Red]#,##0.00&quot; days&quot;"/></numFmts>
+<cellXfs><xf numFmtId="0"/><xf numFmtId="164"/><xf numFmtId="22"/><xf numFmtId="165"/><xf numFmtId="166"/></cellXfs>
+</styleSheet>`
+
+const testSheet = `<worksheet><sheetData>
+<row r="1"><c r="A1" t="inlineStr"><is><t>Exported 2019-03-01</t></is></c></row>
+<row r="3"><c r="A3" t="s"><v>0</v></c><c r="B3" t="s"><v>1</v></c><c r="C3" t="s"><v>2</v></c><c r="D3" t="s"><v>3</v></c>
+<c r="E3" t="s"><v>4</v></c><c r="F3" t="s"><v>7</v></c></row>
+<row r="4"><c r="A4" t="s"><v>5</v></c><c r="B4" t="s"><v>6</v></c><c r="C4" s="1"><v>43525</v></c><c r="D4" t="b"><v>1</v></c>
+<c r="E4"><v>1.2345E+7</v></c><c r="F4" s="2"><v>43525.5</v></c><c r="H4"><v>9</v></c></row>
+<row r="5"/>
+<row r="6"><c r="A6" t="str"><v>Globex</v></c><c r="B6" s="3"><v>42</v></c><c r="D6" t="b"><v>0</v></c>
+<c r="E6" s="4"><v>0.30000000000000004</v></c></row>
+</sheetData></worksheet>`
+
+func testXLSX(t *testing.T, parts map[string]string) []byte {
+	var buf bytes.Buffer
+
+	z := zip.NewWriter(&buf)
+
+	for name, content := range parts {
+		f, err := z.Create(name)
+		assert.NoError(t, err)
+
+		_, err = f.Write([]byte(content))
+		assert.NoError(t, err)
+	}
+
+	assert.NoError(t, z.Close())
+
+	return buf.Bytes()
+}
+
+func TestWorkbook(t *testing.T) {
+	content := testXLSX(t, map[string]string{
+		"xl/workbook.xml":            testWorkbook,
+		"xl/_rels/workbook.xml.rels": testRels,
+		"xl/sharedStrings.xml":       testStrings,
+		"xl/styles.xml":              testStyles,
+		"xl/worksheets/sheet1.xml":   `<worksheet><sheetData/></worksheet>`,
+		"xl/worksheets/sheet2.xml":   testSheet,
+	})
+
+	assert.True(t, IsWorkbook(content))
+	assert.False(t, IsWorkbook([]byte("Name\nAcme\n")))
+
+	w, err := Open(content)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Notes", "Accounts"}, w.Sheets)
+
+	sheet, err := w.Sheet("accounts")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, sheet)
+
+	sheet, err = w.Sheet("2")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, sheet)
+
+	_, err = w.Sheet("Contacts")
+	assert.EqualError(t, err, `workbook has no sheet "Contacts"; its sheets are Notes, Accounts`)
+
+	b, err := w.CSV(sheet, 3)
+	assert.NoError(t, err)
+	assert.Equal(t, "Name,AccountNumber,Founded,Active,Revenue,Updated\n"+
+		"Acme,00042,2019-03-01,true,12345000,2019-03-01T12:00:00.000Z\n"+
+		"Globex,00042,,false,0.3,\n", string(b))
+
+	_, err = w.CSV(0, 1)
+	assert.EqualError(t, err, "sheet Notes has no row 1")
+}
+
+func TestParseFormat(t *testing.T) {
+	assert.Equal(t, numFormat{date: true}, parseFormat("d-mmm-yy"))
+	assert.Equal(t, numFormat{date: true}, parseFormat("mmm"))
+	assert.Equal(t, numFormat{time: true}, parseFormat("h:mm AM/PM"))
+	assert.Equal(t, numFormat{date: true, time: true}, parseFormat(`dd/mm/yyyy\ hh:mm`))
+	assert.Equal(t, numFormat{digits: 5}, parseFormat("00000"))
+	assert.Equal(t, numFormat{}, parseFormat("General"))
+	assert.Equal(t, numFormat{}, parseFormat(`#,##0 "hours"`))
+	assert.Equal(t, numFormat{}, parseFormat("[$-409]#,##0.00;[Red]-#,##0.00"))
+	assert.Equal(t, numFormat{}, parseFormat("[h]:mm"))
+	assert.Equal(t, numFormat{}, parseFormat("[mm]:ss"))
+	assert.Equal(t, numFormat{}, parseFormat("[Blue][HH]:mm:ss"))
+	assert.Equal(t, numFormat{time: true}, parseFormat("[Blue]h:mm"))
+}
+
+func TestFormat(t *testing.T) {
+	assert.Equal(t, "1900-01-01", numFormat{date: true}.format(1, false))
+	assert.Equal(t, "1900-03-01", numFormat{date: true}.format(61, false))
+	assert.Equal(t, "2019-03-01", numFormat{date: true}.format(42063, true))
+	assert.Equal(t, "18:30:00.000Z", numFormat{time: true}.format(0.7708333333333334, false))
+	assert.Equal(t, "0.000001", numFormat{}.format(1e-6, false))
+	assert.Equal(t, "-12.5", numFormat{digits: 5}.format(-12.5, false))
+}